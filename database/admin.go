@@ -4,16 +4,71 @@ import (
 	"github.com/brane-app/librane/types"
 	"github.com/jmoiron/sqlx"
 
+	"context"
 	"database/sql"
 	"time"
 )
 
-func WriteBan(ban map[string]interface{}) (err error) {
-	var statement string
-	var values []interface{}
-	statement, values = makeSQLInsertable(BAN_TABLE, ban)
+/**
+ * Write some ban `ban` into BAN_TABLE, replacing any ban of the same id
+ * Only the columns of BAN_TABLE may be given
+ * CreateBan and UpdateBan should be preferred, as they're checked before writing
+ */
+func (store *SQLStore) WriteBanContext(ctx context.Context, ban map[string]interface{}) (err error) {
+	err = store.replace(ctx, BAN_TABLE, ban)
+	return
+}
 
-	_, err = database_handle.Exec(statement, values...)
+func (store *SQLStore) WriteBan(ban map[string]interface{}) (err error) {
+	err = store.WriteBanContext(context.Background(), ban)
+	return
+}
+
+/**
+ * Create some new ban `ban`
+ * Fails with ErrInvalid if some field of `ban` can't be stored, or ErrDuplicate if its id is taken
+ * Done in one query
+ * 		write ban: 	INSERT INTO BAN_TABLE (fields...) VALUES (values...)
+ */
+func (store *SQLStore) CreateBanContext(ctx context.Context, ban types.Ban) (err error) {
+	if err = validateBan(ban); err == nil {
+		_, err = store.db().ExecContext(ctx, WRITE_BAN, ban.ID, ban.Banner, ban.Banned, ban.Reason, ban.Created, ban.Expires, ban.Forever)
+	}
+
+	return
+}
+
+func (store *SQLStore) CreateBan(ban types.Ban) (err error) {
+	err = store.CreateBanContext(context.Background(), ban)
+	return
+}
+
+/**
+ * Update some ban of the same id as `ban`
+ * Only reason, expires, and forever are updated, so who banned who is kept
+ * Fails with ErrInvalid if some field of `ban` can't be stored, or ErrNotFound if it doesn't exist
+ * Uses 2 queries, inside of one transaction
+ * 		queries from: 	lockRow
+ * 		write ban: 		UPDATE BAN_TABLE SET reason=reason, expires=expires, forever=forever WHERE id=ID
+ */
+func (store *SQLStore) UpdateBanContext(ctx context.Context, ban types.Ban) (err error) {
+	if err = validateBan(ban); err != nil {
+		return
+	}
+
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		if err = lockRow(ctx, tx, BAN_TABLE, ban.ID); err == nil {
+			_, err = tx.ExecContext(ctx, UPDATE_BAN_OF_ID, ban.Reason, ban.Expires, ban.Forever, ban.ID)
+		}
+
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) UpdateBan(ban types.Ban) (err error) {
+	err = store.UpdateBanContext(context.Background(), ban)
 	return
 }
 
@@ -21,8 +76,8 @@ func WriteBan(ban map[string]interface{}) (err error) {
  * Read a single ban of id `ID`
  * Done in one query
  */
-func ReadSingleBan(ID string) (ban types.Ban, exists bool, err error) {
-	if err = database_handle.QueryRowx(READ_BAN_OF_ID, ID).StructScan(&ban); err != nil {
+func (store *SQLStore) ReadSingleBanContext(ctx context.Context, ID string) (ban types.Ban, exists bool, err error) {
+	if err = store.db().QueryRowxContext(ctx, READ_BAN_OF_ID, ID).StructScan(&ban); err != nil {
 		if err == sql.ErrNoRows {
 			err = nil
 		}
@@ -34,16 +89,21 @@ func ReadSingleBan(ID string) (ban types.Ban, exists bool, err error) {
 	return
 }
 
+func (store *SQLStore) ReadSingleBan(ID string) (ban types.Ban, exists bool, err error) {
+	ban, exists, err = store.ReadSingleBanContext(context.Background(), ID)
+	return
+}
+
 /**
  * Read a slice of bans of a user
  * Done in one query
  */
-func ReadBansOfUser(ID, before string, count int) (bans []types.Ban, size int, err error) {
+func (store *SQLStore) ReadBansOfUserContext(ctx context.Context, ID, before string, count int) (bans []types.Ban, size int, err error) {
 	var rows *sqlx.Rows
 	if before == "" {
-		rows, err = database_handle.Queryx(READ_BANS_OF_USER, ID, count)
+		rows, err = store.db().QueryxContext(ctx, READ_BANS_OF_USER, ID, count)
 	} else {
-		rows, err = database_handle.Queryx(READ_BANS_OF_USER_AFTER_ID, ID, before, count)
+		rows, err = store.db().QueryxContext(ctx, READ_BANS_OF_USER_AFTER_ID, ID, before, count)
 	}
 
 	if err != nil {
@@ -63,14 +123,19 @@ func ReadBansOfUser(ID, before string, count int) (bans []types.Ban, size int, e
 	return
 }
 
+func (store *SQLStore) ReadBansOfUser(ID, before string, count int) (bans []types.Ban, size int, err error) {
+	bans, size, err = store.ReadBansOfUserContext(context.Background(), ID, before, count)
+	return
+}
+
 /**
  * Get whether or not a user is banned, either by a permanent ban, or an expirable ban
  * Done in one query
  */
-func IsBanned(ID string) (banned bool, err error) {
+func (store *SQLStore) IsBannedContext(ctx context.Context, ID string) (banned bool, err error) {
 	var count int
 	var now int64 = time.Now().Unix()
-	if err = database_handle.QueryRowx(READ_BANS_OF_USER_COUNT, ID, ID, now).Scan(&count); err != nil {
+	if err = store.db().QueryRowxContext(ctx, READ_BANS_OF_USER_COUNT, ID, ID, now).Scan(&count); err != nil {
 		return
 	}
 
@@ -78,16 +143,76 @@ func IsBanned(ID string) (banned bool, err error) {
 	return
 }
 
+func (store *SQLStore) IsBanned(ID string) (banned bool, err error) {
+	banned, err = store.IsBannedContext(context.Background(), ID)
+	return
+}
+
+/**
+ * Create or update a report for some user, replacing any report of the same id
+ * Only the columns of REPORT_TABLE may be given
+ * CreateReport and UpdateReport should be preferred, as they're checked before writing
+ * Done in one query
+ */
+func (store *SQLStore) WriteReportContext(ctx context.Context, report map[string]interface{}) (err error) {
+	err = store.replace(ctx, REPORT_TABLE, report)
+	return
+}
+
+func (store *SQLStore) WriteReport(report map[string]interface{}) (err error) {
+	err = store.WriteReportContext(context.Background(), report)
+	return
+}
+
 /**
- * Create or update a report for some user
+ * Create some new report `report`
+ * Fails with ErrInvalid if some field of `report` can't be stored, or ErrDuplicate if its id is taken
  * Done in one query
+ * 		write report: 	INSERT INTO REPORT_TABLE (fields...) VALUES (values...)
+ */
+func (store *SQLStore) CreateReportContext(ctx context.Context, report types.Report) (err error) {
+	if err = validateReport(report); err == nil {
+		_, err = store.db().ExecContext(ctx,
+			WRITE_REPORT,
+			report.ID, report.Reporter, report.Reported, report.Type,
+			report.Reason, report.Created, report.Resolved, report.Resolution,
+		)
+	}
+
+	return
+}
+
+func (store *SQLStore) CreateReport(report types.Report) (err error) {
+	err = store.CreateReportContext(context.Background(), report)
+	return
+}
+
+/**
+ * Update some report of the same id as `report`
+ * Only resolved and resolution are updated, so what was reported and why is kept
+ * Fails with ErrInvalid if some field of `report` can't be stored, or ErrNotFound if it doesn't exist
+ * Uses 2 queries, inside of one transaction
+ * 		queries from: 	lockRow
+ * 		write report: 	UPDATE REPORT_TABLE SET resolved=resolved, resolution=resolution WHERE id=ID
  */
-func WriteReport(report map[string]interface{}) (err error) {
-	var statement string
-	var values []interface{}
-	statement, values = makeSQLInsertable(REPORT_TABLE, report)
+func (store *SQLStore) UpdateReportContext(ctx context.Context, report types.Report) (err error) {
+	if err = validateReport(report); err != nil {
+		return
+	}
+
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		if err = lockRow(ctx, tx, REPORT_TABLE, report.ID); err == nil {
+			_, err = tx.ExecContext(ctx, UPDATE_REPORT_OF_ID, report.Resolved, report.Resolution, report.ID)
+		}
+
+		return
+	})
 
-	_, err = database_handle.Exec(statement, values...)
+	return
+}
+
+func (store *SQLStore) UpdateReport(report types.Report) (err error) {
+	err = store.UpdateReportContext(context.Background(), report)
 	return
 }
 
@@ -95,12 +220,12 @@ func WriteReport(report map[string]interface{}) (err error) {
  * Read a slice of unresolved reports (ie, the mod queue) by order of most recent
  * Done in one query
  */
-func ReadManyUnresolvedReport(before string, count int) (reports []types.Report, size int, err error) {
+func (store *SQLStore) ReadManyUnresolvedReportContext(ctx context.Context, before string, count int) (reports []types.Report, size int, err error) {
 	var rows *sqlx.Rows
 	if before == "" {
-		rows, err = database_handle.Queryx(READ_REPORTS_UNRESOLVED, count)
+		rows, err = store.db().QueryxContext(ctx, READ_REPORTS_UNRESOLVED, count)
 	} else {
-		rows, err = database_handle.Queryx(READ_REPORTS_UNRESOLVED_AFTER_ID, before, count)
+		rows, err = store.db().QueryxContext(ctx, READ_REPORTS_UNRESOLVED_AFTER_ID, before, count)
 	}
 
 	if err != nil {
@@ -120,12 +245,17 @@ func ReadManyUnresolvedReport(before string, count int) (reports []types.Report,
 	return
 }
 
+func (store *SQLStore) ReadManyUnresolvedReport(before string, count int) (reports []types.Report, size int, err error) {
+	reports, size, err = store.ReadManyUnresolvedReportContext(context.Background(), before, count)
+	return
+}
+
 /**
  * Lookup single report by it's ID
  * Done in one query
  */
-func ReadSingleReport(ID string) (report types.Report, exists bool, err error) {
-	if err = database_handle.QueryRowx(READ_REPORT_OF_ID, ID).StructScan(&report); err != nil {
+func (store *SQLStore) ReadSingleReportContext(ctx context.Context, ID string) (report types.Report, exists bool, err error) {
+	if err = store.db().QueryRowxContext(ctx, READ_REPORT_OF_ID, ID).StructScan(&report); err != nil {
 		if err == sql.ErrNoRows {
 			err = nil
 		}
@@ -135,3 +265,8 @@ func ReadSingleReport(ID string) (report types.Report, exists bool, err error) {
 	exists = true
 	return
 }
+
+func (store *SQLStore) ReadSingleReport(ID string) (report types.Report, exists bool, err error) {
+	report, exists, err = store.ReadSingleReportContext(context.Background(), ID)
+	return
+}
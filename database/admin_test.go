@@ -296,3 +296,59 @@ func Test_ReadManyUnresolvedReport(test *testing.T) {
 		}
 	}
 }
+
+func Test_ReadManyUnresolvedReport_resolved(test *testing.T) {
+	EmptyTable(REPORT_TABLE)
+
+	var unresolved []string = make([]string, 3)
+	var report types.Report
+	var index int
+	for index = range unresolved {
+		report = types.NewReport(uuid.New().String(), uuid.New().String(), "user", "")
+		report.Resolved = true
+		WriteReport(report.Map())
+
+		report = types.NewReport(uuid.New().String(), uuid.New().String(), "user", "")
+		WriteReport(report.Map())
+		unresolved[len(unresolved)-1-index] = report.ID
+	}
+
+	var fetched []types.Report
+	var size int
+	var err error
+	if fetched, size, err = ReadManyUnresolvedReport("", 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != len(unresolved) {
+		test.Fatalf("unresolved count mismatch! have: %d, want: %d", size, len(unresolved))
+	}
+
+	for index, report = range fetched {
+		if report.Resolved || report.ID != unresolved[index] {
+			test.Errorf("report %d mismatch! have: %s (resolved: %t), want: %s", index, report.ID, report.Resolved, unresolved[index])
+		}
+	}
+
+	if fetched, size, err = ReadManyUnresolvedReport(unresolved[0], 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != len(unresolved)-1 || fetched[0].ID != unresolved[1] {
+		test.Errorf("unresolved reports after %s mismatch! have: %#v", unresolved[0], fetched)
+	}
+
+	report = fetched[0]
+	report.Resolved = true
+	if err = UpdateReport(report); err != nil {
+		test.Fatal(err)
+	}
+
+	if _, size, err = ReadManyUnresolvedReport("", 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != len(unresolved)-1 {
+		test.Errorf("resolved report %s still unresolved! have: %d, want: %d", report.ID, size, len(unresolved)-1)
+	}
+}
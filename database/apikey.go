@@ -0,0 +1,158 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/jmoiron/sqlx"
+
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+/**
+ * Something that a row of APIKEY_FIELDS is scanned from, which is either a sqlx.Row or sqlx.Rows
+ */
+type apiKeyScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+/**
+ * Scan some key of APIKEY_FIELDS from `row`, splitting the scopes that are stored separated by spaces
+ */
+func scanAPIKey(row apiKeyScanner) (key types.APIKey, err error) {
+	var scopes string
+	if err = row.Scan(&key.ID, &key.Owner, &key.Name, &scopes, &key.Created, &key.Expires, &key.LastUsed); err == nil {
+		key.Scopes = strings.Fields(scopes)
+	}
+
+	return
+}
+
+/**
+ * Create an API key for some user of id `ID` named `name`, that grants `scopes`
+ * Returns the token of the key, which expires `lifetime` seconds after it's created, or never if `lifetime` is 0
+ * Keys are kept until they're revoked, and aren't touched by logging out or by revoking sessions
+ * Only the hash of the token is stored, so that it's looked up by its hash
+ * Fails with ErrInvalid if `name` is empty or too long, if any scope is empty or has spaces in it,
+ * or if `lifetime` is negative
+ * Done in one query:
+ * 		write key: 		INSERT INTO APIKEY_TABLE (id, owner, token, name, scopes, created, expires, last_used) VALUES (..., hash(token), ...)
+ */
+func (store *SQLStore) CreateAPIKeyContext(ctx context.Context, ID, name string, scopes []string, lifetime int64) (token string, key types.APIKey, err error) {
+	if key, err = newAPIKey(ID, name, scopes, lifetime); err != nil {
+		return
+	}
+
+	var hash []byte
+	if token, hash, err = newToken(TOKEN_LENGTH); err != nil {
+		return
+	}
+
+	_, err = store.db().ExecContext(ctx, WRITE_APIKEY, key.ID, key.Owner, hash, key.Name, strings.Join(key.Scopes, " "), key.Created, key.Expires, key.LastUsed)
+	return
+}
+
+func (store *SQLStore) CreateAPIKey(ID, name string, scopes []string, lifetime int64) (token string, key types.APIKey, err error) {
+	token, key, err = store.CreateAPIKeyContext(context.Background(), ID, name, scopes, lifetime)
+	return
+}
+
+/**
+ * Read the API key of some token `token`, and whether or not it's valid
+ * A key is valid until it expires, if it does
+ * The key is marked as used now, unless it was already used within SESSION_TOUCH_INTERVAL
+ * Fails with ErrInvalidToken if `token` couldn't have been made by CreateAPIKey
+ * Uses up to 2 queries:
+ * 		read key: 		SELECT APIKEY_FIELDS FROM APIKEY_TABLE WHERE token=hash(token) LIMIT 1
+ * 		touch key: 		UPDATE APIKEY_TABLE SET last_used=now WHERE id=ID
+ */
+func (store *SQLStore) ReadAPIKeyOfTokenContext(ctx context.Context, token string) (key types.APIKey, valid bool, err error) {
+	var hash []byte
+	if hash, err = hashOfToken(token); err != nil {
+		return
+	}
+
+	if key, err = scanAPIKey(store.db().QueryRowxContext(ctx, READ_APIKEY_OF_TOKEN, hash)); err != nil {
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+
+		return
+	}
+
+	var now int64 = time.Now().Unix()
+	if valid = key.Expires == 0 || key.Expires >= now; valid && now-key.LastUsed >= SESSION_TOUCH_INTERVAL {
+		key.LastUsed = now
+		_, err = store.db().ExecContext(ctx, TOUCH_APIKEY_OF_ID, now, key.ID)
+	}
+
+	return
+}
+
+func (store *SQLStore) ReadAPIKeyOfToken(token string) (key types.APIKey, valid bool, err error) {
+	key, valid, err = store.ReadAPIKeyOfTokenContext(context.Background(), token)
+	return
+}
+
+/**
+ * Read every API key of some user of id `ID`, newest first, including those that have expired
+ * Done in one query:
+ * 		read keys: 		SELECT APIKEY_FIELDS FROM APIKEY_TABLE WHERE owner=ID ORDER BY order_index DESC
+ */
+func (store *SQLStore) ListAPIKeysContext(ctx context.Context, ID string) (keys []types.APIKey, err error) {
+	var rows *sqlx.Rows
+	if rows, err = store.db().QueryxContext(ctx, READ_APIKEYS_OF_OWNER, ID); err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	keys = make([]types.APIKey, 0)
+	for rows.Next() {
+		var key types.APIKey
+		if key, err = scanAPIKey(rows); err != nil {
+			return
+		}
+
+		keys = append(keys, key)
+	}
+
+	err = rows.Err()
+	return
+}
+
+func (store *SQLStore) ListAPIKeys(ID string) (keys []types.APIKey, err error) {
+	keys, err = store.ListAPIKeysContext(context.Background(), ID)
+	return
+}
+
+/**
+ * Revoke some API key of id `ID`, so that its token is no longer valid
+ * Done in one query:
+ * 		delete row: 	DELETE FROM APIKEY_TABLE WHERE id=ID LIMIT 1
+ */
+func (store *SQLStore) RevokeAPIKeyContext(ctx context.Context, ID string) (err error) {
+	_, err = store.db().ExecContext(ctx, DELETE_APIKEY_OF_ID, ID)
+	return
+}
+
+func (store *SQLStore) RevokeAPIKey(ID string) (err error) {
+	err = store.RevokeAPIKeyContext(context.Background(), ID)
+	return
+}
+
+/**
+ * Revoke every API key of some user of id `ID`
+ * Done in one query:
+ * 		delete rows: 	DELETE FROM APIKEY_TABLE WHERE owner=ID
+ */
+func (store *SQLStore) RevokeAPIKeysOfContext(ctx context.Context, ID string) (err error) {
+	_, err = store.db().ExecContext(ctx, DELETE_APIKEYS_OF_OWNER, ID)
+	return
+}
+
+func (store *SQLStore) RevokeAPIKeysOf(ID string) (err error) {
+	err = store.RevokeAPIKeysOfContext(context.Background(), ID)
+	return
+}
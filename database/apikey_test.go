@@ -0,0 +1,100 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/google/uuid"
+
+	"encoding/base64"
+	"testing"
+)
+
+func Test_CreateAPIKey(test *testing.T) {
+	var id string = uuid.New().String()
+
+	var token string
+	var key types.APIKey
+	var err error
+	if token, key, err = CreateAPIKey(id, "bot", []string{"content:write"}, 0); err != nil {
+		test.Fatal(err)
+	}
+
+	var read types.APIKey
+	var valid bool
+	if read, valid, err = ReadAPIKeyOfToken(token); err != nil {
+		test.Fatal(err)
+	}
+
+	if !valid || read.ID != key.ID || read.Owner != id || !read.HasScope("content:write") {
+		test.Errorf("key of %s is valid: %t, %#v", token, valid, read)
+	}
+
+	var stored []byte
+	if err = connected.handle.QueryRowx("SELECT token FROM "+APIKEY_TABLE+" WHERE id=?", key.ID).Scan(&stored); err != nil {
+		test.Fatal(err)
+	}
+
+	var bytes []byte
+	if bytes, err = base64.URLEncoding.DecodeString(token); err != nil {
+		test.Fatal(err)
+	}
+
+	if string(stored) != string(hashToken(bytes)) {
+		test.Errorf("%s is not stored as its hash", token)
+	}
+}
+
+func Test_ReadAPIKeyOfToken_expired(test *testing.T) {
+	var id string = uuid.New().String()
+
+	var token string
+	var err error
+	if token, _, err = CreateAPIKey(id, "bot", nil, 60); err != nil {
+		test.Fatal(err)
+	}
+
+	var statement string = "UPDATE " + APIKEY_TABLE + " SET expires=1 WHERE owner=?"
+	if _, err = connected.handle.Exec(statement, id); err != nil {
+		test.Fatal(err)
+	}
+
+	var valid bool
+	if _, valid, err = ReadAPIKeyOfToken(token); err != nil {
+		test.Fatal(err)
+	}
+
+	if valid {
+		test.Errorf("expired key %s is still valid!", token)
+	}
+}
+
+func Test_ReadAPIKeyOfToken_touch(test *testing.T) {
+	var id string = uuid.New().String()
+
+	var token string
+	var err error
+	if token, _, err = CreateAPIKey(id, "bot", nil, 0); err != nil {
+		test.Fatal(err)
+	}
+
+	var keys []types.APIKey
+	if keys, err = ListAPIKeys(id); err != nil {
+		test.Fatal(err)
+	}
+
+	if len(keys) != 1 || keys[0].LastUsed != 0 {
+		test.Errorf("unused key was marked as used, have %#v", keys)
+	}
+
+	var key types.APIKey
+	if key, _, err = ReadAPIKeyOfToken(token); err != nil {
+		test.Fatal(err)
+	}
+
+	if keys, err = ListAPIKeys(id); err != nil {
+		test.Fatal(err)
+	}
+
+	if len(keys) != 1 || keys[0].LastUsed != key.LastUsed || key.LastUsed == 0 {
+		test.Errorf("key was not touched, have %#v", keys)
+	}
+}
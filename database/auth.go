@@ -1,12 +1,17 @@
 package database
 
 import (
+	"github.com/brane-app/librane/types"
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/crypto/bcrypt"
 
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/base64"
+	"sync"
 	"time"
 )
 
@@ -15,8 +20,84 @@ const (
 	TOKEN_LENGTH  = 24
 	SECRET_LENGTH = 128
 	TOKEN_TTL     = 60 * 60 * 24
+	REFRESH_TTL   = 60 * 60 * 24 * 30
+
+	// How long after a session is marked as used that using it again is marked, so that not every read writes
+	SESSION_TOUCH_INTERVAL = 60
 )
 
+/**
+ * How long, in seconds, the tokens of sessions and the refresh tokens that renew them are valid for
+ */
+type tokenLifetimes struct {
+	access  int64
+	refresh int64
+}
+
+func defaultLifetimes() (lifetimes tokenLifetimes) {
+	lifetimes = tokenLifetimes{TOKEN_TTL, REFRESH_TTL}
+	return
+}
+
+/**
+ * Functions to tell of every session that's revoked, as added by OnSessionRevoked
+ * They're shared with every copy of a store, such as the one bound to a transaction
+ */
+type revocationHooks struct {
+	lock  sync.RWMutex
+	hooks []func(session string, until int64)
+}
+
+func (revocations *revocationHooks) add(revoked func(session string, until int64)) {
+	revocations.lock.Lock()
+	revocations.hooks = append(revocations.hooks, revoked)
+	revocations.lock.Unlock()
+}
+
+/**
+ * Tell every hook of each session of id in `sessions`, whose tokens are valid until at most `until`
+ */
+func (revocations *revocationHooks) tell(sessions []string, until int64) {
+	revocations.lock.RLock()
+	defer revocations.lock.RUnlock()
+
+	var revoked func(string, int64)
+	var session string
+	for _, revoked = range revocations.hooks {
+		for _, session = range sessions {
+			revoked(session, until)
+		}
+	}
+}
+
+/**
+ * Get `lifetimes` with those of `access` and `refresh`, keeping whichever are less than a second
+ */
+func (lifetimes tokenLifetimes) with(access, refresh time.Duration) (set tokenLifetimes) {
+	set = lifetimes
+	if access >= time.Second {
+		set.access = int64(access / time.Second)
+	}
+
+	if refresh >= time.Second {
+		set.refresh = int64(refresh / time.Second)
+	}
+
+	return
+}
+
+/**
+ * A token and the refresh token that renews it, as made by CreateTokenPair and RefreshTokenPair
+ * Both belong to `Session`, and expire at the unix times `Expires` and `RefreshExpires`
+ */
+type TokenPair struct {
+	Session        types.Session
+	Token          string
+	Expires        int64
+	Refresh        string
+	RefreshExpires int64
+}
+
 func randomBytes(size int) (generated []byte, err error) {
 	generated = make([]byte, size)
 	_, err = rand.Read(generated)
@@ -32,31 +113,106 @@ func randomString(size int) (generated string, err error) {
 	return
 }
 
+/**
+ * Hash some token or secret `bytes` as it's stored and looked up, so that what's stored can't be used to authenticate
+ * Tokens are random and long enough that neither a salt nor a slow hash would add anything
+ */
+func hashToken(bytes []byte) (hash []byte) {
+	var sum [sha256.Size]byte = sha256.Sum256(bytes)
+	hash = sum[:]
+	return
+}
+
+/**
+ * Make a new token of `size` random bytes, and the hash of it that's stored
+ */
+func newToken(size int) (token string, hash []byte, err error) {
+	var bytes []byte
+	if bytes, err = randomBytes(size); err == nil {
+		token, hash = base64.URLEncoding.EncodeToString(bytes), hashToken(bytes)
+	}
+
+	return
+}
+
+/**
+ * Get the hash of some token `token`, failing with ErrInvalidToken if it couldn't have been made by newToken
+ */
+func hashOfToken(token string) (hash []byte, err error) {
+	var bytes []byte
+	if bytes, err = base64.URLEncoding.DecodeString(token); err != nil {
+		err = &Error{ErrInvalidToken, err}
+		return
+	}
+
+	hash = hashToken(bytes)
+	return
+}
+
+/**
+ * Set how long tokens and refresh tokens made by this store are valid for, which are otherwise
+ * TOKEN_TTL and REFRESH_TTL, keeping whichever of `access` and `refresh` are less than a second
+ * Open sets them from a Config, and this should be called before the store is used
+ */
+func (store *SQLStore) SetTokenLifetimes(access, refresh time.Duration) {
+	store.lifetimes = store.lifetimes.with(access, refresh)
+}
+
+/**
+ * Have `revoked` told the id of every session that this store revokes, along with the unix time `until`
+ * that the last token of it expires, so that tokens that are checked without reading the store,
+ * such as those of a signed.Keyring, can be rejected once their session is revoked
+ * Sessions are told once they're revoked by RevokeSession, RevokeOtherSessions, RevokeToken, RevokeTokenOf,
+ * or RefreshTokenPair finding that a refresh token was used again, or as soon as they are inside of WithTx
+ * Only revocations made through this store are told, not those of other processes
+ */
+func (store *SQLStore) OnSessionRevoked(revoked func(session string, until int64)) {
+	store.revocations.add(revoked)
+}
+
+/**
+ * Tell whatever was given to OnSessionRevoked of every session of id in `sessions`
+ */
+func (store *SQLStore) sessionsRevoked(sessions []string) {
+	store.revocations.tell(sessions, time.Now().Unix()+store.lifetimes.access)
+}
+
 /**
  * Create a secret for some user of id `ID`
- * Any existing secret for that user is destroyed
+ * Any existing secret for that user is destroyed, and only the hash of the new one is stored
  * Done in one query:
- * 		update secret 	REPLACE INTO SECRET_TABLE (id, secret) VALUES ID, new_secret
+ * 		update secret 	REPLACE INTO SECRET_TABLE (id, secret) VALUES ID, hash(new_secret)
  */
-func CreateSecret(ID string) (secret string, err error) {
-	var bytes []byte
-	if bytes, err = randomBytes(SECRET_LENGTH); err != nil {
+func (store *SQLStore) CreateSecretContext(ctx context.Context, ID string) (secret string, err error) {
+	var hash []byte
+	if secret, hash, err = newToken(SECRET_LENGTH); err != nil {
 		return
 	}
 
-	secret = base64.URLEncoding.EncodeToString(bytes)
-	_, err = database_handle.Exec(WRITE_SECRET_OF_ID, ID, bytes)
+	_, err = store.db().ExecContext(ctx, WRITE_SECRET_OF_ID, ID, hash)
+	return
+}
+
+func (store *SQLStore) CreateSecret(ID string) (secret string, err error) {
+	secret, err = store.CreateSecretContext(context.Background(), ID)
 	return
 }
 
 /**
  * Check that a secret `secret` for some user of id `ID` matches
+ * Its hash is compared in constant time, so that how long it takes doesn't tell how much of it matched
  * Done in one query:
  * 		read secret: 	SELECT secret FROM SECRET_TABLE WHERE id=ID LIMIT 1
  */
-func CheckSecret(ID, secret string) (valid bool, err error) {
-	var bytes []byte
-	if err = database_handle.QueryRowx(READ_SECRET_OF_ID, ID).Scan(&bytes); err != nil {
+func (store *SQLStore) CheckSecretContext(ctx context.Context, ID, secret string) (valid bool, err error) {
+	var given []byte
+	if given, err = hashOfToken(secret); err != nil {
+		err = nil
+		return
+	}
+
+	var hash []byte
+	if err = store.db().QueryRowxContext(ctx, READ_SECRET_OF_ID, ID).Scan(&hash); err != nil {
 		if err == sql.ErrNoRows {
 			err = nil
 		}
@@ -64,7 +220,12 @@ func CheckSecret(ID, secret string) (valid bool, err error) {
 		return
 	}
 
-	valid = secret == base64.URLEncoding.EncodeToString(bytes)
+	valid = subtle.ConstantTimeCompare(hash, given) == 1
+	return
+}
+
+func (store *SQLStore) CheckSecret(ID, secret string) (valid bool, err error) {
+	valid, err = store.CheckSecretContext(context.Background(), ID, secret)
 	return
 }
 
@@ -73,84 +234,457 @@ func CheckSecret(ID, secret string) (valid bool, err error) {
  * Done in one query:
  * 		delete row: 	DELETE FROM SECRET_TABLE WHERE id=ID LIMIT 1
  */
-func RevokeSecretOf(ID string) (err error) {
-	_, err = database_handle.Exec(DELETE_SECRET_OF_ID, ID)
+func (store *SQLStore) RevokeSecretOfContext(ctx context.Context, ID string) (err error) {
+	_, err = store.db().ExecContext(ctx, DELETE_SECRET_OF_ID, ID)
+	return
+}
+
+func (store *SQLStore) RevokeSecretOf(ID string) (err error) {
+	err = store.RevokeSecretOfContext(context.Background(), ID)
 	return
 }
 
 /**
- * Create a token for some user of id `ID` that expires in 24 hours
- * Any existing token for that user is destroyed
+ * Create a session for some user of id `ID`, that's used on a device labeled `device` from the address `IP`
+ * Returns the token of the session, which expires TOKEN_TTL seconds after it's created unless set otherwise
+ * Other sessions of that user are kept, so that they may be logged in on many devices at once
+ * Only the hash of the token is stored, so that it's looked up by its hash
+ * Fails with ErrInvalid if `IP` isn't empty or an IP address
  * Done in one query:
- * 		update secret 	REPLACE INTO TOKEN_TABLE (id, token, created) VALUES ID, new_token, now
+ * 		write session: 	INSERT INTO SESSION_TABLE (id, owner, token, device, ip, created, last_used, issued) VALUES (..., hash(token), ...)
  */
-func CreateToken(ID string) (token string, expires int64, err error) {
-	var bytes []byte
-	if bytes, err = randomBytes(TOKEN_LENGTH); err != nil {
+func (store *SQLStore) CreateSessionContext(ctx context.Context, ID, device, IP string) (token string, session types.Session, err error) {
+	if session, err = newSession(ID, device, IP); err != nil {
+		return
+	}
+
+	var hash []byte
+	if token, hash, err = newToken(TOKEN_LENGTH); err != nil {
+		return
+	}
+
+	_, err = store.db().ExecContext(ctx, WRITE_SESSION, session.ID, session.Owner, hash, session.Device, session.IP, session.Created, session.LastUsed, session.Issued)
+	return
+}
+
+func (store *SQLStore) CreateSession(ID, device, IP string) (token string, session types.Session, err error) {
+	token, session, err = store.CreateSessionContext(context.Background(), ID, device, IP)
+	return
+}
+
+/**
+ * Create a token for some user of id `ID` that expires in TOKEN_TTL seconds, unless set otherwise
+ * This is a session of no device or address, see CreateSession
+ */
+func (store *SQLStore) CreateTokenContext(ctx context.Context, ID string) (token string, expires int64, err error) {
+	var session types.Session
+	if token, session, err = store.CreateSessionContext(ctx, ID, "", ""); err == nil {
+		expires = session.Issued + store.lifetimes.access
+	}
+
+	return
+}
+
+func (store *SQLStore) CreateToken(ID string) (token string, expires int64, err error) {
+	token, expires, err = store.CreateTokenContext(context.Background(), ID)
+	return
+}
+
+/**
+ * Read the session of some token `token`, and whether or not it's valid
+ * A token is valid for TOKEN_TTL seconds after it's issued, unless set otherwise
+ * The session is marked as used now, unless it was already used within SESSION_TOUCH_INTERVAL
+ * Fails with ErrInvalidToken if `token` couldn't have been made by CreateSession
+ * Uses up to 2 queries:
+ * 		read session: 	SELECT SESSION_FIELDS FROM SESSION_TABLE WHERE token=hash(token) LIMIT 1
+ * 		touch session: 	UPDATE SESSION_TABLE SET last_used=now WHERE id=ID
+ */
+func (store *SQLStore) ReadSessionOfTokenContext(ctx context.Context, token string) (session types.Session, valid bool, err error) {
+	var hash []byte
+	if hash, err = hashOfToken(token); err != nil {
+		return
+	}
+
+	if err = store.db().QueryRowxContext(ctx, READ_SESSION_OF_TOKEN, hash).StructScan(&session); err != nil {
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+
 		return
 	}
 
 	var now int64 = time.Now().Unix()
-	expires = now + TOKEN_TTL
-	token = base64.URLEncoding.EncodeToString(bytes)
-	_, err = database_handle.Exec(WRITE_TOKEN_OF_ID, ID, bytes, now)
+	if valid = session.Issued <= now && session.Issued+store.lifetimes.access >= now; valid && now-session.LastUsed >= SESSION_TOUCH_INTERVAL {
+		session.LastUsed = now
+		_, err = store.db().ExecContext(ctx, TOUCH_SESSION_OF_ID, now, session.ID)
+	}
+
+	return
+}
+
+func (store *SQLStore) ReadSessionOfToken(token string) (session types.Session, valid bool, err error) {
+	session, valid, err = store.ReadSessionOfTokenContext(context.Background(), token)
 	return
 }
 
 /**
- * Read information about some token `token`
- * Returns who it belongs to, and whether or not it's valid
- * done in one query:
- * 		read token: SELECT id, created FROM TOKEN_TABLE WHERE token=? LIMIT 1
+ * Read some session of id `ID`, and whether or not it exists
+ * It's read from the primary, as it's read to check that the session hasn't been revoked
+ * Done in one query:
+ * 		read session: 	SELECT SESSION_FIELDS FROM SESSION_TABLE WHERE id=ID LIMIT 1
  */
-func ReadTokenStat(token string) (owner string, valid bool, err error) {
-	var bytes []byte
-	if bytes, err = base64.URLEncoding.DecodeString(token); err != nil {
+func (store *SQLStore) ReadSessionContext(ctx context.Context, ID string) (session types.Session, exists bool, err error) {
+	if err = store.db().QueryRowxContext(ctx, READ_SESSION_OF_ID, ID).StructScan(&session); err == sql.ErrNoRows {
 		err = nil
 		return
 	}
 
+	exists = err == nil
+	return
+}
+
+func (store *SQLStore) ReadSession(ID string) (session types.Session, exists bool, err error) {
+	session, exists, err = store.ReadSessionContext(context.Background(), ID)
+	return
+}
+
+/**
+ * Read who some token `token` belongs to, and whether or not it's valid
+ * Works in the same way as ReadSessionOfToken
+ */
+func (store *SQLStore) ReadTokenStatContext(ctx context.Context, token string) (owner string, valid bool, err error) {
+	var session types.Session
+	session, valid, err = store.ReadSessionOfTokenContext(ctx, token)
+	owner = session.Owner
+	return
+}
+
+func (store *SQLStore) ReadTokenStat(token string) (owner string, valid bool, err error) {
+	owner, valid, err = store.ReadTokenStatContext(context.Background(), token)
+	return
+}
+
+/**
+ * Read every session of some user of id `ID`, newest first
+ * Done in one query:
+ * 		read sessions: 	SELECT SESSION_FIELDS FROM SESSION_TABLE WHERE owner=ID ORDER BY order_index DESC
+ */
+func (store *SQLStore) ListSessionsContext(ctx context.Context, ID string) (sessions []types.Session, err error) {
 	var rows *sqlx.Rows
-	if rows, err = database_handle.Queryx(READ_TOKEN_STAT, bytes); err != nil || rows == nil {
+	if rows, err = store.db().QueryxContext(ctx, READ_SESSIONS_OF_OWNER, ID); err != nil {
 		return
 	}
 
 	defer rows.Close()
 
-	if !rows.Next() {
+	sessions = make([]types.Session, 0)
+	for rows.Next() {
+		var session types.Session
+		if err = rows.StructScan(&session); err != nil {
+			return
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	err = rows.Err()
+	return
+}
+
+func (store *SQLStore) ListSessions(ID string) (sessions []types.Session, err error) {
+	sessions, err = store.ListSessionsContext(context.Background(), ID)
+	return
+}
+
+/**
+ * Revoke some session of id `ID`, so that neither its token nor its refresh tokens are valid
+ * Done in 2 queries:
+ * 		delete refresh: DELETE FROM REFRESH_TABLE WHERE session IN (ID)
+ * 		delete row: 	DELETE FROM SESSION_TABLE WHERE id IN (ID)
+ */
+func (store *SQLStore) RevokeSessionContext(ctx context.Context, ID string) (err error) {
+	err = store.inTx(ctx, func(bound *SQLStore) (err error) {
+		err = bound.revoke(ctx, []string{ID})
 		return
+	})
+
+	if err == nil {
+		store.sessionsRevoked([]string{ID})
 	}
 
-	var created int64
-	if err = rows.Scan(&owner, &created); err == nil {
-		valid = created <= time.Now().Unix() && created+TOKEN_TTL >= time.Now().Unix()
+	return
+}
+
+func (store *SQLStore) RevokeSession(ID string) (err error) {
+	err = store.RevokeSessionContext(context.Background(), ID)
+	return
+}
+
+/**
+ * Revoke every session of some user of id `ID` but the one of id `keep`, logging them out everywhere else
+ * Done in 3 queries:
+ * 		read ids: 		SELECT id FROM SESSION_TABLE WHERE owner=ID AND id<>keep
+ * 		queries from: 	revoke
+ */
+func (store *SQLStore) RevokeOtherSessionsContext(ctx context.Context, ID, keep string) (err error) {
+	err = store.revokeFound(ctx, READ_SESSION_IDS_OF_OWNER_EXCEPT, ID, keep)
+	return
+}
+
+func (store *SQLStore) RevokeOtherSessions(ID, keep string) (err error) {
+	err = store.RevokeOtherSessionsContext(context.Background(), ID, keep)
+	return
+}
+
+/**
+ * Revoke the session of some token `token`
+ * Done in 3 queries:
+ * 		read ids: 		SELECT id FROM SESSION_TABLE WHERE token=hash(token)
+ * 		queries from: 	revoke
+ */
+func (store *SQLStore) RevokeTokenContext(ctx context.Context, token string) (err error) {
+	var hash []byte
+	if hash, err = hashOfToken(token); err != nil {
+		return
 	}
 
+	err = store.revokeFound(ctx, READ_SESSION_IDS_OF_TOKEN, hash)
+	return
+}
+
+func (store *SQLStore) RevokeToken(token string) (err error) {
+	err = store.RevokeTokenContext(context.Background(), token)
 	return
 }
 
 /**
- * Revoke some token `token`
- * Done in one query:
- * 		delete row: 	DELETE FROM TOKEN_TABLE WHERE token=token LIMIT 1
+ * Revoke every session of some user of id `ID`, logging them out everywhere
+ * Done in 3 queries:
+ * 		read ids: 		SELECT id FROM SESSION_TABLE WHERE owner=ID
+ * 		queries from: 	revoke
  */
-func RevokeToken(token string) (err error) {
-	var bytes []byte
-	if bytes, err = base64.URLEncoding.DecodeString(token); err != nil {
+func (store *SQLStore) RevokeTokenOfContext(ctx context.Context, ID string) (err error) {
+	err = store.revokeFound(ctx, READ_SESSION_IDS_OF_OWNER, ID)
+	return
+}
+
+func (store *SQLStore) RevokeTokenOf(ID string) (err error) {
+	err = store.RevokeTokenOfContext(context.Background(), ID)
+	return
+}
+
+/**
+ * Revoke every session that the statement `read` finds given `values`, in one transaction
+ * The ids of the sessions are read first, and only those are deleted, so that every session
+ * that's revoked is told to OnSessionRevoked
+ * Done in 3 queries:
+ * 		read ids: 		read
+ * 		queries from: 	revoke
+ */
+func (store *SQLStore) revokeFound(ctx context.Context, read string, values ...interface{}) (err error) {
+	var revoked []string
+	err = store.inTx(ctx, func(bound *SQLStore) (err error) {
+		var rows *sql.Rows
+		if rows, err = bound.db().QueryContext(ctx, read, values...); err != nil {
+			return
+		}
+
+		defer rows.Close()
+
+		var ID string
+		for rows.Next() {
+			if err = rows.Scan(&ID); err != nil {
+				return
+			}
+
+			revoked = append(revoked, ID)
+		}
+
+		if err = rows.Err(); err == nil {
+			err = bound.revoke(ctx, revoked)
+		}
+
 		return
+	})
+
+	if err == nil {
+		store.sessionsRevoked(revoked)
 	}
 
-	_, err = database_handle.Exec(DELETE_TOKEN, bytes)
 	return
 }
 
 /**
- * Revoke the token of some user of id `ID`
- * Done in one query:
- * 		delete row: 	DELETE FROM TOKEN_TABLE WHERE id=ID LIMIT 1
+ * Delete every session of id in `IDs`, along with their refresh tokens
+ * Refresh tokens go first, as they're found by the sessions that they belong to
+ * Done in 2 queries:
+ * 		delete refresh: DELETE FROM REFRESH_TABLE WHERE session IN (IDs...)
+ * 		delete rows: 	DELETE FROM SESSION_TABLE WHERE id IN (IDs...)
  */
-func RevokeTokenOf(ID string) (err error) {
-	_, err = database_handle.Exec(DELETE_TOKEN_OF_ID, ID)
+func (store *SQLStore) revoke(ctx context.Context, IDs []string) (err error) {
+	if len(IDs) == 0 {
+		return
+	}
+
+	var paramString string = "(" + manyParamString("?", len(IDs)) + ")"
+	var values []interface{} = interfaceStrings(IDs...)
+	if _, err = store.db().ExecContext(ctx, DELETE_REFRESH_OF_MANY_SESSION+paramString, values...); err == nil {
+		_, err = store.db().ExecContext(ctx, DELETE_SESSIONS_OF_MANY_ID+paramString, values...)
+	}
+
+	return
+}
+
+/**
+ * Write a new refresh token of some session of id `ID`, issued at `now`, storing only its hash
+ */
+func (store *SQLStore) writeRefresh(ctx context.Context, ID string, now int64) (refresh string, err error) {
+	var hash []byte
+	if refresh, hash, err = newToken(TOKEN_LENGTH); err == nil {
+		_, err = store.db().ExecContext(ctx, WRITE_REFRESH, hash, ID, now)
+	}
+
+	return
+}
+
+/**
+ * Create a session for some user of id `ID` in the same way as CreateSession, along with a refresh token
+ * that RefreshTokenPair trades for a new pair once the token expires
+ * The refresh token expires REFRESH_TTL seconds after it's issued, unless set otherwise
+ * Fails with ErrInvalid if `IP` isn't empty or an IP address
+ * Done in 2 queries:
+ * 		write session: 	INSERT INTO SESSION_TABLE (id, owner, token, device, ip, created, last_used, issued) VALUES (...)
+ * 		write refresh: 	INSERT INTO REFRESH_TABLE (token, session, created, used) VALUES (hash(refresh), session, now, FALSE)
+ */
+func (store *SQLStore) CreateTokenPairContext(ctx context.Context, ID, device, IP string) (pair TokenPair, err error) {
+	err = store.inTx(ctx, func(bound *SQLStore) (err error) {
+		if pair.Token, pair.Session, err = bound.CreateSessionContext(ctx, ID, device, IP); err == nil {
+			pair.Refresh, err = bound.writeRefresh(ctx, pair.Session.ID, pair.Session.Issued)
+		}
+
+		return
+	})
+
+	if err == nil {
+		pair.Expires = pair.Session.Issued + store.lifetimes.access
+		pair.RefreshExpires = pair.Session.Issued + store.lifetimes.refresh
+	}
+
+	return
+}
+
+func (store *SQLStore) CreateTokenPair(ID, device, IP string) (pair TokenPair, err error) {
+	pair, err = store.CreateTokenPairContext(context.Background(), ID, device, IP)
+	return
+}
+
+/**
+ * Trade some refresh token `refresh` for a new token and refresh token of the same session
+ * The old token and refresh token are no longer valid once traded, so each refresh token is used only once
+ * A refresh token that's used again is taken to be stolen, and the session that it belongs to is revoked,
+ * along with every refresh token of it, so that neither whoever stole it nor its owner may use them
+ * Fails with ErrReusedToken if `refresh` was already traded, or ErrInvalidToken if it's expired,
+ * its session was revoked, or it couldn't have been made by CreateTokenPair
+ * Uses up to 6 queries:
+ * 		read refresh: 	SELECT session, created FROM REFRESH_TABLE WHERE token=hash(refresh) LIMIT 1
+ * 		use refresh: 	UPDATE REFRESH_TABLE SET used=TRUE WHERE token=hash(refresh) AND NOT used
+ * 		rotate session: UPDATE SESSION_TABLE SET token=hash(new_token), issued=now, last_used=now WHERE id=session
+ * 		forget used: 	DELETE FROM REFRESH_TABLE WHERE session=session AND used AND created<now-REFRESH_TTL
+ * 		write refresh: 	INSERT INTO REFRESH_TABLE (token, session, created, used) VALUES (hash(new_refresh), session, now, FALSE)
+ * 		read session: 	SELECT SESSION_FIELDS FROM SESSION_TABLE WHERE id=session LIMIT 1
+ * or if `refresh` was already used, 4 queries:
+ * 		read refresh, use refresh, and
+ * 		delete refresh: DELETE FROM REFRESH_TABLE WHERE session IN (session)
+ * 		delete session: DELETE FROM SESSION_TABLE WHERE id IN (session)
+ */
+func (store *SQLStore) RefreshTokenPairContext(ctx context.Context, refresh string) (pair TokenPair, err error) {
+	var hash []byte
+	if hash, err = hashOfToken(refresh); err != nil {
+		return
+	}
+
+	var reused bool
+	var revoked string
+	var now int64 = time.Now().Unix()
+	err = store.inTx(ctx, func(bound *SQLStore) (err error) {
+		var ID string
+		var created int64
+		if err = bound.db().QueryRowxContext(ctx, READ_REFRESH_OF_TOKEN, hash).Scan(&ID, &created); err == sql.ErrNoRows {
+			err = errorOf(ErrInvalidToken, "Refresh token does not exist")
+			return
+		} else if err != nil {
+			return
+		}
+
+		var result sql.Result
+		if result, err = bound.db().ExecContext(ctx, USE_REFRESH_OF_TOKEN, hash); err != nil {
+			return
+		}
+
+		var used int64
+		if used, err = result.RowsAffected(); err != nil {
+			return
+		}
+
+		if reused = used == 0; reused {
+			revoked = ID
+			err = bound.revoke(ctx, []string{ID})
+			return
+		}
+
+		if created+store.lifetimes.refresh < now {
+			err = errorOf(ErrInvalidToken, "Refresh token expired at %d", created+store.lifetimes.refresh)
+			return
+		}
+
+		var tokenHash []byte
+		if pair.Token, tokenHash, err = newToken(TOKEN_LENGTH); err != nil {
+			return
+		}
+
+		if result, err = bound.db().ExecContext(ctx, ROTATE_SESSION_OF_ID, tokenHash, now, now, ID); err != nil {
+			return
+		}
+
+		var rotated int64
+		if rotated, err = result.RowsAffected(); err != nil {
+			return
+		}
+
+		if rotated == 0 {
+			err = errorOf(ErrInvalidToken, "Session %s was revoked", ID)
+			return
+		}
+
+		if _, err = bound.db().ExecContext(ctx, DELETE_REFRESH_USED_OF_SESSION, ID, now-store.lifetimes.refresh); err != nil {
+			return
+		}
+
+		if pair.Refresh, err = bound.writeRefresh(ctx, ID, now); err != nil {
+			return
+		}
+
+		err = bound.db().QueryRowxContext(ctx, READ_SESSION_OF_ID, ID).StructScan(&pair.Session)
+		return
+	})
+
+	if err == nil && reused {
+		store.sessionsRevoked([]string{revoked})
+		err = errorOf(ErrReusedToken, "Refresh token was already used, its session is revoked")
+	}
+
+	if err == nil {
+		pair.Expires = now + store.lifetimes.access
+		pair.RefreshExpires = now + store.lifetimes.refresh
+	} else {
+		pair = TokenPair{}
+	}
+
+	return
+}
+
+func (store *SQLStore) RefreshTokenPair(refresh string) (pair TokenPair, err error) {
+	pair, err = store.RefreshTokenPairContext(context.Background(), refresh)
 	return
 }
 
@@ -159,9 +693,9 @@ func RevokeTokenOf(ID string) (err error) {
  * Done in one query:
  *  		read hash: 		SELECT hash FROM AUTH_TABLE WHERE id=ID LIMIT 1
  */
-func CheckPassword(ID, password string) (valid bool, err error) {
+func (store *SQLStore) CheckPasswordContext(ctx context.Context, ID, password string) (valid bool, err error) {
 	var hash []byte
-	if err = database_handle.QueryRowx(READ_HASH_OF_ID, ID).Scan(&hash); err != nil {
+	if err = store.db().QueryRowxContext(ctx, READ_HASH_OF_ID, ID).Scan(&hash); err != nil {
 		if err == sql.ErrNoRows {
 			err = nil
 		}
@@ -173,18 +707,28 @@ func CheckPassword(ID, password string) (valid bool, err error) {
 	return
 }
 
+func (store *SQLStore) CheckPassword(ID, password string) (valid bool, err error) {
+	valid, err = store.CheckPasswordContext(context.Background(), ID, password)
+	return
+}
+
 /**
  * Set a password `password` for some user of id `ID`
  * Done in one query:
  * 		write row:		REPLACE INTO AUTH_TABLE (id, hash) VALUES (ID, hash(password))
  */
-func SetPassword(ID, password string) (err error) {
+func (store *SQLStore) SetPasswordContext(ctx context.Context, ID, password string) (err error) {
 	var hash []byte
 	if hash, err = bcrypt.GenerateFromPassword([]byte(password), BCRYPT_ITERS); err != nil {
 		return
 	}
 
-	_, err = database_handle.Exec(WRITE_HASH_OF_ID, ID, hash)
+	_, err = store.db().ExecContext(ctx, WRITE_HASH_OF_ID, ID, hash)
+
+	return
+}
 
+func (store *SQLStore) SetPassword(ID, password string) (err error) {
+	err = store.SetPasswordContext(context.Background(), ID, password)
 	return
 }
@@ -1,9 +1,11 @@
 package database
 
 import (
+	"github.com/brane-app/librane/types"
 	"github.com/google/uuid"
 
 	"encoding/base64"
+	"errors"
 	"testing"
 	"time"
 )
@@ -228,23 +230,124 @@ func Test_ReadTokenStat_expired(test *testing.T) {
 		test.Fatal(err)
 	}
 
-	var bytes []byte
-	if bytes, err = base64.URLEncoding.DecodeString(token); err != nil {
+	var statement string = "UPDATE " + SESSION_TABLE + " SET issued=1 WHERE owner=?"
+	if _, err = connected.handle.Exec(statement, id); err != nil {
 		test.Fatal(err)
 	}
 
-	var statement string = "REPLACE INTO " + TOKEN_TABLE + " (id, token, created) VALUES (?, ?, ?)"
-	if _, err = database_handle.Exec(statement, id, bytes, 1); err != nil {
+	var valid bool
+	if _, valid, err = ReadTokenStat(token); err != nil {
+		test.Fatal(err)
+	}
+
+	if valid {
+		test.Errorf("expired token %s is still valid!", token)
+	}
+}
+
+func Test_ReadSessionOfToken_touch(test *testing.T) {
+	var id string = uuid.New().String()
+
+	var token string
+	var err error
+	if token, _, err = CreateToken(id); err != nil {
+		test.Fatal(err)
+	}
+
+	var statement string = "UPDATE " + SESSION_TABLE + " SET last_used=1 WHERE owner=?"
+	if _, err = connected.handle.Exec(statement, id); err != nil {
+		test.Fatal(err)
+	}
+
+	var session types.Session
+	if session, _, err = ReadSessionOfToken(token); err != nil {
+		test.Fatal(err)
+	}
+
+	var sessions []types.Session
+	if sessions, err = ListSessions(id); err != nil {
+		test.Fatal(err)
+	}
+
+	if len(sessions) != 1 || sessions[0].LastUsed != session.LastUsed || session.LastUsed == 1 {
+		test.Errorf("session was not touched, have %#v", sessions)
+	}
+}
+
+func Test_SetTokenLifetimes(test *testing.T) {
+	var store *SQLStore = freshSQLite(test)
+	store.SetTokenLifetimes(time.Hour, 0)
+
+	var pair TokenPair
+	var err error
+	if pair, err = store.CreateTokenPair(uuid.New().String(), "", ""); err != nil {
+		test.Fatal(err)
+	}
+
+	if pair.Expires != pair.Session.Issued+60*60 {
+		test.Errorf("token issued at %d expires at %d, not an hour later", pair.Session.Issued, pair.Expires)
+	}
+
+	if pair.RefreshExpires != pair.Session.Issued+REFRESH_TTL {
+		test.Errorf("refresh token issued at %d expires at %d, not REFRESH_TTL later", pair.Session.Issued, pair.RefreshExpires)
+	}
+
+	if _, err = store.handle.Exec("UPDATE "+SESSION_TABLE+" SET issued=? WHERE id=?", pair.Session.Issued-2*60*60, pair.Session.ID); err != nil {
 		test.Fatal(err)
 	}
 
 	var valid bool
-	if _, valid, err = ReadTokenStat(token); err != nil {
+	if _, valid, err = store.ReadTokenStat(pair.Token); err != nil {
 		test.Fatal(err)
 	}
 
 	if valid {
-		test.Errorf("expired token %s is still valid!", token)
+		test.Errorf("token issued two hours ago is valid for an hour")
+	}
+
+	store.SetTokenLifetimes(0, time.Minute)
+	if _, err = store.handle.Exec("UPDATE "+REFRESH_TABLE+" SET created=? WHERE session=?", pair.Session.Issued-2*60, pair.Session.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if _, err = store.RefreshTokenPair(pair.Refresh); !errors.Is(err, ErrInvalidToken) {
+		test.Errorf("refresh token issued two minutes ago is valid for a minute, err: %v", err)
+	}
+}
+
+func Test_CreateToken_hashed(test *testing.T) {
+	var id string = uuid.New().String()
+
+	var token, secret string
+	var err error
+	if token, _, err = CreateToken(id); err != nil {
+		test.Fatal(err)
+	}
+
+	if secret, err = CreateSecret(id); err != nil {
+		test.Fatal(err)
+	}
+
+	var cases [][2]string = [][2]string{
+		[2]string{"SELECT token FROM " + SESSION_TABLE + " WHERE owner=?", token},
+		[2]string{"SELECT secret FROM " + SECRET_TABLE + " WHERE id=?", secret},
+	}
+
+	var it [2]string
+	for _, it = range cases {
+		var stored []byte
+		if err = connected.handle.QueryRowx(it[0], id).Scan(&stored); err != nil {
+			test.Fatal(err)
+		}
+
+		var bytes []byte
+		if bytes, err = base64.URLEncoding.DecodeString(it[1]); err != nil {
+			test.Fatal(err)
+		}
+
+		if string(stored) != string(hashToken(bytes)) {
+			test.Errorf("%s is not stored as its hash", it[1])
+		}
 	}
 }
 
@@ -269,8 +372,8 @@ func Test_ReadTokenStat_nobody(test *testing.T) {
 func Test_ReadTokenStat_err(test *testing.T) {
 	var valid bool
 	var err error
-	if _, _, err = ReadTokenStat("f"); err != nil {
-		test.Fatal(err)
+	if _, valid, err = ReadTokenStat("f"); !errors.Is(err, ErrInvalidToken) {
+		test.Errorf("invalid base64 was not ErrInvalidToken, have: %v", err)
 	}
 
 	if valid {
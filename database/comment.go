@@ -0,0 +1,219 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/jmoiron/sqlx"
+
+	"context"
+	"database/sql"
+	"time"
+)
+
+/**
+ * Write a new comment `comment`, and count it towards the comment_count of its content
+ * If the comment has a parent, that parent must be a comment on the same content
+ * Fails with ErrNotFound if its content doesn't exist, or its parent isn't on that content
+ * Its content is locked so that it can't be deleted before the comment is counted
+ * Done in one transaction of up to 4 queries
+ * 		queries from: 	lockRow
+ * 		read parent: 	SELECT content FROM COMMENT_TABLE WHERE id=parent LIMIT 1
+ * 		write comment: 	INSERT INTO COMMENT_TABLE (fields...) VALUES (values...)
+ * 		count comment: 	UPDATE CONTENT_TABLE SET comment_count=comment_count+1 WHERE id=content
+ */
+func (store *SQLStore) WriteCommentContext(ctx context.Context, comment types.Comment) (err error) {
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		if err = lockRow(ctx, tx, CONTENT_TABLE, comment.Content); err != nil {
+			return
+		}
+
+		if comment.Parent != "" {
+			var parentContent string
+			if err = tx.QueryRowxContext(ctx, READ_CONTENT_OF_COMMENT, comment.Parent).Scan(&parentContent); err != nil && err != sql.ErrNoRows {
+				return
+			}
+
+			if parentContent != comment.Content {
+				err = errorOf(ErrNotFound, "Parent comment %s does not exist on content %s", comment.Parent, comment.Content)
+				return
+			}
+		}
+
+		if _, err = tx.ExecContext(ctx,
+			WRITE_COMMENT,
+			comment.ID, comment.Content, comment.Author, comment.Parent,
+			comment.Body, comment.Created, comment.Edited, comment.Deleted,
+		); err != nil {
+			return
+		}
+
+		_, err = tx.ExecContext(ctx, INCREMENT_CONTENT_COMMENT_COUNT_OF_ID, comment.Content)
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) WriteComment(comment types.Comment) (err error) {
+	err = store.WriteCommentContext(context.Background(), comment)
+	return
+}
+
+/**
+ * Edit the body of some comment of id `ID`
+ * Deleted comments can not be edited, so fail with ErrNotFound like those that don't exist
+ * Done in one transaction of 2 queries
+ * 		read content: 	SELECT content FROM COMMENT_TABLE WHERE id=ID AND NOT deleted LIMIT 1 FOR UPDATE
+ * 		write body: 	UPDATE COMMENT_TABLE SET body=body, edited=now WHERE id=ID AND NOT deleted
+ */
+func (store *SQLStore) EditCommentContext(ctx context.Context, ID, body string) (err error) {
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		var content string
+		if err = tx.QueryRowxContext(ctx, READ_CONTENT_OF_LIVE_COMMENT, ID).Scan(&content); err == sql.ErrNoRows {
+			err = errorOf(ErrNotFound, "Comment %s does not exist", ID)
+		}
+
+		if err != nil {
+			return
+		}
+
+		_, err = tx.ExecContext(ctx, WRITE_COMMENT_BODY_OF_ID, body, time.Now().Unix(), ID)
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) EditComment(ID, body string) (err error) {
+	err = store.EditCommentContext(context.Background(), ID, body)
+	return
+}
+
+/**
+ * Soft-delete some comment of id `ID`
+ * The comment is kept so that its replies stay threaded, but its body is cleared
+ * and it no longer counts towards the comment_count of its content
+ * Done in one transaction of up to 3 queries
+ * 		read content: 	SELECT content FROM COMMENT_TABLE WHERE id=ID AND NOT deleted LIMIT 1 FOR UPDATE
+ * 		delete: 		UPDATE COMMENT_TABLE SET body='', deleted=TRUE WHERE id=ID
+ * 		uncount: 		UPDATE CONTENT_TABLE SET comment_count=comment_count-1 WHERE id=content
+ */
+func (store *SQLStore) DeleteCommentContext(ctx context.Context, ID string) (err error) {
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		var content string
+		if err = tx.QueryRowxContext(ctx, READ_CONTENT_OF_LIVE_COMMENT, ID).Scan(&content); err != nil {
+			if err == sql.ErrNoRows {
+				err = nil
+			}
+
+			return
+		}
+
+		if _, err = tx.ExecContext(ctx, DELETE_COMMENT_OF_ID, ID); err != nil {
+			return
+		}
+
+		_, err = tx.ExecContext(ctx, DECREMENT_CONTENT_COMMENT_COUNT_OF_ID, content)
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) DeleteComment(ID string) (err error) {
+	err = store.DeleteCommentContext(context.Background(), ID)
+	return
+}
+
+/**
+ * Read a single comment of id `ID`
+ * Done in one query
+ */
+func (store *SQLStore) ReadSingleCommentContext(ctx context.Context, ID string) (comment types.Comment, exists bool, err error) {
+	store = store.reader(ctx)
+	if err = store.db().QueryRowxContext(ctx, READ_COMMENT_OF_ID, ID).StructScan(&comment); err != nil {
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+
+		return
+	}
+
+	exists = true
+	return
+}
+
+func (store *SQLStore) ReadSingleComment(ID string) (comment types.Comment, exists bool, err error) {
+	comment, exists, err = store.ReadSingleCommentContext(context.Background(), ID)
+	return
+}
+
+func readManyComment(rows *sqlx.Rows, count int) (comments []types.Comment, size int, err error) {
+	defer rows.Close()
+
+	comments = make([]types.Comment, count)
+	size = 0
+	for rows.Next() {
+		if err = rows.StructScan(&comments[size]); err != nil {
+			break
+		}
+
+		size++
+	}
+
+	comments = comments[:size]
+	return
+}
+
+/**
+ * Read `count` top level comments on some content of id `ID`, before comment of id `before`
+ * If the first set of comments should be read, `before` may be empty
+ * Newest comments are returned first
+ * Done in one query
+ */
+func (store *SQLStore) ReadCommentsContext(ctx context.Context, ID, before string, count int) (comments []types.Comment, size int, err error) {
+	store = store.reader(ctx)
+	var rows *sqlx.Rows
+	if before == "" {
+		rows, err = store.db().QueryxContext(ctx, READ_COMMENTS_OF_CONTENT, ID, count)
+	} else {
+		rows, err = store.db().QueryxContext(ctx, READ_COMMENTS_OF_CONTENT_AFTER_ID, ID, before, count)
+	}
+
+	if err != nil {
+		return
+	}
+
+	comments, size, err = readManyComment(rows, count)
+	return
+}
+
+func (store *SQLStore) ReadComments(ID, before string, count int) (comments []types.Comment, size int, err error) {
+	comments, size, err = store.ReadCommentsContext(context.Background(), ID, before, count)
+	return
+}
+
+/**
+ * Same as ReadComments, but for the replies to some comment of id `ID`
+ * Done in one query
+ */
+func (store *SQLStore) ReadRepliesContext(ctx context.Context, ID, before string, count int) (comments []types.Comment, size int, err error) {
+	store = store.reader(ctx)
+	var rows *sqlx.Rows
+	if before == "" {
+		rows, err = store.db().QueryxContext(ctx, READ_REPLIES_OF_COMMENT, ID, count)
+	} else {
+		rows, err = store.db().QueryxContext(ctx, READ_REPLIES_OF_COMMENT_AFTER_ID, ID, before, count)
+	}
+
+	if err != nil {
+		return
+	}
+
+	comments, size, err = readManyComment(rows, count)
+	return
+}
+
+func (store *SQLStore) ReadReplies(ID, before string, count int) (comments []types.Comment, size int, err error) {
+	comments, size, err = store.ReadRepliesContext(context.Background(), ID, before, count)
+	return
+}
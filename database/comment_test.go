@@ -0,0 +1,265 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/google/uuid"
+
+	"errors"
+	"testing"
+)
+
+func commentCountOK(test *testing.T, ID string, count int) {
+	var fetched types.Content
+	var err error
+	if fetched, _, err = ReadSingleContent(ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if fetched.CommentCount != count {
+		test.Errorf("comment count mismatch for %s! have: %d, want: %d", ID, fetched.CommentCount, count)
+	}
+}
+
+func Test_WriteComment(test *testing.T) {
+	var ID string = populateSingle(test)
+	var comment types.Comment = types.NewComment(ID, uuid.New().String(), "", "first")
+
+	var err error
+	if err = WriteComment(comment); err != nil {
+		test.Fatal(err)
+	}
+
+	var fetched types.Comment
+	var exists bool
+	if fetched, exists, err = ReadSingleComment(comment.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if !exists {
+		test.Errorf("comment %s does not exist", comment.ID)
+	}
+
+	if fetched.Body != comment.Body {
+		test.Errorf("body mismatch! have: %s, want: %s", fetched.Body, comment.Body)
+	}
+
+	commentCountOK(test, ID, 1)
+}
+
+func Test_WriteComment_reply(test *testing.T) {
+	var ID string = populateSingle(test)
+	var parent types.Comment = types.NewComment(ID, uuid.New().String(), "", "first")
+	var reply types.Comment = types.NewComment(ID, uuid.New().String(), parent.ID, "second")
+
+	var err error
+	if err = WriteComment(parent); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = WriteComment(reply); err != nil {
+		test.Fatal(err)
+	}
+
+	commentCountOK(test, ID, 2)
+}
+
+func Test_WriteComment_err(test *testing.T) {
+	var ID, other string = populateSingle(test), populateSingle(test)
+	var parent types.Comment = types.NewComment(other, uuid.New().String(), "", "first")
+
+	var err error
+	if err = WriteComment(parent); err != nil {
+		test.Fatal(err)
+	}
+
+	var comments []types.Comment = []types.Comment{
+		types.NewComment(ID, uuid.New().String(), uuid.New().String(), "orphan"),
+		types.NewComment(ID, uuid.New().String(), parent.ID, "elsewhere"),
+	}
+
+	var comment types.Comment
+	for _, comment = range comments {
+		if err = WriteComment(comment); err == nil {
+			test.Errorf("comment %#v produced no error!", comment)
+		}
+	}
+
+	if err = WriteComment(parent); err == nil {
+		test.Errorf("duplicate comment %s produced no error!", parent.ID)
+	}
+
+	commentCountOK(test, ID, 0)
+	commentCountOK(test, other, 1)
+}
+
+func Test_EditComment(test *testing.T) {
+	var ID string = populateSingle(test)
+	var comment types.Comment = types.NewComment(ID, uuid.New().String(), "", "first")
+	WriteComment(comment)
+
+	var body string = "edited"
+	var err error
+	if err = EditComment(comment.ID, body); err != nil {
+		test.Fatal(err)
+	}
+
+	var fetched types.Comment
+	if fetched, _, err = ReadSingleComment(comment.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if fetched.Body != body {
+		test.Errorf("body mismatch! have: %s, want: %s", fetched.Body, body)
+	}
+
+	if fetched.Edited == 0 {
+		test.Errorf("edited comment %s has no edit time", comment.ID)
+	}
+}
+
+func Test_DeleteComment(test *testing.T) {
+	var ID string = populateSingle(test)
+	var comment types.Comment = types.NewComment(ID, uuid.New().String(), "", "first")
+	WriteComment(comment)
+
+	var err error
+	if err = DeleteComment(comment.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = DeleteComment(comment.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	commentCountOK(test, ID, 0)
+
+	if err = EditComment(comment.ID, "undead"); !errors.Is(err, ErrNotFound) {
+		test.Errorf("edit of deleted comment %s was not ErrNotFound, have: %v", comment.ID, err)
+	}
+
+	var fetched types.Comment
+	var exists bool
+	if fetched, exists, err = ReadSingleComment(comment.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if !exists {
+		test.Errorf("soft-deleted comment %s does not exist", comment.ID)
+	}
+
+	if !fetched.Deleted {
+		test.Errorf("comment %s is not deleted", comment.ID)
+	}
+
+	if fetched.Body != "" {
+		test.Errorf("deleted comment %s has body %s", comment.ID, fetched.Body)
+	}
+}
+
+func Test_ReadSingleComment_nobody(test *testing.T) {
+	var exists bool
+	var err error
+	if _, exists, err = ReadSingleComment(uuid.New().String()); err != nil {
+		test.Fatal(err)
+	}
+
+	if exists {
+		test.Errorf("random uuid got some comment")
+	}
+}
+
+func Test_ReadComments(test *testing.T) {
+	var ID, other string = populateSingle(test), populateSingle(test)
+	var parent types.Comment = types.NewComment(ID, uuid.New().String(), "", "parent")
+	WriteComment(parent)
+
+	var index, population int = 0, 20
+	for index != population {
+		WriteComment(types.NewComment(ID, uuid.New().String(), "", "top"))
+		WriteComment(types.NewComment(ID, uuid.New().String(), parent.ID, "reply"))
+		WriteComment(types.NewComment(other, uuid.New().String(), "", "elsewhere"))
+		index++
+	}
+
+	var count int = 10
+	var comments []types.Comment
+	var size int
+	var err error
+	if comments, size, err = ReadComments(ID, "", count); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != count {
+		test.Errorf("size mismatch! have: %d, want: %d", size, count)
+	}
+
+	if len(comments) != size {
+		test.Errorf("block size mismatch! have: %d, want: %d", len(comments), size)
+	}
+
+	var single types.Comment
+	for _, single = range comments {
+		if single.Content != ID || single.Parent != "" {
+			test.Errorf("comment %#v is not top level on %s", single, ID)
+		}
+	}
+}
+
+func Test_ReadComments_after(test *testing.T) {
+	var ID string = populateSingle(test)
+
+	var index, population int = 0, 20
+	for index != population {
+		WriteComment(types.NewComment(ID, uuid.New().String(), "", "top"))
+		index++
+	}
+
+	var count, offset int = 10, 5
+	var first, second []types.Comment
+	var err error
+	if first, _, err = ReadComments(ID, "", count); err != nil {
+		test.Fatal(err)
+	}
+
+	if second, _, err = ReadComments(ID, first[offset].ID, count); err != nil {
+		test.Fatal(err)
+	}
+
+	var single types.Comment
+	for index, single = range first[offset+1:] {
+		if single.ID != second[index].ID {
+			test.Errorf("IDs not aligned! have: %s, want: %s", second[index].ID, single.ID)
+		}
+	}
+}
+
+func Test_ReadReplies(test *testing.T) {
+	var ID string = populateSingle(test)
+	var parent types.Comment = types.NewComment(ID, uuid.New().String(), "", "parent")
+	WriteComment(parent)
+
+	var index, population int = 0, 5
+	for index != population {
+		WriteComment(types.NewComment(ID, uuid.New().String(), "", "top"))
+		WriteComment(types.NewComment(ID, uuid.New().String(), parent.ID, "reply"))
+		index++
+	}
+
+	var comments []types.Comment
+	var size int
+	var err error
+	if comments, size, err = ReadReplies(parent.ID, "", 20); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != population {
+		test.Errorf("size mismatch! have: %d, want: %d", size, population)
+	}
+
+	var single types.Comment
+	for _, single = range comments {
+		if single.Parent != parent.ID {
+			test.Errorf("parent mismatch! have: %s, want: %s", single.Parent, parent.ID)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package database
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+)
+
+const (
+	RETRY_BACKOFF     = time.Second
+	RETRY_BACKOFF_MAX = 30 * time.Second
+)
+
+/**
+ * How to open a database with Open
+ * Everything but Address may be left as its zero value for the defaults of database/sql and the driver
+ */
+type Config struct {
+	// Connection string, in any form that Connect takes
+	Address string
+
+	// Limits of the connection pool
+	// SQLite always keeps to one open connection, and fails to open if MaxOpenConns is more
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// How many more times to ping the database if it can't be reached at first,
+	// waiting RetryBackoff before the first retry, and twice as long before each one after
+	Retries      int
+	RetryBackoff time.Duration
+
+	// TLS to use with MariaDB, or the sslmode to use with Postgres,
+	// which is given sslrootcert, sslcert, and sslkey in its Address instead
+	TLS *tls.Config
+
+	// How long to wait on a read before giving up, which is a statement_timeout for Postgres
+	ReadTimeout time.Duration
+
+	// Whether or not to migrate the schema to the latest version once connected
+	Migrate bool
+
+	// Connection strings of read replicas of Address, which must be of the same database
+	// Every other option applies to them too, though they aren't retried or migrated
+	Replicas []string
+
+	// How often to check which replicas are up, REPLICA_CHECK_INTERVAL if not set
+	ReplicaCheckInterval time.Duration
+
+	// How long reads with a context from ReadOwnWrites stay on the primary after a write with it, REPLICA_PIN_WINDOW if not set
+	// It should be about as long as the replicas take to catch up
+	ReplicaPinWindow time.Duration
+
+	// How long tokens and refresh tokens are valid for, TOKEN_TTL and REFRESH_TTL seconds if not set
+	TokenTTL   time.Duration
+	RefreshTTL time.Duration
+}
+
+/**
+ * Open a database as described by `config`, and return a store that reads and writes to it
+ * Fails if the database can't be reached after every retry, or if its schema is newer than this library
+ */
+func Open(config Config) (store *SQLStore, err error) {
+	store, err = OpenContext(context.Background(), config)
+	return
+}
+
+/**
+ * Open a database as described by `config`, giving up on retries once `ctx` is done
+ */
+func OpenContext(ctx context.Context, config Config) (store *SQLStore, err error) {
+	var found *dialect
+	var handle *sqlx.DB
+	if found, handle, err = openHandle(config.Address, config); err != nil {
+		return
+	}
+
+	var replicas []*sqlx.DB = make([]*sqlx.DB, 0, len(config.Replicas))
+	defer func() {
+		if err != nil {
+			handle.Close()
+
+			var replica *sqlx.DB
+			for _, replica = range replicas {
+				replica.Close()
+			}
+		}
+	}()
+
+	var address string
+	for _, address = range config.Replicas {
+		var replicaFound *dialect
+		var replica *sqlx.DB
+		if replicaFound, replica, err = openHandle(address, config); err != nil {
+			return
+		}
+
+		replicas = append(replicas, replica)
+
+		if replicaFound != found {
+			err = fmt.Errorf("Replica %s is not the same database as its primary", address)
+			return
+		}
+	}
+
+	var opened *SQLStore = NewSQLStore(handle)
+	opened.SetTokenLifetimes(config.TokenTTL, config.RefreshTTL)
+	if err = opened.healthRetry(ctx, config.Retries, config.RetryBackoff); err != nil {
+		return
+	}
+
+	if err = opened.checkSchema(ctx, config.Migrate); err != nil {
+		return
+	}
+
+	var interval time.Duration = config.ReplicaCheckInterval
+	if interval <= 0 {
+		interval = REPLICA_CHECK_INTERVAL
+	}
+
+	var pin time.Duration = config.ReplicaPinWindow
+	if pin <= 0 {
+		pin = REPLICA_PIN_WINDOW
+	}
+
+	opened.watchReplicas(replicas, interval, pin)
+	store = opened
+	return
+}
+
+/**
+ * Open a handle to some `address`, with the driver options and pool limits of `config`
+ * The handle is not pinged
+ */
+func openHandle(address string, config Config) (found *dialect, handle *sqlx.DB, err error) {
+	var dsn string
+	if found, dsn, err = dialectOfAddress(address); err != nil {
+		return
+	}
+
+	if dsn, err = found.configure(dsn, config); err != nil {
+		return
+	}
+
+	if handle, err = sqlx.Open(found.driver, dsn); err != nil {
+		return
+	}
+
+	if config.MaxOpenConns > 0 {
+		handle.SetMaxOpenConns(config.MaxOpenConns)
+	}
+
+	if config.MaxIdleConns > 0 {
+		handle.SetMaxIdleConns(config.MaxIdleConns)
+	}
+
+	if config.ConnMaxLifetime > 0 {
+		handle.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+
+	return
+}
+
+/**
+ * Open a database as described by `config`, and have the package level functions use it
+ * Does the same as Connect, but returns an error rather than panicking
+ */
+func ConnectConfig(config Config) (err error) {
+	var store *SQLStore
+	if store, err = Open(config); err == nil {
+		connected = store
+	}
+
+	return
+}
+
+/**
+ * Ping the database, trying again up to `retries` more times with an exponential backoff
+ * starting at `backoff`, or RETRY_BACKOFF if it's not set
+ */
+func (store *SQLStore) healthRetry(ctx context.Context, retries int, backoff time.Duration) (err error) {
+	if backoff <= 0 {
+		backoff = RETRY_BACKOFF
+	}
+
+	var attempt int
+	for {
+		if err = store.HealthContext(ctx); err == nil || attempt >= retries {
+			return
+		}
+
+		attempt++
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > RETRY_BACKOFF_MAX {
+			backoff = RETRY_BACKOFF_MAX
+		}
+	}
+}
+
+/**
+ * Check that the schema of the database isn't newer than this library, migrating it first if `migrate`
+ */
+func (store *SQLStore) checkSchema(ctx context.Context, migrate bool) (err error) {
+	if migrate {
+		err = store.MigrateContext(ctx, SchemaLatest())
+		return
+	}
+
+	var version int
+	if version, err = store.SchemaVersionContext(ctx); err != nil {
+		return
+	}
+
+	if version > SchemaLatest() {
+		err = fmt.Errorf("Database schema version %d is newer than latest %d, refusing to use it", version, SchemaLatest())
+	}
+
+	return
+}
@@ -0,0 +1,863 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+
+	"context"
+	"time"
+)
+
+/**
+ * Package level functions that act on the store opened by Connect
+ * Each of these does the same as the SQLStore method of the same name
+ */
+
+func WriteContent(content map[string]interface{}) (err error) {
+	err = connected.WriteContent(content)
+	return
+}
+
+func WriteContentContext(ctx context.Context, content map[string]interface{}) (err error) {
+	err = connected.WriteContentContext(ctx, content)
+	return
+}
+
+func CreateContent(content types.Content) (err error) {
+	err = connected.CreateContent(content)
+	return
+}
+
+func CreateContentContext(ctx context.Context, content types.Content) (err error) {
+	err = connected.CreateContentContext(ctx, content)
+	return
+}
+
+func UpdateContent(ID string, patch map[string]interface{}) (err error) {
+	err = connected.UpdateContent(ID, patch)
+	return
+}
+
+func UpdateContentContext(ctx context.Context, ID string, patch map[string]interface{}) (err error) {
+	err = connected.UpdateContentContext(ctx, ID, patch)
+	return
+}
+
+func DeleteContent(ID string) (err error) {
+	err = connected.DeleteContent(ID)
+	return
+}
+
+func DeleteContentContext(ctx context.Context, ID string) (err error) {
+	err = connected.DeleteContentContext(ctx, ID)
+	return
+}
+
+func ReadSingleContent(ID string) (content types.Content, exists bool, err error) {
+	content, exists, err = connected.ReadSingleContent(ID)
+	return
+}
+
+func ReadSingleContentContext(ctx context.Context, ID string) (content types.Content, exists bool, err error) {
+	content, exists, err = connected.ReadSingleContentContext(ctx, ID)
+	return
+}
+
+func ReadManyContent(before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = connected.ReadManyContent(before, count)
+	return
+}
+
+func ReadManyContentContext(ctx context.Context, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = connected.ReadManyContentContext(ctx, before, count)
+	return
+}
+
+func ReadAuthorContent(ID, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = connected.ReadAuthorContent(ID, before, count)
+	return
+}
+
+func ReadAuthorContentContext(ctx context.Context, ID, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = connected.ReadAuthorContentContext(ctx, ID, before, count)
+	return
+}
+
+func ReadSubscriptionFeed(ID, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = connected.ReadSubscriptionFeed(ID, before, count)
+	return
+}
+
+func ReadSubscriptionFeedContext(ctx context.Context, ID, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = connected.ReadSubscriptionFeedContext(ctx, ID, before, count)
+	return
+}
+
+func ReadContentByTags(query TagQuery, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = connected.ReadContentByTags(query, before, count)
+	return
+}
+
+func ReadContentByTagsContext(ctx context.Context, query TagQuery, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = connected.ReadContentByTagsContext(ctx, query, before, count)
+	return
+}
+
+func ReadContentByTag(tag, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = connected.ReadContentByTag(tag, before, count)
+	return
+}
+
+func ReadContentByTagContext(ctx context.Context, tag, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = connected.ReadContentByTagContext(ctx, tag, before, count)
+	return
+}
+
+func ReadPopularTags(since int64, count int) (tags []types.TagCount, size int, err error) {
+	tags, size, err = connected.ReadPopularTags(since, count)
+	return
+}
+
+func ReadPopularTagsContext(ctx context.Context, since int64, count int) (tags []types.TagCount, size int, err error) {
+	tags, size, err = connected.ReadPopularTagsContext(ctx, since, count)
+	return
+}
+
+func SetViewWindow(window time.Duration) {
+	connected.SetViewWindow(window)
+}
+
+func SetViewFlushSize(size int) {
+	connected.SetViewFlushSize(size)
+}
+
+func SetViewFlushFailed(failed func(error)) {
+	connected.SetViewFlushFailed(failed)
+}
+
+func RecordView(ID, viewer string) (counted bool, err error) {
+	counted, err = connected.RecordView(ID, viewer)
+	return
+}
+
+func RecordViewContext(ctx context.Context, ID, viewer string) (counted bool, err error) {
+	counted, err = connected.RecordViewContext(ctx, ID, viewer)
+	return
+}
+
+func FlushViews() (err error) {
+	err = connected.FlushViews()
+	return
+}
+
+func FlushViewsContext(ctx context.Context) (err error) {
+	err = connected.FlushViewsContext(ctx)
+	return
+}
+
+func FlushViewsEvery(interval time.Duration, failed func(error)) (stop func()) {
+	stop = connected.FlushViewsEvery(interval, failed)
+	return
+}
+
+func WriteUser(user map[string]interface{}) (err error) {
+	err = connected.WriteUser(user)
+	return
+}
+
+func WriteUserContext(ctx context.Context, user map[string]interface{}) (err error) {
+	err = connected.WriteUserContext(ctx, user)
+	return
+}
+
+func CreateUser(user types.User) (err error) {
+	err = connected.CreateUser(user)
+	return
+}
+
+func CreateUserContext(ctx context.Context, user types.User) (err error) {
+	err = connected.CreateUserContext(ctx, user)
+	return
+}
+
+func UpdateUser(ID string, patch map[string]interface{}) (err error) {
+	err = connected.UpdateUser(ID, patch)
+	return
+}
+
+func UpdateUserContext(ctx context.Context, ID string, patch map[string]interface{}) (err error) {
+	err = connected.UpdateUserContext(ctx, ID, patch)
+	return
+}
+
+func DeleteUser(ID string) (err error) {
+	err = connected.DeleteUser(ID)
+	return
+}
+
+func DeleteUserContext(ctx context.Context, ID string) (err error) {
+	err = connected.DeleteUserContext(ctx, ID)
+	return
+}
+
+func ReadSingleUser(ID string) (user types.User, exists bool, err error) {
+	user, exists, err = connected.ReadSingleUser(ID)
+	return
+}
+
+func ReadSingleUserContext(ctx context.Context, ID string) (user types.User, exists bool, err error) {
+	user, exists, err = connected.ReadSingleUserContext(ctx, ID)
+	return
+}
+
+func ReadSingleUserEmail(email string) (user types.User, exists bool, err error) {
+	user, exists, err = connected.ReadSingleUserEmail(email)
+	return
+}
+
+func ReadSingleUserEmailContext(ctx context.Context, email string) (user types.User, exists bool, err error) {
+	user, exists, err = connected.ReadSingleUserEmailContext(ctx, email)
+	return
+}
+
+func ReadSingleUserNick(nick string) (user types.User, exists bool, err error) {
+	user, exists, err = connected.ReadSingleUserNick(nick)
+	return
+}
+
+func ReadSingleUserNickContext(ctx context.Context, nick string) (user types.User, exists bool, err error) {
+	user, exists, err = connected.ReadSingleUserNickContext(ctx, nick)
+	return
+}
+
+func IncrementPostCount(ID string) (err error) {
+	err = connected.IncrementPostCount(ID)
+	return
+}
+
+func IncrementPostCountContext(ctx context.Context, ID string) (err error) {
+	err = connected.IncrementPostCountContext(ctx, ID)
+	return
+}
+
+func IsModerator(ID string) (moderator bool, err error) {
+	moderator, err = connected.IsModerator(ID)
+	return
+}
+
+func IsModeratorContext(ctx context.Context, ID string) (moderator bool, err error) {
+	moderator, err = connected.IsModeratorContext(ctx, ID)
+	return
+}
+
+func IsAdmin(ID string) (admin bool, err error) {
+	admin, err = connected.IsAdmin(ID)
+	return
+}
+
+func IsAdminContext(ctx context.Context, ID string) (admin bool, err error) {
+	admin, err = connected.IsAdminContext(ctx, ID)
+	return
+}
+
+func SetModerator(ID string, state bool) (err error) {
+	err = connected.SetModerator(ID, state)
+	return
+}
+
+func SetModeratorContext(ctx context.Context, ID string, state bool) (err error) {
+	err = connected.SetModeratorContext(ctx, ID, state)
+	return
+}
+
+func SetAdmin(ID string, state bool) (err error) {
+	err = connected.SetAdmin(ID, state)
+	return
+}
+
+func SetAdminContext(ctx context.Context, ID string, state bool) (err error) {
+	err = connected.SetAdminContext(ctx, ID, state)
+	return
+}
+
+func CreateSecret(ID string) (secret string, err error) {
+	secret, err = connected.CreateSecret(ID)
+	return
+}
+
+func CreateSecretContext(ctx context.Context, ID string) (secret string, err error) {
+	secret, err = connected.CreateSecretContext(ctx, ID)
+	return
+}
+
+func CheckSecret(ID, secret string) (valid bool, err error) {
+	valid, err = connected.CheckSecret(ID, secret)
+	return
+}
+
+func CheckSecretContext(ctx context.Context, ID, secret string) (valid bool, err error) {
+	valid, err = connected.CheckSecretContext(ctx, ID, secret)
+	return
+}
+
+func RevokeSecretOf(ID string) (err error) {
+	err = connected.RevokeSecretOf(ID)
+	return
+}
+
+func RevokeSecretOfContext(ctx context.Context, ID string) (err error) {
+	err = connected.RevokeSecretOfContext(ctx, ID)
+	return
+}
+
+func CreateSession(ID, device, IP string) (token string, session types.Session, err error) {
+	token, session, err = connected.CreateSession(ID, device, IP)
+	return
+}
+
+func CreateSessionContext(ctx context.Context, ID, device, IP string) (token string, session types.Session, err error) {
+	token, session, err = connected.CreateSessionContext(ctx, ID, device, IP)
+	return
+}
+
+func ReadSessionOfToken(token string) (session types.Session, valid bool, err error) {
+	session, valid, err = connected.ReadSessionOfToken(token)
+	return
+}
+
+func ReadSessionOfTokenContext(ctx context.Context, token string) (session types.Session, valid bool, err error) {
+	session, valid, err = connected.ReadSessionOfTokenContext(ctx, token)
+	return
+}
+
+func ReadSession(ID string) (session types.Session, exists bool, err error) {
+	session, exists, err = connected.ReadSession(ID)
+	return
+}
+
+func ReadSessionContext(ctx context.Context, ID string) (session types.Session, exists bool, err error) {
+	session, exists, err = connected.ReadSessionContext(ctx, ID)
+	return
+}
+
+func OnSessionRevoked(revoked func(session string, until int64)) {
+	connected.OnSessionRevoked(revoked)
+}
+
+func ListSessions(ID string) (sessions []types.Session, err error) {
+	sessions, err = connected.ListSessions(ID)
+	return
+}
+
+func ListSessionsContext(ctx context.Context, ID string) (sessions []types.Session, err error) {
+	sessions, err = connected.ListSessionsContext(ctx, ID)
+	return
+}
+
+func RevokeSession(ID string) (err error) {
+	err = connected.RevokeSession(ID)
+	return
+}
+
+func RevokeSessionContext(ctx context.Context, ID string) (err error) {
+	err = connected.RevokeSessionContext(ctx, ID)
+	return
+}
+
+func RevokeOtherSessions(ID, keep string) (err error) {
+	err = connected.RevokeOtherSessions(ID, keep)
+	return
+}
+
+func RevokeOtherSessionsContext(ctx context.Context, ID, keep string) (err error) {
+	err = connected.RevokeOtherSessionsContext(ctx, ID, keep)
+	return
+}
+
+func CreateTokenPair(ID, device, IP string) (pair TokenPair, err error) {
+	pair, err = connected.CreateTokenPair(ID, device, IP)
+	return
+}
+
+func CreateTokenPairContext(ctx context.Context, ID, device, IP string) (pair TokenPair, err error) {
+	pair, err = connected.CreateTokenPairContext(ctx, ID, device, IP)
+	return
+}
+
+func RefreshTokenPair(refresh string) (pair TokenPair, err error) {
+	pair, err = connected.RefreshTokenPair(refresh)
+	return
+}
+
+func RefreshTokenPairContext(ctx context.Context, refresh string) (pair TokenPair, err error) {
+	pair, err = connected.RefreshTokenPairContext(ctx, refresh)
+	return
+}
+
+func CreateToken(ID string) (token string, expires int64, err error) {
+	token, expires, err = connected.CreateToken(ID)
+	return
+}
+
+func CreateTokenContext(ctx context.Context, ID string) (token string, expires int64, err error) {
+	token, expires, err = connected.CreateTokenContext(ctx, ID)
+	return
+}
+
+func ReadTokenStat(token string) (owner string, valid bool, err error) {
+	owner, valid, err = connected.ReadTokenStat(token)
+	return
+}
+
+func ReadTokenStatContext(ctx context.Context, token string) (owner string, valid bool, err error) {
+	owner, valid, err = connected.ReadTokenStatContext(ctx, token)
+	return
+}
+
+func RevokeToken(token string) (err error) {
+	err = connected.RevokeToken(token)
+	return
+}
+
+func RevokeTokenContext(ctx context.Context, token string) (err error) {
+	err = connected.RevokeTokenContext(ctx, token)
+	return
+}
+
+func RevokeTokenOf(ID string) (err error) {
+	err = connected.RevokeTokenOf(ID)
+	return
+}
+
+func RevokeTokenOfContext(ctx context.Context, ID string) (err error) {
+	err = connected.RevokeTokenOfContext(ctx, ID)
+	return
+}
+
+func CreateAPIKey(ID, name string, scopes []string, lifetime int64) (token string, key types.APIKey, err error) {
+	token, key, err = connected.CreateAPIKey(ID, name, scopes, lifetime)
+	return
+}
+
+func CreateAPIKeyContext(ctx context.Context, ID, name string, scopes []string, lifetime int64) (token string, key types.APIKey, err error) {
+	token, key, err = connected.CreateAPIKeyContext(ctx, ID, name, scopes, lifetime)
+	return
+}
+
+func ReadAPIKeyOfToken(token string) (key types.APIKey, valid bool, err error) {
+	key, valid, err = connected.ReadAPIKeyOfToken(token)
+	return
+}
+
+func ReadAPIKeyOfTokenContext(ctx context.Context, token string) (key types.APIKey, valid bool, err error) {
+	key, valid, err = connected.ReadAPIKeyOfTokenContext(ctx, token)
+	return
+}
+
+func ListAPIKeys(ID string) (keys []types.APIKey, err error) {
+	keys, err = connected.ListAPIKeys(ID)
+	return
+}
+
+func ListAPIKeysContext(ctx context.Context, ID string) (keys []types.APIKey, err error) {
+	keys, err = connected.ListAPIKeysContext(ctx, ID)
+	return
+}
+
+func RevokeAPIKey(ID string) (err error) {
+	err = connected.RevokeAPIKey(ID)
+	return
+}
+
+func RevokeAPIKeyContext(ctx context.Context, ID string) (err error) {
+	err = connected.RevokeAPIKeyContext(ctx, ID)
+	return
+}
+
+func RevokeAPIKeysOf(ID string) (err error) {
+	err = connected.RevokeAPIKeysOf(ID)
+	return
+}
+
+func RevokeAPIKeysOfContext(ctx context.Context, ID string) (err error) {
+	err = connected.RevokeAPIKeysOfContext(ctx, ID)
+	return
+}
+
+func CheckPassword(ID, password string) (valid bool, err error) {
+	valid, err = connected.CheckPassword(ID, password)
+	return
+}
+
+func CheckPasswordContext(ctx context.Context, ID, password string) (valid bool, err error) {
+	valid, err = connected.CheckPasswordContext(ctx, ID, password)
+	return
+}
+
+func SetPassword(ID, password string) (err error) {
+	err = connected.SetPassword(ID, password)
+	return
+}
+
+func SetPasswordContext(ctx context.Context, ID, password string) (err error) {
+	err = connected.SetPasswordContext(ctx, ID, password)
+	return
+}
+
+func WriteBan(ban map[string]interface{}) (err error) {
+	err = connected.WriteBan(ban)
+	return
+}
+
+func WriteBanContext(ctx context.Context, ban map[string]interface{}) (err error) {
+	err = connected.WriteBanContext(ctx, ban)
+	return
+}
+
+func CreateBan(ban types.Ban) (err error) {
+	err = connected.CreateBan(ban)
+	return
+}
+
+func CreateBanContext(ctx context.Context, ban types.Ban) (err error) {
+	err = connected.CreateBanContext(ctx, ban)
+	return
+}
+
+func UpdateBan(ban types.Ban) (err error) {
+	err = connected.UpdateBan(ban)
+	return
+}
+
+func UpdateBanContext(ctx context.Context, ban types.Ban) (err error) {
+	err = connected.UpdateBanContext(ctx, ban)
+	return
+}
+
+func ReadSingleBan(ID string) (ban types.Ban, exists bool, err error) {
+	ban, exists, err = connected.ReadSingleBan(ID)
+	return
+}
+
+func ReadSingleBanContext(ctx context.Context, ID string) (ban types.Ban, exists bool, err error) {
+	ban, exists, err = connected.ReadSingleBanContext(ctx, ID)
+	return
+}
+
+func ReadBansOfUser(ID, before string, count int) (bans []types.Ban, size int, err error) {
+	bans, size, err = connected.ReadBansOfUser(ID, before, count)
+	return
+}
+
+func ReadBansOfUserContext(ctx context.Context, ID, before string, count int) (bans []types.Ban, size int, err error) {
+	bans, size, err = connected.ReadBansOfUserContext(ctx, ID, before, count)
+	return
+}
+
+func IsBanned(ID string) (banned bool, err error) {
+	banned, err = connected.IsBanned(ID)
+	return
+}
+
+func IsBannedContext(ctx context.Context, ID string) (banned bool, err error) {
+	banned, err = connected.IsBannedContext(ctx, ID)
+	return
+}
+
+func WriteReport(report map[string]interface{}) (err error) {
+	err = connected.WriteReport(report)
+	return
+}
+
+func WriteReportContext(ctx context.Context, report map[string]interface{}) (err error) {
+	err = connected.WriteReportContext(ctx, report)
+	return
+}
+
+func CreateReport(report types.Report) (err error) {
+	err = connected.CreateReport(report)
+	return
+}
+
+func CreateReportContext(ctx context.Context, report types.Report) (err error) {
+	err = connected.CreateReportContext(ctx, report)
+	return
+}
+
+func UpdateReport(report types.Report) (err error) {
+	err = connected.UpdateReport(report)
+	return
+}
+
+func UpdateReportContext(ctx context.Context, report types.Report) (err error) {
+	err = connected.UpdateReportContext(ctx, report)
+	return
+}
+
+func ReadManyUnresolvedReport(before string, count int) (reports []types.Report, size int, err error) {
+	reports, size, err = connected.ReadManyUnresolvedReport(before, count)
+	return
+}
+
+func ReadManyUnresolvedReportContext(ctx context.Context, before string, count int) (reports []types.Report, size int, err error) {
+	reports, size, err = connected.ReadManyUnresolvedReportContext(ctx, before, count)
+	return
+}
+
+func ReadSingleReport(ID string) (report types.Report, exists bool, err error) {
+	report, exists, err = connected.ReadSingleReport(ID)
+	return
+}
+
+func ReadSingleReportContext(ctx context.Context, ID string) (report types.Report, exists bool, err error) {
+	report, exists, err = connected.ReadSingleReportContext(ctx, ID)
+	return
+}
+
+func Subscribe(subscriber, subscription string) (err error) {
+	err = connected.Subscribe(subscriber, subscription)
+	return
+}
+
+func SubscribeContext(ctx context.Context, subscriber, subscription string) (err error) {
+	err = connected.SubscribeContext(ctx, subscriber, subscription)
+	return
+}
+
+func Unsubscribe(subscriber, subscription string) (err error) {
+	err = connected.Unsubscribe(subscriber, subscription)
+	return
+}
+
+func UnsubscribeContext(ctx context.Context, subscriber, subscription string) (err error) {
+	err = connected.UnsubscribeContext(ctx, subscriber, subscription)
+	return
+}
+
+func IsSubscribed(subscriber, subscription string) (subscribed bool, err error) {
+	subscribed, err = connected.IsSubscribed(subscriber, subscription)
+	return
+}
+
+func IsSubscribedContext(ctx context.Context, subscriber, subscription string) (subscribed bool, err error) {
+	subscribed, err = connected.IsSubscribedContext(ctx, subscriber, subscription)
+	return
+}
+
+func ReadSubscribers(ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	subscriptions, size, err = connected.ReadSubscribers(ID, before, count)
+	return
+}
+
+func ReadSubscribersContext(ctx context.Context, ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	subscriptions, size, err = connected.ReadSubscribersContext(ctx, ID, before, count)
+	return
+}
+
+func ReadSubscriptions(ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	subscriptions, size, err = connected.ReadSubscriptions(ID, before, count)
+	return
+}
+
+func ReadSubscriptionsContext(ctx context.Context, ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	subscriptions, size, err = connected.ReadSubscriptionsContext(ctx, ID, before, count)
+	return
+}
+
+func Vote(voter, ID string, value int) (err error) {
+	err = connected.Vote(voter, ID, value)
+	return
+}
+
+func VoteContext(ctx context.Context, voter, ID string, value int) (err error) {
+	err = connected.VoteContext(ctx, voter, ID, value)
+	return
+}
+
+func ClearVote(voter, ID string) (err error) {
+	err = connected.ClearVote(voter, ID)
+	return
+}
+
+func ClearVoteContext(ctx context.Context, voter, ID string) (err error) {
+	err = connected.ClearVoteContext(ctx, voter, ID)
+	return
+}
+
+func ReadVote(voter, ID string) (value int, err error) {
+	value, err = connected.ReadVote(voter, ID)
+	return
+}
+
+func ReadVoteContext(ctx context.Context, voter, ID string) (value int, err error) {
+	value, err = connected.ReadVoteContext(ctx, voter, ID)
+	return
+}
+
+func ReadVotesOf(voter string, IDs []string) (votes map[string]int, err error) {
+	votes, err = connected.ReadVotesOf(voter, IDs)
+	return
+}
+
+func ReadVotesOfContext(ctx context.Context, voter string, IDs []string) (votes map[string]int, err error) {
+	votes, err = connected.ReadVotesOfContext(ctx, voter, IDs)
+	return
+}
+
+func WriteComment(comment types.Comment) (err error) {
+	err = connected.WriteComment(comment)
+	return
+}
+
+func WriteCommentContext(ctx context.Context, comment types.Comment) (err error) {
+	err = connected.WriteCommentContext(ctx, comment)
+	return
+}
+
+func EditComment(ID, body string) (err error) {
+	err = connected.EditComment(ID, body)
+	return
+}
+
+func EditCommentContext(ctx context.Context, ID, body string) (err error) {
+	err = connected.EditCommentContext(ctx, ID, body)
+	return
+}
+
+func DeleteComment(ID string) (err error) {
+	err = connected.DeleteComment(ID)
+	return
+}
+
+func DeleteCommentContext(ctx context.Context, ID string) (err error) {
+	err = connected.DeleteCommentContext(ctx, ID)
+	return
+}
+
+func ReadSingleComment(ID string) (comment types.Comment, exists bool, err error) {
+	comment, exists, err = connected.ReadSingleComment(ID)
+	return
+}
+
+func ReadSingleCommentContext(ctx context.Context, ID string) (comment types.Comment, exists bool, err error) {
+	comment, exists, err = connected.ReadSingleCommentContext(ctx, ID)
+	return
+}
+
+func ReadComments(ID, before string, count int) (comments []types.Comment, size int, err error) {
+	comments, size, err = connected.ReadComments(ID, before, count)
+	return
+}
+
+func ReadCommentsContext(ctx context.Context, ID, before string, count int) (comments []types.Comment, size int, err error) {
+	comments, size, err = connected.ReadCommentsContext(ctx, ID, before, count)
+	return
+}
+
+func ReadReplies(ID, before string, count int) (comments []types.Comment, size int, err error) {
+	comments, size, err = connected.ReadReplies(ID, before, count)
+	return
+}
+
+func ReadRepliesContext(ctx context.Context, ID, before string, count int) (comments []types.Comment, size int, err error) {
+	comments, size, err = connected.ReadRepliesContext(ctx, ID, before, count)
+	return
+}
+
+func Repub(author, ID string) (err error) {
+	err = connected.Repub(author, ID)
+	return
+}
+
+func RepubContext(ctx context.Context, author, ID string) (err error) {
+	err = connected.RepubContext(ctx, author, ID)
+	return
+}
+
+func Unrepub(author, ID string) (err error) {
+	err = connected.Unrepub(author, ID)
+	return
+}
+
+func UnrepubContext(ctx context.Context, author, ID string) (err error) {
+	err = connected.UnrepubContext(ctx, author, ID)
+	return
+}
+
+func IsRepubbed(author, ID string) (repubbed bool, err error) {
+	repubbed, err = connected.IsRepubbed(author, ID)
+	return
+}
+
+func IsRepubbedContext(ctx context.Context, author, ID string) (repubbed bool, err error) {
+	repubbed, err = connected.IsRepubbedContext(ctx, author, ID)
+	return
+}
+
+func ReadAuthorEntries(ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	entries, size, err = connected.ReadAuthorEntries(ID, before, count)
+	return
+}
+
+func ReadAuthorEntriesContext(ctx context.Context, ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	entries, size, err = connected.ReadAuthorEntriesContext(ctx, ID, before, count)
+	return
+}
+
+func ReadSubscriptionEntries(ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	entries, size, err = connected.ReadSubscriptionEntries(ID, before, count)
+	return
+}
+
+func ReadSubscriptionEntriesContext(ctx context.Context, ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	entries, size, err = connected.ReadSubscriptionEntriesContext(ctx, ID, before, count)
+	return
+}
+
+func Health() (err error) {
+	err = connected.Health()
+	return
+}
+
+func HealthContext(ctx context.Context) (err error) {
+	err = connected.HealthContext(ctx)
+	return
+}
+
+func WithTx(ctx context.Context, work func(Store) error) (err error) {
+	err = connected.WithTx(ctx, work)
+	return
+}
+
+func Create() {
+	connected.Create()
+}
+
+func SchemaVersion() (version int, err error) {
+	version, err = connected.SchemaVersion()
+	return
+}
+
+func SchemaVersionContext(ctx context.Context) (version int, err error) {
+	version, err = connected.SchemaVersionContext(ctx)
+	return
+}
+
+func Migrate(target int) (err error) {
+	err = connected.Migrate(target)
+	return
+}
+
+func MigrateContext(ctx context.Context, target int) (err error) {
+	err = connected.MigrateContext(ctx, target)
+	return
+}
+
+func EmptyTable(table string) (err error) {
+	err = connected.EmptyTable(table)
+	return
+}
+
+func EmptyTableContext(ctx context.Context, table string) (err error) {
+	err = connected.EmptyTableContext(ctx, table)
+	return
+}
@@ -4,41 +4,167 @@ import (
 	"github.com/brane-app/librane/types"
 	"github.com/jmoiron/sqlx"
 
+	"context"
 	"database/sql"
 	"time"
 )
 
 /**
  * Write some content `content` to the table CONTENT_TABLE
- * Uses 3 query
+ * Only the columns of CONTENT_TABLE, and tags, may be given
+ * CreateContent and UpdateContent should be preferred, as they're checked before writing
+ * Replaced content keeps its votes, and its like_count and dislike_count are counted from them
+ * Uses 4 query, inside of one transaction
  * 		write content: 	REPLACE INTO CONTENT_TABLE (keys...) VALUES (values...)
+ * 		count votes: 	UPDATE CONTENT_TABLE SET like_count=(SELECT COUNT(*) FROM VOTE_TABLE ...), dislike_count=(...) WHERE id=ID
  * 		queries from: setTags
  * Returns error, if any
  */
-func WriteContent(content map[string]interface{}) (err error) {
-	var tags []string = make([]string, len(content["tags"].([]string)))
-	copy(tags, content["tags"].([]string))
+func (store *SQLStore) WriteContentContext(ctx context.Context, content map[string]interface{}) (err error) {
+	var given []string
+	given, _ = content["tags"].([]string)
+
+	var tags []string = make([]string, len(given))
+	copy(tags, given)
 
 	var copied map[string]interface{} = mapCopy(content)
 	delete(copied, "tags")
 
-	var statement string
-	var values []interface{}
-	statement, values = makeSQLInsertable(CONTENT_TABLE, copied)
-	if _, err = database_handle.Exec(statement, values...); err == nil && len(tags) != 0 {
-		err = setTags(copied["id"].(string), tags)
+	err = store.inTx(ctx, func(bound *SQLStore) (err error) {
+		if err = bound.replace(ctx, CONTENT_TABLE, copied); err != nil {
+			return
+		}
+
+		var ID string
+		ID, _ = copied["id"].(string)
+		if _, err = bound.db().ExecContext(ctx, COUNT_CONTENT_VOTES_OF_ID, ID, ID, ID); err == nil && len(tags) != 0 {
+			err = bound.setTags(ctx, ID, tags)
+		}
+
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) WriteContent(content map[string]interface{}) (err error) {
+	err = store.WriteContentContext(context.Background(), content)
+	return
+}
+
+/**
+ * Create some new content `content`, along with its tags
+ * Its counts start at zero, whatever `content` has
+ * Fails with ErrInvalid if some field of `content` can't be stored, or ErrDuplicate if its id is taken
+ * Uses 3 queries, inside of one transaction
+ * 		write content: 	INSERT INTO CONTENT_TABLE (fields...) VALUES (values...)
+ * 		queries from: 	setTags
+ */
+func (store *SQLStore) CreateContentContext(ctx context.Context, content types.Content) (err error) {
+	if err = validateContent(content); err != nil {
+		return
+	}
+
+	err = store.inTx(ctx, func(bound *SQLStore) (err error) {
+		if _, err = bound.db().ExecContext(ctx,
+			WRITE_CONTENT,
+			content.ID, content.FileURL, content.Author, content.Mime,
+			content.Created, content.Featured, content.Featurable, content.Removed, content.NSFW,
+		); err == nil && len(content.Tags) != 0 {
+			err = bound.setTags(ctx, content.ID, content.Tags)
+		}
+
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) CreateContent(content types.Content) (err error) {
+	err = store.CreateContentContext(context.Background(), content)
+	return
+}
+
+/**
+ * Update only the fields in `patch` of some content of id `ID`, replacing its tags if tags is given
+ * Only file_url, mime, featured, featurable, removed, nsfw, and tags may be patched,
+ * so its author, counts, and place in order are kept
+ * Fails with ErrInvalid if `patch` has some other key or a value that can't be stored, or ErrNotFound if it doesn't exist
+ * Uses up to 6 queries, inside of one transaction
+ * 		queries from: 	lockRow
+ * 		queries from: 	ReadSingleContent
+ * 		write content: 	UPDATE CONTENT_TABLE SET patched=patched... WHERE id=ID
+ * 		queries from: 	setTags
+ */
+func (store *SQLStore) UpdateContentContext(ctx context.Context, ID string, patch map[string]interface{}) (err error) {
+	if err = checkPatch(CONTENT_TABLE, patch, "tags"); err != nil {
+		return
 	}
 
+	err = store.inTx(ctx, func(bound *SQLStore) (err error) {
+		if err = lockRow(ctx, bound.db(), CONTENT_TABLE, ID); err != nil {
+			return
+		}
+
+		var content types.Content
+		if content, _, err = bound.ReadSingleContentContext(ctx, ID); err != nil {
+			return
+		}
+
+		if content, err = patchContent(content, patch); err != nil {
+			return
+		}
+
+		var statement string
+		var values []interface{}
+		if statement, values = makeSQLPatch(CONTENT_TABLE, ID, patch, content.Map()); statement != "" {
+			if _, err = bound.db().ExecContext(ctx, statement, values...); err != nil {
+				return
+			}
+		}
+
+		var given bool
+		if _, given = patch["tags"]; given {
+			err = bound.setTags(ctx, ID, content.Tags)
+		}
+
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) UpdateContent(ID string, patch map[string]interface{}) (err error) {
+	err = store.UpdateContentContext(context.Background(), ID, patch)
 	return
 }
 
 /**
- * Delete some content of id `ID`
- * Uses 1 querie
+ * Delete some content of id `ID`, along with its votes, repubs and comments
+ * Its tags are deleted along with it by their foreign key
+ * Uses 4 queries, inside of one transaction
+ * 		delete votes:		DELETE FROM VOTE_TABLE WHERE content=ID
+ * 		delete repubs:		DELETE FROM REPUB_TABLE WHERE content=ID
+ * 		delete comments:	DELETE FROM COMMENT_TABLE WHERE content=ID
  * 		delete content:		DELETE FROM CONTENT_TABLE WHERE id=ID LIMIT 1
  */
-func DeleteContent(ID string) (err error) {
-	_, err = database_handle.Exec(DELETE_CONTENT_ID, ID)
+func (store *SQLStore) DeleteContentContext(ctx context.Context, ID string) (err error) {
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		var statement string
+		for _, statement = range []string{DELETE_VOTES_OF_CONTENT, DELETE_REPUBS_OF_CONTENT, DELETE_COMMENTS_OF_CONTENT, DELETE_CONTENT_ID} {
+			if _, err = tx.ExecContext(ctx, statement, ID); err != nil {
+				return
+			}
+		}
+
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) DeleteContent(ID string) (err error) {
+	err = store.DeleteContentContext(context.Background(), ID)
 	return
 }
 
@@ -48,8 +174,9 @@ func DeleteContent(ID string) (err error) {
  * 		get content: 	SELECT * FROM CONTENT_TABLE WHERE id=ID LIMIT 1
  * 		get tags:		SELECT tag FROM TAG_TABLE WHERE id=ID
  */
-func ReadSingleContent(ID string) (content types.Content, exists bool, err error) {
-	if err = database_handle.QueryRowx(READ_CONTENT_ID, ID).StructScan(&content); err != nil {
+func (store *SQLStore) ReadSingleContentContext(ctx context.Context, ID string) (content types.Content, exists bool, err error) {
+	store = store.reader(ctx)
+	if err = store.db().QueryRowxContext(ctx, READ_CONTENT_ID, ID).StructScan(&content); err != nil {
 		if err == sql.ErrNoRows {
 			err = nil
 		}
@@ -57,7 +184,12 @@ func ReadSingleContent(ID string) (content types.Content, exists bool, err error
 	}
 
 	exists = true
-	content.Tags, err = getTags(ID)
+	content.Tags, err = store.getTags(ctx, ID)
+	return
+}
+
+func (store *SQLStore) ReadSingleContent(ID string) (content types.Content, exists bool, err error) {
+	content, exists, err = store.ReadSingleContentContext(context.Background(), ID)
 	return
 }
 
@@ -69,19 +201,26 @@ func ReadSingleContent(ID string) (content types.Content, exists bool, err error
  * 		get content: 	SELECT * FROM CONTENT_TABLE ORDER BY created DESC LIMIT offset, count
  * 		queries from: 	getManyTags
  */
-func ReadManyContent(before string, count int) (content []types.Content, size int, err error) {
+func (store *SQLStore) ReadManyContentContext(ctx context.Context, before string, count int) (content []types.Content, size int, err error) {
+	store = store.reader(ctx)
 	var rows *sqlx.Rows
 	if before == "" {
-		rows, err = database_handle.Queryx(READ_MANY_CONTENT, count)
+		rows, err = store.db().QueryxContext(ctx, READ_MANY_CONTENT, count)
 	} else {
-		rows, err = database_handle.Queryx(READ_MANY_CONTENT_AFTER_ID, before, count)
+		rows, err = store.db().QueryxContext(ctx, READ_MANY_CONTENT_AFTER_ID, before, count)
 	}
 
-	defer rows.Close()
-	if err == nil {
-		content, size, err = scanManyContent(rows, count)
+	if err != nil {
+		return
 	}
 
+	defer rows.Close()
+	content, size, err = store.scanManyContent(ctx, rows, count)
+	return
+}
+
+func (store *SQLStore) ReadManyContent(before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = store.ReadManyContentContext(context.Background(), before, count)
 	return
 }
 
@@ -91,18 +230,102 @@ func ReadManyContent(before string, count int) (content []types.Content, size in
  * 		get content: 	SELECT * FROM CONTENT_TABLE ORDER BY created DESC LIMIT offset, count
  * 		queries from: 	getManyTags
  */
-func ReadAuthorContent(ID, before string, count int) (content []types.Content, size int, err error) {
+func (store *SQLStore) ReadAuthorContentContext(ctx context.Context, ID, before string, count int) (content []types.Content, size int, err error) {
+	store = store.reader(ctx)
 	var rows *sqlx.Rows
 	if before == "" {
-		rows, err = database_handle.Queryx(READ_MANY_CONTENT_OF_AUTHOR, ID, count)
+		rows, err = store.db().QueryxContext(ctx, READ_MANY_CONTENT_OF_AUTHOR, ID, count)
 	} else {
-		rows, err = database_handle.Queryx(READ_MANY_CONTENT_OF_AUTHOR_AFTER_ID, ID, before, count)
+		rows, err = store.db().QueryxContext(ctx, READ_MANY_CONTENT_OF_AUTHOR_AFTER_ID, ID, before, count)
+	}
+
+	if err != nil {
+		return
 	}
 
 	defer rows.Close()
+	content, size, err = store.scanManyContent(ctx, rows, count)
+	return
+}
+
+func (store *SQLStore) ReadAuthorContent(ID, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = store.ReadAuthorContentContext(context.Background(), ID, before, count)
+	return
+}
+
+/**
+ * Read `count` number of contents authored by anyone that some user of id `ID` is subscribed to
+ * Works in the same way as ReadManyContent, but removed content is skipped
+ * ReadSubscriptionEntries reads the same, along with repubs
+ * Uses 2 queries
+ * 		get content: 	SELECT * FROM CONTENT_TABLE WHERE author IN (SELECT subscription FROM SUBSCRIPTION_TABLE WHERE subscriber=ID) ORDER BY order_index DESC LIMIT count
+ * 		queries from: 	getManyTags
+ */
+func (store *SQLStore) ReadSubscriptionFeedContext(ctx context.Context, ID, before string, count int) (content []types.Content, size int, err error) {
+	store = store.reader(ctx)
+	var rows *sqlx.Rows
+	if before == "" {
+		rows, err = store.db().QueryxContext(ctx, READ_MANY_CONTENT_OF_SUBSCRIPTIONS, ID, count)
+	} else {
+		rows, err = store.db().QueryxContext(ctx, READ_MANY_CONTENT_OF_SUBSCRIPTIONS_AFTER_ID, ID, before, count)
+	}
+
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+	content, size, err = store.scanManyContent(ctx, rows, count)
+	return
+}
+
+func (store *SQLStore) ReadSubscriptionFeed(ID, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = store.ReadSubscriptionFeedContext(context.Background(), ID, before, count)
+	return
+}
 
-	if err == nil {
-		content, size, err = scanManyContent(rows, count)
+/**
+ * Read every content of id in `IDs`, along with their tags
+ * Returns a map where
+ * 		id -> content
+ * Content that does not exist is left out
+ * Uses 2 queries
+ * 		get content: 	SELECT * FROM CONTENT_TABLE WHERE id IN (IDs...)
+ * 		queries from: 	getManyTags
+ */
+func (store *SQLStore) readManyContentOf(ctx context.Context, IDs []string) (content map[string]types.Content, err error) {
+	content = make(map[string]types.Content, len(IDs))
+	if len(IDs) < 1 {
+		return
+	}
+
+	var paramString string = "(" + manyParamString("?", len(IDs)) + ")"
+	var rows *sqlx.Rows
+	if rows, err = store.db().QueryxContext(ctx, READ_CONTENT_OF_MANY_ID+paramString, interfaceStrings(IDs...)...); err != nil {
+		return
+	}
+
+	var single types.Content
+	for rows.Next() {
+		if err = rows.StructScan(&single); err != nil {
+			rows.Close()
+			return
+		}
+
+		content[single.ID] = single
+	}
+
+	rows.Close()
+
+	var tags map[string][]string
+	if tags, err = store.getManyTags(ctx, IDs); err != nil {
+		return
+	}
+
+	var id string
+	for id, single = range content {
+		single.Tags = tags[id]
+		content[id] = single
 	}
 
 	return
@@ -113,9 +336,9 @@ func ReadAuthorContent(ID, before string, count int) (content []types.Content, s
  * Uses 1 query
  * 		get tags:	SELECT tag FROM TAG_TABLE WHERE id=ID
  */
-func getTags(ID string) (tags []string, err error) {
+func (store *SQLStore) getTags(ctx context.Context, ID string) (tags []string, err error) {
 	var rows *sqlx.Rows
-	if rows, err = database_handle.Queryx(READ_TAGS_OF_ID, ID); err != nil || rows == nil {
+	if rows, err = store.db().QueryxContext(ctx, READ_TAGS_OF_ID, ID); err != nil || rows == nil {
 		return
 	}
 
@@ -141,7 +364,7 @@ func getTags(ID string) (tags []string, err error) {
  * Uses 1 query:
  * 		get tags: SELECT id, tag FROM TAG_TABLE WHERE id IN (IDs...)
  */
-func getManyTags(IDs []string) (tags map[string][]string, err error) {
+func (store *SQLStore) getManyTags(ctx context.Context, IDs []string) (tags map[string][]string, err error) {
 	var size int = len(IDs)
 	if size < 1 {
 		return
@@ -155,7 +378,7 @@ func getManyTags(IDs []string) (tags map[string][]string, err error) {
 
 	var paramString string = "(" + manyParamString("?", len(IDs)) + ")"
 	var rows *sql.Rows
-	if rows, err = database_handle.Query(READ_TAGS_OF_MANY_ID+paramString, interfaceStrings(IDs...)...); err != nil || rows == nil {
+	if rows, err = store.db().QueryContext(ctx, READ_TAGS_OF_MANY_ID+paramString, interfaceStrings(IDs...)...); err != nil || rows == nil {
 		return
 	}
 
@@ -175,26 +398,31 @@ func getManyTags(IDs []string) (tags map[string][]string, err error) {
 
 /**
  * Updates the tags of a post
+ * Tags that it already had are kept as they are, so that they still count as tagged when they were first given
  * Done in two queries if there are tags
  * Or one if there are no tags
+ * 		delete tags: 	DELETE FROM TAG_TABLE WHERE id=ID AND tag NOT IN (tags...)
+ * 		write tags: 	INSERT IGNORE INTO TAG_TABLE (id, tag, created) VALUES (ID, tag, now)...
  */
-func setTags(ID string, tags []string) (err error) {
-	if _, err = database_handle.Exec(DELETE_TAGS_OF_ID, ID); err != nil || len(tags) == 0 {
+func (store *SQLStore) setTags(ctx context.Context, ID string, tags []string) (err error) {
+	var unique []string = uniqueStrings(tags)
+	if len(unique) == 0 {
+		_, err = store.db().ExecContext(ctx, DELETE_TAGS_OF_ID, ID)
 		return
 	}
 
-	var length int = len(tags)
-	var insertable []interface{} = make([]interface{}, length*3)
+	var paramString string = "(" + manyParamString("?", len(unique)) + ")"
+	if _, err = store.db().ExecContext(ctx, DELETE_TAGS_OF_ID_EXCEPT+paramString, append([]interface{}{ID}, interfaceStrings(unique...)...)...); err != nil {
+		return
+	}
+
+	var insertable []interface{} = make([]interface{}, 0, len(unique)*3)
 	var now int64 = time.Now().Unix()
-	var index int = 0
-	for index < length {
-		insertable[index*3] = ID
-		insertable[index*3+1] = tags[index]
-		insertable[index*3+2] = now
-		index++
+	var tag string
+	for _, tag = range unique {
+		insertable = append(insertable, ID, tag, now)
 	}
 
-	var paramString string = manyParamString("(?, ?, ?)", length)
-	_, err = database_handle.Exec(WRITE_TAGS_OF_MANY_ID+paramString, insertable...)
+	_, err = store.db().ExecContext(ctx, WRITE_TAGS_OF_MANY_ID+manyParamString("(?, ?, ?)", len(unique)), insertable...)
 	return
 }
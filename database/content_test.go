@@ -4,6 +4,7 @@ import (
 	"github.com/brane-app/librane/types"
 	"github.com/google/uuid"
 
+	"context"
 	"sort"
 	"strconv"
 	"testing"
@@ -65,6 +66,17 @@ func populate(limit int) {
 	return
 }
 
+func populateSingle(test *testing.T) (ID string) {
+	ID = uuid.New().String()
+
+	var err error
+	if err = WriteContent(mapMod(writableContent, map[string]interface{}{"id": ID})); err != nil {
+		test.Fatal(err)
+	}
+
+	return
+}
+
 func populateAuthor(author string, limit int) {
 	var now int64 = time.Now().Unix()
 	var modified map[string]interface{}
@@ -143,6 +155,46 @@ func Test_WriteContent_err(test *testing.T) {
 	}
 }
 
+func Test_WriteContent_votes(test *testing.T) {
+	var id string = uuid.New().String()
+	var voter string = uuid.New().String()
+	var writable map[string]interface{} = mapMod(
+		writableContent,
+		map[string]interface{}{"id": id},
+	)
+
+	var err error
+	if err = WriteContent(writable); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = Vote(voter, id, VOTE_LIKE); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = WriteContent(mapMod(writable, map[string]interface{}{"like_count": 0, "mime": "image/gif"})); err != nil {
+		test.Fatal(err)
+	}
+
+	var value int
+	if value, err = ReadVote(voter, id); err != nil {
+		test.Fatal(err)
+	}
+
+	if value != VOTE_LIKE {
+		test.Errorf("vote on rewritten content %s was lost, have: %d", id, value)
+	}
+
+	var fetched types.Content
+	if fetched, _, err = ReadSingleContent(id); err != nil {
+		test.Fatal(err)
+	}
+
+	if fetched.LikeCount != 1 || fetched.DislikeCount != 0 || fetched.Mime != "image/gif" {
+		test.Errorf("rewritten content %s mismatch! have: %#v", id, fetched)
+	}
+}
+
 func Test_DeleteContent(test *testing.T) {
 	var id string = uuid.New().String()
 	var writable map[string]interface{} = mapMod(
@@ -169,7 +221,7 @@ func Test_DeleteContent(test *testing.T) {
 	}
 
 	var tags []string
-	if tags, err = getTags(id); err != nil {
+	if tags, err = connected.getTags(context.Background(), id); err != nil {
 		test.Fatal(err)
 	}
 
@@ -502,3 +554,113 @@ func Test_ReadAuthorContent_fewer(test *testing.T) {
 		}
 	}
 }
+
+func Test_ReadSubscriptionFeed(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+
+	var subscriber string = uuid.New().String()
+	var authors map[string]bool = populateSubscriptions(subscriber, 10)
+
+	var count int = 15
+	var content []types.Content
+	var size int
+	var err error
+	if content, size, err = ReadSubscriptionFeed(subscriber, "", count); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != count {
+		test.Errorf("size mismatch! have: %d, want: %d", size, count)
+	}
+
+	if len(content) != size {
+		test.Errorf("block size mismatch! have: %d, want: %d", len(content), size)
+	}
+
+	var single types.Content
+	for _, single = range content {
+		if !authors[single.Author] {
+			test.Errorf("author %s is not subscribed to", single.Author)
+		}
+
+		if single.Tags == nil {
+			test.Errorf("%s has nil tags!", single.ID)
+		}
+	}
+}
+
+func Test_ReadSubscriptionFeed_after(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+
+	var subscriber string = uuid.New().String()
+	populateSubscriptions(subscriber, 10)
+
+	var count, offset int = 10, 5
+	var first, second []types.Content
+	var err error
+	if first, _, err = ReadSubscriptionFeed(subscriber, "", count); err != nil {
+		test.Fatal(err)
+	}
+
+	if second, _, err = ReadSubscriptionFeed(subscriber, first[offset].ID, count); err != nil {
+		test.Fatal(err)
+	}
+
+	var index int
+	var single types.Content
+	for index, single = range first[offset+1:] {
+		if single.ID != second[index].ID {
+			test.Errorf("IDs not aligned! have: %s, want: %s", second[index].ID, single.ID)
+		}
+	}
+}
+
+func Test_ReadSubscriptionFeed_removed(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+
+	var subscriber, author string = uuid.New().String(), subscribable(test).ID
+	Subscribe(subscriber, author)
+
+	var removed map[string]interface{} = mapMod(writableContent, map[string]interface{}{
+		"id":      uuid.New().String(),
+		"author":  author,
+		"removed": true,
+	})
+	WriteContent(removed)
+
+	var kept map[string]interface{} = mapMod(writableContent, map[string]interface{}{
+		"id":     uuid.New().String(),
+		"author": author,
+	})
+	WriteContent(kept)
+
+	var content []types.Content
+	var size int
+	var err error
+	if content, size, err = ReadSubscriptionFeed(subscriber, "", 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != 1 {
+		test.Fatalf("size mismatch! have: %d, want: %d", size, 1)
+	}
+
+	if content[0].ID != kept["id"].(string) {
+		test.Errorf("ID mismatch! have: %s, want: %s", content[0].ID, kept["id"])
+	}
+}
+
+func Test_ReadSubscriptionFeed_nothing(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+	populate(10)
+
+	var size int
+	var err error
+	if _, size, err = ReadSubscriptionFeed(uuid.New().String(), "", 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != 0 {
+		test.Errorf("subscribed to nobody got %d posts", size)
+	}
+}
@@ -0,0 +1,382 @@
+package database
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+/**
+ * How some SQL database differs from MariaDB
+ * Statements and table definitions are written for MariaDB, and rewritten for the others
+ * Dialects without REPLACE INTO have replaceKeys, the unique columns of each table that
+ * makeSQLInsertable writes to
+ * Errors of the driver are translated to the kinds in errors.go where they can be
+ * stale tells whether an error means that a prepared statement must be prepared again
+ * tableExists and columnExists count the tables of some name, and the columns of some name in some table,
+ * so that migrations can tell what a run of them that failed partway through left behind
+ */
+type dialect struct {
+	driver       string
+	dsn          func(address string) string
+	statement    func(statement string) string
+	table        func(definition string) string
+	prepare      func(handle *sqlx.DB)
+	configure    func(dsn string, config Config) (string, error)
+	translate    func(err error) error
+	stale        func(err error) bool
+	replaceKeys  map[string][]string
+	tableExists  string
+	columnExists string
+}
+
+var (
+	mariadbDialect *dialect = &dialect{
+		driver:       "mysql",
+		dsn:          func(address string) string { return address },
+		statement:    func(statement string) string { return statement },
+		table:        func(definition string) string { return definition },
+		prepare:      func(*sqlx.DB) {},
+		configure:    mariadbConfigure,
+		translate:    mariadbTranslate,
+		stale:        mariadbStale,
+		tableExists:  MARIADB_TABLE_EXISTS,
+		columnExists: MARIADB_COLUMN_EXISTS,
+	}
+
+	dialectSchemes map[string]*dialect = map[string]*dialect{
+		"mysql":      mariadbDialect,
+		"mariadb":    mariadbDialect,
+		"sqlite":     sqliteDialect,
+		"sqlite3":    sqliteDialect,
+		"postgres":   postgresDialect,
+		"postgresql": postgresDialect,
+	}
+
+	dialectDrivers map[string]*dialect = map[string]*dialect{
+		"mysql":    mariadbDialect,
+		"sqlite3":  sqliteDialect,
+		"postgres": postgresDialect,
+	}
+)
+
+/**
+ * Find the dialect of some connection string `address`, by its scheme
+ * Addresses without a scheme are MariaDB connection strings, like user:pass@tcp(addr)/table
+ * Returns the dialect, and the address that its driver should be given
+ */
+func dialectOfAddress(address string) (found *dialect, dsn string, err error) {
+	var index int = strings.Index(address, "://")
+	if index == -1 {
+		found, dsn = mariadbDialect, address
+		return
+	}
+
+	var ok bool
+	if found, ok = dialectSchemes[address[:index]]; !ok {
+		err = fmt.Errorf("Unsupported database scheme %s", address[:index])
+		return
+	}
+
+	dsn = found.dsn(address[index+3:])
+	return
+}
+
+/**
+ * Find the dialect of some driver `driver`, defaulting to MariaDB
+ */
+func dialectOfDriver(driver string) (found *dialect) {
+	var ok bool
+	if found, ok = dialectDrivers[driver]; !ok {
+		found = mariadbDialect
+	}
+
+	return
+}
+
+/**
+ * Something that statements can be run on, either a dialectDB or a dialectTx
+ */
+type dialectQueryer interface {
+	ExecContext(ctx context.Context, statement string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, statement string, args ...interface{}) (*sql.Rows, error)
+	QueryxContext(ctx context.Context, statement string, args ...interface{}) (*sqlx.Rows, error)
+	QueryRowxContext(ctx context.Context, statement string, args ...interface{}) *dialectRow
+}
+
+/**
+ * A handle that rewrites every statement for its dialect before running it,
+ * and runs those of preparedStatements as prepared statements
+ */
+type dialectDB struct {
+	*sqlx.DB
+	dialect    *dialect
+	statements sync.Map
+	prepared   sync.Map
+}
+
+/**
+ * A transaction that rewrites every statement for its dialect before running it
+ * Statements that its handle has already prepared are run as prepared statements, but none are
+ * prepared by it, as preparing on the handle would wait on a connection that SQLite doesn't have
+ */
+type dialectTx struct {
+	*sqlx.Tx
+	db *dialectDB
+}
+
+/**
+ * A row that translates its errors for its dialect, as those of a query of one row
+ * only come out once it's scanned
+ */
+type dialectRow struct {
+	*sqlx.Row
+	dialect *dialect
+}
+
+func newDialectDB(handle *sqlx.DB) (wrapped *dialectDB) {
+	wrapped = &dialectDB{
+		DB:      handle,
+		dialect: dialectOfDriver(handle.DriverName()),
+	}
+
+	wrapped.dialect.prepare(handle)
+	return
+}
+
+/**
+ * Rewrite some statement `statement` for this dialect
+ * Rewritten statements are remembered, as the same few statements are run over and over
+ */
+func (handle *dialectDB) rewrite(statement string) (rewritten string) {
+	var cached interface{}
+	var ok bool
+	if cached, ok = handle.statements.Load(statement); ok {
+		rewritten = cached.(string)
+		return
+	}
+
+	rewritten = handle.dialect.statement(statement)
+	handle.statements.Store(statement, rewritten)
+	return
+}
+
+func (handle *dialectDB) Exec(statement string, args ...interface{}) (sql.Result, error) {
+	return handle.ExecContext(context.Background(), statement, args...)
+}
+
+func (handle *dialectDB) Query(statement string, args ...interface{}) (*sql.Rows, error) {
+	return handle.QueryContext(context.Background(), statement, args...)
+}
+
+func (handle *dialectDB) Queryx(statement string, args ...interface{}) (*sqlx.Rows, error) {
+	return handle.QueryxContext(context.Background(), statement, args...)
+}
+
+func (handle *dialectDB) QueryRowx(statement string, args ...interface{}) *dialectRow {
+	return handle.QueryRowxContext(context.Background(), statement, args...)
+}
+
+func (handle *dialectDB) Beginx() (tx *dialectTx, err error) {
+	tx, err = handle.BeginTxx(context.Background(), nil)
+	return
+}
+
+func (handle *dialectDB) ExecContext(ctx context.Context, statement string, args ...interface{}) (result sql.Result, err error) {
+	var stmt *sqlx.Stmt
+	if stmt = handle.stmt(ctx, statement); stmt != nil {
+		result, err = stmt.ExecContext(ctx, args...)
+	}
+
+	if stmt == nil || handle.stale(statement, stmt, err) {
+		result, err = handle.DB.ExecContext(ctx, handle.rewrite(statement), args...)
+	}
+
+	wroteWith(ctx)
+
+	if err != nil {
+		err = handle.dialect.translate(err)
+	}
+
+	return
+}
+
+func (handle *dialectDB) QueryContext(ctx context.Context, statement string, args ...interface{}) (rows *sql.Rows, err error) {
+	var stmt *sqlx.Stmt
+	if stmt = handle.stmt(ctx, statement); stmt != nil {
+		rows, err = stmt.QueryContext(ctx, args...)
+	}
+
+	if stmt == nil || handle.stale(statement, stmt, err) {
+		rows, err = handle.DB.QueryContext(ctx, handle.rewrite(statement), args...)
+	}
+
+	if err != nil {
+		err = handle.dialect.translate(err)
+	}
+
+	return
+}
+
+func (handle *dialectDB) QueryxContext(ctx context.Context, statement string, args ...interface{}) (rows *sqlx.Rows, err error) {
+	var stmt *sqlx.Stmt
+	if stmt = handle.stmt(ctx, statement); stmt != nil {
+		rows, err = stmt.QueryxContext(ctx, args...)
+	}
+
+	if stmt == nil || handle.stale(statement, stmt, err) {
+		rows, err = handle.DB.QueryxContext(ctx, handle.rewrite(statement), args...)
+	}
+
+	if err != nil {
+		err = handle.dialect.translate(err)
+	}
+
+	return
+}
+
+func (handle *dialectDB) QueryRowxContext(ctx context.Context, statement string, args ...interface{}) (row *dialectRow) {
+	var stmt *sqlx.Stmt
+	var found *sqlx.Row
+	if stmt = handle.stmt(ctx, statement); stmt != nil {
+		found = stmt.QueryRowxContext(ctx, args...)
+	}
+
+	if stmt == nil || handle.stale(statement, stmt, found.Err()) {
+		found = handle.DB.QueryRowxContext(ctx, handle.rewrite(statement), args...)
+	}
+
+	row = &dialectRow{found, handle.dialect}
+	return
+}
+
+func (handle *dialectDB) BeginTxx(ctx context.Context, options *sql.TxOptions) (tx *dialectTx, err error) {
+	var begun *sqlx.Tx
+	if begun, err = handle.DB.BeginTxx(ctx, options); err == nil {
+		tx = &dialectTx{begun, handle}
+	}
+
+	return
+}
+
+func (tx *dialectTx) Exec(statement string, args ...interface{}) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), statement, args...)
+}
+
+func (tx *dialectTx) Query(statement string, args ...interface{}) (*sql.Rows, error) {
+	return tx.QueryContext(context.Background(), statement, args...)
+}
+
+func (tx *dialectTx) Queryx(statement string, args ...interface{}) (*sqlx.Rows, error) {
+	return tx.QueryxContext(context.Background(), statement, args...)
+}
+
+func (tx *dialectTx) QueryRowx(statement string, args ...interface{}) *dialectRow {
+	return tx.QueryRowxContext(context.Background(), statement, args...)
+}
+
+/**
+ * Get the prepared statement of `statement` that its handle has, and bind it to this transaction
+ * bound is nil if its handle hasn't prepared it
+ */
+func (tx *dialectTx) stmt(ctx context.Context, statement string) (stmt, bound *sqlx.Stmt) {
+	if stmt = tx.db.cached(statement); stmt != nil {
+		bound = tx.Tx.StmtxContext(ctx, stmt)
+	}
+
+	return
+}
+
+func (tx *dialectTx) ExecContext(ctx context.Context, statement string, args ...interface{}) (result sql.Result, err error) {
+	var stmt, bound *sqlx.Stmt
+	if stmt, bound = tx.stmt(ctx, statement); bound != nil {
+		result, err = bound.ExecContext(ctx, args...)
+	}
+
+	if bound == nil || tx.db.stale(statement, stmt, err) {
+		result, err = tx.Tx.ExecContext(ctx, tx.db.rewrite(statement), args...)
+	}
+
+	if err != nil {
+		err = tx.db.dialect.translate(err)
+	}
+
+	return
+}
+
+func (tx *dialectTx) QueryContext(ctx context.Context, statement string, args ...interface{}) (rows *sql.Rows, err error) {
+	var stmt, bound *sqlx.Stmt
+	if stmt, bound = tx.stmt(ctx, statement); bound != nil {
+		rows, err = bound.QueryContext(ctx, args...)
+	}
+
+	if bound == nil || tx.db.stale(statement, stmt, err) {
+		rows, err = tx.Tx.QueryContext(ctx, tx.db.rewrite(statement), args...)
+	}
+
+	if err != nil {
+		err = tx.db.dialect.translate(err)
+	}
+
+	return
+}
+
+func (tx *dialectTx) QueryxContext(ctx context.Context, statement string, args ...interface{}) (rows *sqlx.Rows, err error) {
+	var stmt, bound *sqlx.Stmt
+	if stmt, bound = tx.stmt(ctx, statement); bound != nil {
+		rows, err = bound.QueryxContext(ctx, args...)
+	}
+
+	if bound == nil || tx.db.stale(statement, stmt, err) {
+		rows, err = tx.Tx.QueryxContext(ctx, tx.db.rewrite(statement), args...)
+	}
+
+	if err != nil {
+		err = tx.db.dialect.translate(err)
+	}
+
+	return
+}
+
+func (tx *dialectTx) QueryRowxContext(ctx context.Context, statement string, args ...interface{}) (row *dialectRow) {
+	var stmt, bound *sqlx.Stmt
+	var found *sqlx.Row
+	if stmt, bound = tx.stmt(ctx, statement); bound != nil {
+		found = bound.QueryRowxContext(ctx, args...)
+	}
+
+	if bound == nil || tx.db.stale(statement, stmt, found.Err()) {
+		found = tx.Tx.QueryRowxContext(ctx, tx.db.rewrite(statement), args...)
+	}
+
+	row = &dialectRow{found, tx.db.dialect}
+	return
+}
+
+func (row *dialectRow) Err() (err error) {
+	if err = row.Row.Err(); err != nil {
+		err = row.dialect.translate(err)
+	}
+
+	return
+}
+
+func (row *dialectRow) Scan(dest ...interface{}) (err error) {
+	if err = row.Row.Scan(dest...); err != nil {
+		err = row.dialect.translate(err)
+	}
+
+	return
+}
+
+func (row *dialectRow) StructScan(dest interface{}) (err error) {
+	if err = row.Row.StructScan(dest); err != nil {
+		err = row.dialect.translate(err)
+	}
+
+	return
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/**
+ * A kind of error, that may be a narrower kind of some `parent`
+ */
+type errorKind struct {
+	message string
+	parent  error
+}
+
+func (kind *errorKind) Error() string {
+	return kind.message
+}
+
+func (kind *errorKind) Unwrap() error {
+	return kind.parent
+}
+
+// Kinds of errors that are returned by the store, to be checked with errors.Is
+// Reads of a single row still report whether it exists with a bool, so ErrNotFound is
+// for writes that need some other row that isn't there
+var (
+	ErrNotFound       error = &errorKind{"Not found", nil}
+	ErrDuplicate      error = &errorKind{"Already exists", nil}
+	ErrDuplicateNick  error = &errorKind{"Nick is taken", ErrDuplicate}
+	ErrDuplicateEmail error = &errorKind{"Email is taken", ErrDuplicate}
+	ErrInvalid        error = &errorKind{"Invalid", nil}
+	ErrInvalidToken   error = &errorKind{"Invalid token", ErrInvalid}
+	ErrReusedToken    error = &errorKind{"Reused token", ErrInvalidToken}
+)
+
+/**
+ * An error of some kind `Kind`, caused by `Err`
+ * errors.Is matches the kind, errors.As and errors.Unwrap get to the error of the driver
+ */
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (err *Error) Error() string {
+	return err.Err.Error()
+}
+
+func (err *Error) Is(target error) bool {
+	return errors.Is(err.Kind, target)
+}
+
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+/**
+ * Make an error of some kind `kind`, with a message formatted like fmt.Errorf
+ */
+func errorOf(kind error, format string, values ...interface{}) (err error) {
+	err = &Error{kind, fmt.Errorf(format, values...)}
+	return
+}
+
+/**
+ * Get the kind of duplicate error from the message of a unique constraint failing,
+ * which names the key or column that it failed on
+ */
+func duplicateOf(message string) (kind error) {
+	message = strings.ToLower(message)
+
+	switch {
+	case strings.Contains(message, "nick"):
+		kind = ErrDuplicateNick
+	case strings.Contains(message, "email"):
+		kind = ErrDuplicateEmail
+	default:
+		kind = ErrDuplicate
+	}
+
+	return
+}
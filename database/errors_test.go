@@ -0,0 +1,70 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/google/uuid"
+
+	"errors"
+	"testing"
+)
+
+func Test_Error(test *testing.T) {
+	var cause error = errors.New("Error 1062: Duplicate entry 'imonke' for key 'nick'")
+	var err error = &Error{duplicateOf(cause.Error()), cause}
+
+	if !errors.Is(err, ErrDuplicateNick) || !errors.Is(err, ErrDuplicate) {
+		test.Errorf("%v is not ErrDuplicateNick", err)
+	}
+
+	if errors.Is(err, ErrDuplicateEmail) || errors.Is(err, ErrNotFound) {
+		test.Errorf("%v is some other kind", err)
+	}
+
+	if errors.Unwrap(err) != cause || err.Error() != cause.Error() {
+		test.Errorf("%v does not wrap %v", err, cause)
+	}
+}
+
+func Test_duplicateOf(test *testing.T) {
+	var cases map[string]error = map[string]error{
+		"Duplicate entry 'a' for key 'users.nick'":        ErrDuplicateNick,
+		"UNIQUE constraint failed: users.email":           ErrDuplicateEmail,
+		"users_email_key Key (email)=(a) already exists.": ErrDuplicateEmail,
+		"Duplicate entry 'a' for key 'PRIMARY'":           ErrDuplicate,
+	}
+
+	var message string
+	var want error
+	for message, want = range cases {
+		if have := duplicateOf(message); have != want {
+			test.Errorf("kind of %s mismatch! have: %v, want: %v", message, have, want)
+		}
+	}
+}
+
+/**
+ * Some databases don't name the key that a duplicate entry is of, so this only checks that
+ * it's some ErrDuplicate
+ */
+func Test_translate_duplicate(test *testing.T) {
+	var written types.User = types.NewUser(uuid.New().String()[:16], "", uuid.New().String()+"@monke.io")
+
+	var err error
+	if err = WriteUser(written.Map()); err != nil {
+		test.Fatal(err)
+	}
+
+	var statement string
+	var values []interface{}
+	statement, values = makeSQLInsertable(USER_TABLE, types.NewUser(written.Nick, "", uuid.New().String()+"@monke.io").Map())
+	if _, err = connected.handle.Exec("INSERT"+statement[len("REPLACE"):], values...); !errors.Is(err, ErrDuplicate) {
+		test.Errorf("duplicate nick from the driver was not ErrDuplicate, have: %v", err)
+	}
+}
+
+func Test_translate_invalid(test *testing.T) {
+	var err error
+	if _, err = connected.handle.Exec("INSERT INTO "+USER_TABLE+" (id, answer) VALUES (?, ?)", uuid.New().String(), 42); !errors.Is(err, ErrInvalid) {
+		test.Errorf("unknown column from the driver was not ErrInvalid, have: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/jmoiron/sqlx"
+
+	"context"
+	"database/sql"
+)
+
+type feedRow struct {
+	Entry    string `db:"entry"`
+	Original string `db:"original"`
+	Reposter string `db:"reposter"`
+	Created  int64  `db:"created"`
+}
+
+/**
+ * Read `count` feed entries with `statement`, or with `statementAfter` if there's some entry of id `before`
+ * Both statements are given `ID` (and the position of `before`) once for original posts, and once for repubs
+ * Entries are ordered by order_index, newest first, where each repub is placed by the order_index of the newest
+ * content when it was made, and after any content of that order_index
+ * Uses up to 4 queries
+ * 		get index: 		SELECT order_index, 0 FROM CONTENT_TABLE WHERE id=before UNION ALL SELECT feed_index, order_index FROM REPUB_TABLE WHERE id=before
+ * 		get entries: 	`statement` or `statementAfter`
+ * 		queries from: 	readManyContentOf
+ */
+func (store *SQLStore) readFeed(ctx context.Context, statement, statementAfter, ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	var rows *sqlx.Rows
+	if before == "" {
+		rows, err = store.db().QueryxContext(ctx, statement, ID, ID, count)
+	} else {
+		var index, repubIndex int64
+		if err = store.db().QueryRowxContext(ctx, READ_INDEX_OF_FEED_ENTRY, before, before).Scan(&index, &repubIndex); err != nil {
+			if err == sql.ErrNoRows {
+				entries, err = make([]types.FeedEntry, 0), nil
+			}
+
+			return
+		}
+
+		// content of the same order_index as a repub comes before it
+		var originalIndex int64 = index
+		if repubIndex != 0 {
+			originalIndex++
+		}
+
+		rows, err = store.db().QueryxContext(ctx,
+			statementAfter,
+			ID, originalIndex,
+			ID, index, index, repubIndex,
+			count,
+		)
+	}
+
+	if err != nil {
+		return
+	}
+
+	var scanned []feedRow = make([]feedRow, count)
+	var IDs []string = make([]string, count)
+	for rows.Next() {
+		if err = rows.StructScan(&scanned[size]); err != nil {
+			rows.Close()
+			return
+		}
+
+		IDs[size] = scanned[size].Original
+		size++
+	}
+
+	rows.Close()
+
+	var content map[string]types.Content
+	if content, err = store.readManyContentOf(ctx, IDs[:size]); err != nil {
+		return
+	}
+
+	entries = make([]types.FeedEntry, size)
+
+	var index int
+	var row feedRow
+	for index, row = range scanned[:size] {
+		entries[index] = types.FeedEntry{
+			ID:      row.Entry,
+			Content: content[row.Original],
+		}
+
+		if row.Reposter != "" {
+			entries[index].Repub = &types.Repub{
+				ID:      row.Entry,
+				Content: row.Original,
+				Author:  row.Reposter,
+				Created: row.Created,
+			}
+		}
+	}
+
+	return
+}
+
+/**
+ * Read `count` feed entries of some user of id `ID`, before entry of id `before`
+ * Entries are both the content authored by that user, and their repubs of other content
+ * If the first set of entries should be read, `before` may be empty
+ * Removed content is skipped
+ * Uses up to 4 queries, from readFeed
+ */
+func (store *SQLStore) ReadAuthorEntriesContext(ctx context.Context, ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	store = store.reader(ctx)
+	entries, size, err = store.readFeed(ctx, READ_FEED_OF_AUTHOR, READ_FEED_OF_AUTHOR_AFTER_ID, ID, before, count)
+	return
+}
+
+func (store *SQLStore) ReadAuthorEntries(ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	entries, size, err = store.ReadAuthorEntriesContext(context.Background(), ID, before, count)
+	return
+}
+
+/**
+ * Read `count` feed entries from anyone that some user of id `ID` is subscribed to, before entry of id `before`
+ * Entries are both the content authored by those users, and their repubs of other content
+ * This is ReadSubscriptionFeed, but with repubs
+ * If the first set of entries should be read, `before` may be empty
+ * Removed content is skipped
+ * Uses up to 4 queries, from readFeed
+ */
+func (store *SQLStore) ReadSubscriptionEntriesContext(ctx context.Context, ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	store = store.reader(ctx)
+	entries, size, err = store.readFeed(ctx, READ_FEED_OF_SUBSCRIPTIONS, READ_FEED_OF_SUBSCRIPTIONS_AFTER_ID, ID, before, count)
+	return
+}
+
+func (store *SQLStore) ReadSubscriptionEntries(ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	entries, size, err = store.ReadSubscriptionEntriesContext(context.Background(), ID, before, count)
+	return
+}
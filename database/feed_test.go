@@ -0,0 +1,450 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/google/uuid"
+
+	"errors"
+	"testing"
+)
+
+func populateSubscriptions(subscriber string, limit int) (authors map[string]bool) {
+	authors = map[string]bool{}
+
+	var author string
+	for len(authors) != 2 {
+		author = uuid.New().String()
+		var user types.User = types.NewUser(author[:16], "", author+"@imonke.io")
+		WriteUser(user.Map())
+		Subscribe(subscriber, user.ID)
+		authors[user.ID] = true
+	}
+
+	var modified map[string]interface{}
+	var index int = 0
+	for index != limit {
+		for author = range authors {
+			modified = mapCopy(writableContent)
+			modified["id"] = uuid.New().String()
+			modified["author"] = author
+			WriteContent(modified)
+		}
+
+		modified = mapCopy(writableContent)
+		modified["id"] = uuid.New().String()
+		modified["author"] = uuid.New().String()
+		WriteContent(modified)
+
+		index++
+	}
+
+	return
+}
+
+func feedAligned(test *testing.T, first, second []types.FeedEntry) {
+	var index int
+	var single types.FeedEntry
+	for index, single = range first {
+		if single.ID != second[index].ID {
+			test.Errorf("IDs not aligned! have: %s, want: %s", second[index].ID, single.ID)
+		}
+	}
+}
+
+func Test_Repub(test *testing.T) {
+	var ID string = populateSingle(test)
+	var author string = uuid.New().String()
+
+	var err error
+	if err = Repub(author, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = Repub(author, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	var repubbed bool
+	if repubbed, err = IsRepubbed(author, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if !repubbed {
+		test.Errorf("%s did not repub %s", author, ID)
+	}
+
+	var fetched types.Content
+	if fetched, _, err = ReadSingleContent(ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if fetched.RepubCount != 1 {
+		test.Errorf("repub count mismatch! have: %d, want: %d", fetched.RepubCount, 1)
+	}
+}
+
+func Test_Repub_nothing(test *testing.T) {
+	var ID, author string = uuid.New().String(), uuid.New().String()
+
+	var err error
+	if err = Repub(author, ID); !errors.Is(err, ErrNotFound) {
+		test.Errorf("repub of content that does not exist was not ErrNotFound, have: %v", err)
+	}
+
+	var repubbed bool
+	if repubbed, err = IsRepubbed(author, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if repubbed {
+		test.Errorf("%s repubbed %s, which does not exist", author, ID)
+	}
+}
+
+func Test_Repub_deleted(test *testing.T) {
+	var ID string = populateSingle(test)
+	var author string = uuid.New().String()
+
+	var err error
+	if err = Repub(author, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = DeleteContent(ID); err != nil {
+		test.Fatal(err)
+	}
+
+	var repubbed bool
+	if repubbed, err = IsRepubbed(author, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if repubbed {
+		test.Errorf("repub of deleted content %s was kept", ID)
+	}
+}
+
+func Test_Unrepub(test *testing.T) {
+	var ID string = populateSingle(test)
+	var author string = uuid.New().String()
+
+	var err error
+	if err = Repub(author, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = Unrepub(author, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = Unrepub(author, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	var repubbed bool
+	if repubbed, err = IsRepubbed(author, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if repubbed {
+		test.Errorf("%s still repubbed %s", author, ID)
+	}
+
+	var fetched types.Content
+	if fetched, _, err = ReadSingleContent(ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if fetched.RepubCount != 0 {
+		test.Errorf("repub count mismatch! have: %d, want: %d", fetched.RepubCount, 0)
+	}
+}
+
+func Test_ReadAuthorEntries(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+
+	var author string = uuid.New().String()
+	populateAuthor(author, 5)
+
+	var other string = uuid.New().String()
+	populateAuthor(other, 5)
+
+	var repubbed []types.Content
+	var err error
+	if repubbed, _, err = ReadAuthorContent(other, "", 3); err != nil {
+		test.Fatal(err)
+	}
+
+	var single types.Content
+	for _, single = range repubbed {
+		if err = Repub(author, single.ID); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	var entries []types.FeedEntry
+	var size int
+	if entries, size, err = ReadAuthorEntries(author, "", 20); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != 8 {
+		test.Errorf("size mismatch! have: %d, want: %d", size, 8)
+	}
+
+	if len(entries) != size {
+		test.Errorf("block size mismatch! have: %d, want: %d", len(entries), size)
+	}
+
+	var repubs int
+	var entry types.FeedEntry
+	for _, entry = range entries {
+		if entry.Repub == nil {
+			if entry.Content.Author != author || entry.ID != entry.Content.ID {
+				test.Errorf("original entry %#v is not by %s", entry, author)
+			}
+
+			continue
+		}
+
+		repubs++
+		if entry.Repub.Author != author || entry.Content.Author != other {
+			test.Errorf("repub entry %#v is not by %s of %s", entry, author, other)
+		}
+
+		if entry.Repub.Content != entry.Content.ID || entry.ID != entry.Repub.ID {
+			test.Errorf("repub entry %#v does not point at its original", entry)
+		}
+
+		if entry.Content.Tags == nil {
+			test.Errorf("%s has nil tags!", entry.Content.ID)
+		}
+	}
+
+	if repubs != len(repubbed) {
+		test.Errorf("repub count mismatch! have: %d, want: %d", repubs, len(repubbed))
+	}
+}
+
+func Test_ReadAuthorEntries_order(test *testing.T) {
+	var author, repubbed string = uuid.New().String(), populateSingle(test)
+
+	var err error
+	if err = Repub(author, repubbed); err != nil {
+		test.Fatal(err)
+	}
+
+	var original map[string]interface{} = mapMod(writableContent, map[string]interface{}{
+		"id":      uuid.New().String(),
+		"author":  author,
+		"created": 1,
+	})
+
+	if err = WriteContent(original); err != nil {
+		test.Fatal(err)
+	}
+
+	var entries []types.FeedEntry
+	if entries, _, err = ReadAuthorEntries(author, "", 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if len(entries) != 2 || entries[0].ID != original["id"].(string) || entries[1].Content.ID != repubbed {
+		test.Fatalf("entries are not in the order they were written! have: %#v", entries)
+	}
+
+	var after []types.FeedEntry
+	if after, _, err = ReadAuthorEntries(author, entries[0].ID, 10); err != nil {
+		test.Fatal(err)
+	}
+
+	feedAligned(test, entries[1:], after)
+
+	if after, _, err = ReadAuthorEntries(author, entries[1].ID, 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if len(after) != 0 {
+		test.Errorf("read after the last entry got %#v", after)
+	}
+}
+
+func Test_ReadAuthorEntries_after(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+
+	var author string = uuid.New().String()
+	populateAuthor(author, 10)
+
+	var repubbed []types.Content
+	var err error
+	if repubbed, _, err = ReadManyContent("", 10); err != nil {
+		test.Fatal(err)
+	}
+
+	var single types.Content
+	for _, single = range repubbed {
+		Repub(author, single.ID)
+	}
+
+	var count, offset int = 10, 5
+	var first, second []types.FeedEntry
+	if first, _, err = ReadAuthorEntries(author, "", count); err != nil {
+		test.Fatal(err)
+	}
+
+	if second, _, err = ReadAuthorEntries(author, first[offset].ID, count); err != nil {
+		test.Fatal(err)
+	}
+
+	feedAligned(test, first[offset+1:], second)
+}
+
+func Test_ReadAuthorEntries_afterNothing(test *testing.T) {
+	var author string = uuid.New().String()
+	populateAuthor(author, 5)
+
+	var entries []types.FeedEntry
+	var err error
+	if entries, _, err = ReadAuthorEntries(author, "foobar", 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		test.Errorf("read after nonexisting id got %v", entries)
+	}
+}
+
+func Test_ReadSubscriptionEntries(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+
+	var subscriber string = uuid.New().String()
+	var authors map[string]bool = populateSubscriptions(subscriber, 10)
+
+	var count int = 15
+	var entries []types.FeedEntry
+	var size int
+	var err error
+	if entries, size, err = ReadSubscriptionEntries(subscriber, "", count); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != count {
+		test.Errorf("size mismatch! have: %d, want: %d", size, count)
+	}
+
+	if len(entries) != size {
+		test.Errorf("block size mismatch! have: %d, want: %d", len(entries), size)
+	}
+
+	var single types.FeedEntry
+	for _, single = range entries {
+		if !authors[single.Content.Author] {
+			test.Errorf("author %s is not subscribed to", single.Content.Author)
+		}
+
+		if single.Content.Tags == nil {
+			test.Errorf("%s has nil tags!", single.ID)
+		}
+	}
+}
+
+func Test_ReadSubscriptionEntries_after(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+
+	var subscriber string = uuid.New().String()
+	populateSubscriptions(subscriber, 10)
+
+	var count, offset int = 10, 5
+	var first, second []types.FeedEntry
+	var err error
+	if first, _, err = ReadSubscriptionEntries(subscriber, "", count); err != nil {
+		test.Fatal(err)
+	}
+
+	if second, _, err = ReadSubscriptionEntries(subscriber, first[offset].ID, count); err != nil {
+		test.Fatal(err)
+	}
+
+	feedAligned(test, first[offset+1:], second)
+}
+
+func Test_ReadSubscriptionEntries_repubs(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+
+	var subscriber, author string = uuid.New().String(), subscribable(test).ID
+	Subscribe(subscriber, author)
+
+	var ID string = populateSingle(test)
+	Repub(author, ID)
+	Repub(uuid.New().String(), populateSingle(test))
+
+	var entries []types.FeedEntry
+	var size int
+	var err error
+	if entries, size, err = ReadSubscriptionEntries(subscriber, "", 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != 1 {
+		test.Fatalf("size mismatch! have: %d, want: %d", size, 1)
+	}
+
+	if entries[0].Repub == nil || entries[0].Repub.Author != author {
+		test.Errorf("entry %#v is not a repub by %s", entries[0], author)
+	}
+
+	if entries[0].Content.ID != ID {
+		test.Errorf("ID mismatch! have: %s, want: %s", entries[0].Content.ID, ID)
+	}
+}
+
+func Test_ReadSubscriptionEntries_removed(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+
+	var subscriber, author string = uuid.New().String(), subscribable(test).ID
+	Subscribe(subscriber, author)
+
+	var removed map[string]interface{} = mapMod(writableContent, map[string]interface{}{
+		"id":      uuid.New().String(),
+		"author":  author,
+		"removed": true,
+	})
+	WriteContent(removed)
+	Repub(author, removed["id"].(string))
+
+	var kept map[string]interface{} = mapMod(writableContent, map[string]interface{}{
+		"id":     uuid.New().String(),
+		"author": author,
+	})
+	WriteContent(kept)
+
+	var entries []types.FeedEntry
+	var size int
+	var err error
+	if entries, size, err = ReadSubscriptionEntries(subscriber, "", 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != 1 {
+		test.Fatalf("size mismatch! have: %d, want: %d", size, 1)
+	}
+
+	if entries[0].ID != kept["id"].(string) {
+		test.Errorf("ID mismatch! have: %s, want: %s", entries[0].ID, kept["id"])
+	}
+}
+
+func Test_ReadSubscriptionEntries_nothing(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+	populate(10)
+
+	var size int
+	var err error
+	if _, size, err = ReadSubscriptionEntries(uuid.New().String(), "", 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != 0 {
+		test.Errorf("subscribed to nobody got %d posts", size)
+	}
+}
@@ -1,11 +1,12 @@
 package database
 
 import (
-	"github.com/jmoiron/sqlx"
+	"github.com/go-sql-driver/mysql"
 
+	"context"
+	"errors"
 	"fmt"
-
-	_ "github.com/go-sql-driver/mysql"
+	"sync/atomic"
 )
 
 // Longest allowed mimetype is 255 ( {127}/{127} ) per RFC 4288
@@ -13,8 +14,9 @@ import (
 // Max CHAR size is 255
 
 var (
-	database_handle *sqlx.DB
-	tables          map[string]string = map[string]string{
+	// Definitions of every table at the latest schema
+	// Migrations don't use these, and keep the definitions that they create in migrate.go
+	tables map[string]string = map[string]string{
 		CONTENT_TABLE: `
 			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
 			file_url CHAR(64) NOT NULL,
@@ -46,13 +48,34 @@ var (
 		AUTH_TABLE: `
 			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
 			hash BINARY(60) NOT NULL`,
-		TOKEN_TABLE: `
+		SESSION_TABLE: `
 			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
-			token BINARY(24) UNIQUE,
-			created BIGINT UNSIGNED NOT NULL`,
+			owner CHAR(36) NOT NULL,
+			token BINARY(32) UNIQUE NOT NULL,
+			device CHAR(64) NOT NULL,
+			ip CHAR(45) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			last_used BIGINT UNSIGNED NOT NULL,
+			issued BIGINT UNSIGNED NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`,
+		REFRESH_TABLE: `
+			token BINARY(32) UNIQUE PRIMARY KEY NOT NULL,
+			session CHAR(36) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			used BOOLEAN NOT NULL`,
 		SECRET_TABLE: `
 			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
-			secret BINARY(128) UNIQUE`,
+			secret BINARY(32) UNIQUE NOT NULL`,
+		APIKEY_TABLE: `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			owner CHAR(36) NOT NULL,
+			token BINARY(32) UNIQUE NOT NULL,
+			name CHAR(64) NOT NULL,
+			scopes CHAR(255) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			expires BIGINT UNSIGNED NOT NULL,
+			last_used BIGINT UNSIGNED NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`,
 		TAG_TABLE: `
 			id CHAR(36) NOT NULL,
 			tag CHAR(64) NOT NULL,
@@ -60,6 +83,30 @@ var (
 			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT,
 			CONSTRAINT no_dupe_tags UNIQUE(id, tag),
 			CONSTRAINT content_bound_tags FOREIGN KEY (id) REFERENCES ` + CONTENT_TABLE + `(id) ON DELETE CASCADE`,
+		COMMENT_TABLE: `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			content CHAR(36) NOT NULL,
+			author CHAR(36) NOT NULL,
+			parent CHAR(36) NOT NULL,
+			body TEXT NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			edited BIGINT UNSIGNED NOT NULL,
+			deleted BOOLEAN NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`,
+		REPUB_TABLE: `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			content CHAR(36) NOT NULL,
+			author CHAR(36) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT,
+			feed_index BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			CONSTRAINT no_dupe_repubs UNIQUE(author, content)`,
+		VOTE_TABLE: `
+			voter CHAR(36) NOT NULL,
+			content CHAR(36) NOT NULL,
+			value TINYINT NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			CONSTRAINT unique_votes UNIQUE(voter, content)`,
 		SUBSCRIPTION_TABLE: `
 			subscriber CHAR(36) NOT NULL,
 			subscription CHAR(36) NOT NULL,
@@ -87,16 +134,23 @@ var (
 			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`,
 	}
 
-	tableOrdered []string = []string{
+	// Every table of the latest schema, in an order that they can be created in
+	// Tables are added here as migrations create them, and removed as migrations drop them
+	tableLatest []string = []string{
 		USER_TABLE,
 		CONTENT_TABLE,
 		AUTH_TABLE,
-		TOKEN_TABLE,
+		SESSION_TABLE,
+		REFRESH_TABLE,
 		SECRET_TABLE,
+		APIKEY_TABLE,
 		SUBSCRIPTION_TABLE,
 		BAN_TABLE,
 		REPORT_TABLE,
 		TAG_TABLE,
+		VOTE_TABLE,
+		COMMENT_TABLE,
+		REPUB_TABLE,
 	}
 )
 
@@ -105,13 +159,98 @@ const (
 	CONTENT_TABLE      = "content"
 	AUTH_TABLE         = "auth"
 	TOKEN_TABLE        = "token"
+	SESSION_TABLE      = "sessions"
+	REFRESH_TABLE      = "refresh"
 	SECRET_TABLE       = "secret"
+	APIKEY_TABLE       = "api_keys"
 	TAG_TABLE          = "tags"
 	SUBSCRIPTION_TABLE = "subs"
 	BAN_TABLE          = "bans"
 	REPORT_TABLE       = "reports"
+	VOTE_TABLE         = "votes"
+	COMMENT_TABLE      = "comments"
+	REPUB_TABLE        = "repubs"
 )
 
+const (
+	MARIADB_TABLE_EXISTS  = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema=DATABASE() AND table_name=?"
+	MARIADB_COLUMN_EXISTS = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema=DATABASE() AND table_name=? AND column_name=?"
+)
+
+var (
+	// How many tls.Configs have been registered with the mysql driver, to name the next one
+	mariadbTLSCount int64
+)
+
+/**
+ * Configure a MariaDB connection string
+ * The mysql driver is given a tls.Config by registering it under a name that the connection string uses
+ */
+func mariadbConfigure(dsn string, config Config) (configured string, err error) {
+	configured = dsn
+	if config.TLS == nil && config.ReadTimeout <= 0 {
+		return
+	}
+
+	var parsed *mysql.Config
+	if parsed, err = mysql.ParseDSN(dsn); err != nil {
+		return
+	}
+
+	if config.TLS != nil {
+		var name string = fmt.Sprintf("librane-%d", atomic.AddInt64(&mariadbTLSCount, 1))
+		if err = mysql.RegisterTLSConfig(name, config.TLS); err != nil {
+			return
+		}
+
+		parsed.TLSConfig = name
+	}
+
+	if config.ReadTimeout > 0 {
+		parsed.ReadTimeout = config.ReadTimeout
+	}
+
+	configured = parsed.FormatDSN()
+	return
+}
+
+/**
+ * Translate a MariaDB error by its number
+ * Duplicate entries name the key that they failed on, like 'nick' or 'users.nick'
+ */
+func mariadbTranslate(err error) (translated error) {
+	translated = err
+
+	var driverErr *mysql.MySQLError
+	if !errors.As(err, &driverErr) {
+		return
+	}
+
+	switch driverErr.Number {
+	case 1062:
+		translated = &Error{duplicateOf(driverErr.Message), err}
+	case 1216, 1452:
+		translated = &Error{ErrNotFound, err}
+	case 1048, 1054, 1264, 1364, 1366, 1406:
+		translated = &Error{ErrInvalid, err}
+	}
+
+	return
+}
+
+/**
+ * Check whether a MariaDB error is of a prepared statement that the server has forgotten,
+ * or that must be prepared again as some table that it reads has changed
+ */
+func mariadbStale(err error) (stale bool) {
+	var driverErr *mysql.MySQLError
+	if errors.As(err, &driverErr) {
+		stale = driverErr.Number == 1243 || driverErr.Number == 1615
+	}
+
+	return
+}
+
 func listStringReverse(source []string) (reversed []string) {
 	var size int = len(source)
 	reversed = make([]string, size)
@@ -129,39 +268,33 @@ func listStringReverse(source []string) (reversed []string) {
  * Ping the database, and return any error
  * useful for health checks
  */
-func Health() (err error) {
-	err = database_handle.Ping()
+func (store *SQLStore) HealthContext(ctx context.Context) (err error) {
+	err = store.handle.PingContext(ctx)
+	return
+}
+
+func (store *SQLStore) Health() (err error) {
+	err = store.HealthContext(context.Background())
 	return
 }
 
 /**
- * Connect to a database, given a connection string
- * If the connection fails a ping, this function wil panic with the err
- * The conenction string should look something like
- * user:pass@tcp(addr)/table
+ * Migrate the schema to the latest version
+ * If any migration fails, this function will panic with the err
  */
-func Connect(address string) {
+func (store *SQLStore) Create() {
 	var err error
-	if database_handle, err = sqlx.Open("mysql", address); err != nil {
-		panic(err)
-	}
-
-	if err = Health(); err != nil {
+	if err = store.Migrate(SchemaLatest()); err != nil {
 		panic(err)
 	}
 }
 
-func Create() {
-	var err error
-	var table string
-	for _, table = range tableOrdered {
-		if _, err = database_handle.Query(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, tables[table])); err != nil {
-			panic(err)
-		}
-	}
+func (store *SQLStore) EmptyTableContext(ctx context.Context, table string) (err error) {
+	_, err = store.db().ExecContext(ctx, "DELETE FROM "+table)
+	return
 }
 
-func EmptyTable(table string) (err error) {
-	_, err = database_handle.Exec("DELETE FROM " + table)
+func (store *SQLStore) EmptyTable(table string) (err error) {
+	err = store.EmptyTableContext(context.Background(), table)
 	return
 }
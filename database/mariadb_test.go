@@ -2,7 +2,6 @@ package database
 
 import (
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 
 	"os"
 	"testing"
@@ -30,27 +29,27 @@ func mapMod(source map[string]interface{}, mods ...map[string]interface{}) (modi
 
 func TestMain(main *testing.M) {
 	Connect(CONNECTION)
-	if database_handle == nil {
-		panic("database_handle nil after being set!")
+	if connected == nil {
+		panic("connected nil after being set!")
 	}
 
-	database_handle.Exec("SET FOREIGN_KEY_CHECKS=OFF")
+	connected.handle.Exec("SET FOREIGN_KEY_CHECKS=OFF")
 
 	var err error
 	var table string
-	for _, table = range listStringReverse(tableOrdered) {
-		if _, err = database_handle.Query("DROP TABLE IF EXISTS " + table); err != nil {
-			database_handle.Exec("SET FOREIGN_KEY_CHECKS=ON")
+	for _, table = range append(listStringReverse(tableLatest), TOKEN_TABLE, MIGRATION_TABLE) {
+		if _, err = connected.handle.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+			connected.handle.Exec("SET FOREIGN_KEY_CHECKS=ON")
 			panic(err)
 		}
 	}
 
-	database_handle.Exec("SET FOREIGN_KEY_CHECKS=ON")
+	connected.handle.Exec("SET FOREIGN_KEY_CHECKS=ON")
 	Create()
 
 	var result int = main.Run()
 
-	for table, _ = range tables {
+	for _, table = range listStringReverse(tableLatest) {
 		if err = EmptyTable(table); err != nil {
 			panic(err)
 		}
@@ -66,8 +65,8 @@ func Test_Connect_malformedAddress(test *testing.T) {
 		}
 	}(test)
 
-	var existing *sqlx.DB = database_handle
-	defer func(existing *sqlx.DB) { database_handle = existing }(existing)
+	var existing *SQLStore = connected
+	defer func(existing *SQLStore) { connected = existing }(existing)
 
 	Connect("foobar")
 }
@@ -79,24 +78,28 @@ func Test_Connect_unreachableAddress(test *testing.T) {
 		}
 	}(test)
 
-	var existing *sqlx.DB = database_handle
-	defer func(existing *sqlx.DB) { database_handle = existing }(existing)
+	var existing *SQLStore = connected
+	defer func(existing *SQLStore) { connected = existing }(existing)
 
 	Connect("foo:bar@tcp(nothing)/table")
 }
 
 func Test_create_badTable(test *testing.T) {
-	var backup map[string]string = tables
+	var backup []migration = migrations
 
-	defer func(test *testing.T, backup map[string]string) {
-		tables = backup
+	defer func(test *testing.T, backup []migration) {
+		migrations = backup
 
 		if recover() == nil {
 			test.Errorf("recover recovered nil!")
 		}
 	}(test, backup)
 
-	tables = map[string]string{"foobar": "id CHAR CHAR CHAR CHAR CHAR GAS GAS GAS DEJA VU"}
+	migrations = append(migrations, migration{
+		up:   migrationSteps("CREATE TABLE foobar id CHAR CHAR CHAR CHAR CHAR GAS GAS GAS DEJA VU"),
+		down: migrationSteps("DROP TABLE foobar"),
+	})
+
 	Create()
 }
 
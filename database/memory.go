@@ -0,0 +1,652 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryOrdered struct {
+	key   string
+	order int64
+}
+
+type memoryContent struct {
+	content types.Content
+	order   int64
+}
+
+type memoryTag struct {
+	tag     string
+	created int64
+}
+
+/**
+ * A Store that keeps everything in memory, for tests and local development
+ * It behaves in the same way as SQLStore, with the same ordering and pagination,
+ * but nothing is persisted, and it only lives as long as the process
+ * A MemoryStore is safe to use from many goroutines
+ */
+type MemoryStore struct {
+	*memoryState
+
+	// Whether this is the Store given to work inside of WithTx, which already holds txLock
+	inTx bool
+}
+
+/**
+ * Everything in a MemoryStore, which is shared with the Store given to work inside of its WithTx
+ * lock is held to read or write, and txLock is held by a transaction for as long as it runs,
+ * and by anything that writes outside of one for as long as it writes
+ */
+type memoryState struct {
+	lock        sync.RWMutex
+	txLock      sync.Mutex
+	order       int64
+	views       *viewBuffer
+	lifetimes   tokenLifetimes
+	revocations *revocationHooks
+
+	content       map[string]*memoryContent
+	tags          map[string][]memoryTag
+	users         map[string]*memoryUser
+	hashes        map[string][]byte
+	sessions      map[string]*memorySession
+	refreshes     map[string]*memoryRefresh
+	secrets       map[string][]byte
+	apikeys       map[string]*memoryAPIKey
+	bans          map[string]*memoryBan
+	reports       map[string]*memoryReport
+	subscriptions map[[2]string]*memorySubscription
+	votes         map[[2]string]int
+	comments      map[string]*memoryComment
+	repubs        map[string]*memoryRepub
+}
+
+var (
+	_ Store = (*MemoryStore)(nil)
+)
+
+/**
+ * Make an empty MemoryStore
+ */
+func NewMemoryStore() (store *MemoryStore) {
+	store = &MemoryStore{memoryState: &memoryState{
+		views:         newViewBuffer(),
+		lifetimes:     defaultLifetimes(),
+		revocations:   &revocationHooks{},
+		content:       map[string]*memoryContent{},
+		tags:          map[string][]memoryTag{},
+		users:         map[string]*memoryUser{},
+		hashes:        map[string][]byte{},
+		sessions:      map[string]*memorySession{},
+		refreshes:     map[string]*memoryRefresh{},
+		secrets:       map[string][]byte{},
+		apikeys:       map[string]*memoryAPIKey{},
+		bans:          map[string]*memoryBan{},
+		reports:       map[string]*memoryReport{},
+		subscriptions: map[[2]string]*memorySubscription{},
+		votes:         map[[2]string]int{},
+		comments:      map[string]*memoryComment{},
+		repubs:        map[string]*memoryRepub{},
+	}}
+
+	return
+}
+
+/**
+ * Take the lock to write, once any transaction that this isn't a part of is done
+ */
+func (store *MemoryStore) writeLock() {
+	if !store.inTx {
+		store.txLock.Lock()
+	}
+
+	store.lock.Lock()
+}
+
+func (store *MemoryStore) writeUnlock() {
+	store.lock.Unlock()
+
+	if !store.inTx {
+		store.txLock.Unlock()
+	}
+}
+
+/**
+ * Get the next insertion order, in the same way as an AUTO_INCREMENT order_index
+ * Must be called with the lock held
+ */
+func (store *MemoryStore) nextOrder() (order int64) {
+	store.order++
+	order = store.order
+	return
+}
+
+/**
+ * Sort `items` newest first, and take up to `count` of them that come before the order `before`
+ * If `before` is negative, items are taken from the start
+ */
+func pageOrdered(items []memoryOrdered, before int64, count int) (keys []string) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].order > items[j].order
+	})
+
+	keys = make([]string, 0)
+
+	var item memoryOrdered
+	for _, item = range items {
+		if len(keys) >= count {
+			break
+		}
+
+		if before < 0 || item.order < before {
+			keys = append(keys, item.key)
+		}
+	}
+
+	return
+}
+
+/**
+ * A MemoryStore is always healthy
+ */
+func (store *MemoryStore) Health() (err error) {
+	return
+}
+
+/**
+ * Forget everything in the store
+ */
+func (store *MemoryStore) Empty() {
+	var empty *MemoryStore = NewMemoryStore()
+
+	store.writeLock()
+	store.content, store.tags = empty.content, empty.tags
+	store.users, store.hashes, store.sessions, store.refreshes, store.secrets = empty.users, empty.hashes, empty.sessions, empty.refreshes, empty.secrets
+	store.apikeys = empty.apikeys
+	store.bans, store.reports = empty.bans, empty.reports
+	store.subscriptions, store.votes, store.comments, store.repubs = empty.subscriptions, empty.votes, empty.comments, empty.repubs
+	store.writeUnlock()
+}
+
+/**
+ * Read the tags of content of id `ID`, which is never nil
+ * Must be called with the lock held
+ */
+func (store *MemoryStore) tagsOf(ID string) (tags []string) {
+	tags = make([]string, len(store.tags[ID]))
+
+	var index int
+	var tag memoryTag
+	for index, tag = range store.tags[ID] {
+		tags[index] = tag.tag
+	}
+
+	return
+}
+
+/**
+ * Read a copy of content of id `ID` along with its tags
+ * Must be called with the lock held
+ */
+func (store *MemoryStore) contentOf(ID string) (content types.Content, exists bool) {
+	var row *memoryContent
+	if row, exists = store.content[ID]; !exists {
+		return
+	}
+
+	content = row.content
+	content.Tags = store.tagsOf(ID)
+	return
+}
+
+/**
+ * Get the order of content of id `before`, to paginate by
+ * Returns -1 if `before` is empty, and ok is false if it does not exist
+ * Must be called with the lock held
+ */
+func (store *MemoryStore) contentCursor(before string) (order int64, ok bool) {
+	if before == "" {
+		order, ok = -1, true
+		return
+	}
+
+	var row *memoryContent
+	if row, ok = store.content[before]; ok {
+		order = row.order
+	}
+
+	return
+}
+
+/**
+ * Read a page of content that matches `match`, in the same way as ReadManyContent
+ */
+func (store *MemoryStore) readContentWhere(match func(types.Content) bool, before string, count int) (content []types.Content, size int, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	content = make([]types.Content, 0)
+
+	var cursor int64
+	var ok bool
+	if cursor, ok = store.contentCursor(before); !ok {
+		return
+	}
+
+	var items []memoryOrdered = make([]memoryOrdered, 0)
+	var row *memoryContent
+	for _, row = range store.content {
+		if match(row.content) {
+			items = append(items, memoryOrdered{row.content.ID, row.order})
+		}
+	}
+
+	var ID string
+	var single types.Content
+	for _, ID = range pageOrdered(items, cursor, count) {
+		single, _ = store.contentOf(ID)
+		content = append(content, single)
+	}
+
+	size = len(content)
+	return
+}
+
+/**
+ * Delete content of id `ID`, along with its tags, votes, repubs and comments
+ * Must be called with the lock held
+ */
+func (store *MemoryStore) deleteContent(ID string) {
+	delete(store.content, ID)
+	delete(store.tags, ID)
+
+	var key [2]string
+	for key = range store.votes {
+		if key[1] == ID {
+			delete(store.votes, key)
+		}
+	}
+
+	var repubID string
+	var repub *memoryRepub
+	for repubID, repub = range store.repubs {
+		if repub.repub.Content == ID {
+			delete(store.repubs, repubID)
+		}
+	}
+
+	var commentID string
+	var comment *memoryComment
+	for commentID, comment = range store.comments {
+		if comment.comment.Content == ID {
+			delete(store.comments, commentID)
+		}
+	}
+}
+
+/**
+ * Count the likes and dislikes of every vote on content of id `ID`
+ */
+func (store *MemoryStore) countVotesOf(ID string) (likes, dislikes int) {
+	var key [2]string
+	var value int
+	for key, value = range store.votes {
+		if key[1] != ID {
+			continue
+		}
+
+		switch value {
+		case VOTE_LIKE:
+			likes++
+		case VOTE_DISLIKE:
+			dislikes++
+		}
+	}
+
+	return
+}
+
+/**
+ * Write some content `content`, replacing any content of the same id
+ * Like REPLACE INTO, the replaced content is ordered as new, though it keeps its votes and is counted by them
+ */
+func (store *MemoryStore) WriteContent(content map[string]interface{}) (err error) {
+	var given []string
+	given, _ = content["tags"].([]string)
+
+	var tags []string = uniqueStrings(given)
+	var copied map[string]interface{} = mapCopy(content)
+	delete(copied, "tags")
+
+	if err = checkColumns(CONTENT_TABLE, copied); err != nil {
+		return
+	}
+
+	var written types.Content
+	if err = written.FromMap(copied); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	delete(store.content, written.ID)
+	delete(store.tags, written.ID)
+	written.LikeCount, written.DislikeCount = store.countVotesOf(written.ID)
+	store.content[written.ID] = &memoryContent{written, store.nextOrder()}
+
+	var now int64 = time.Now().Unix()
+	var tag string
+	for _, tag = range tags {
+		store.tags[written.ID] = append(store.tags[written.ID], memoryTag{tag, now})
+	}
+
+	return
+}
+
+/**
+ * Set the tags of content of id `ID` to `tags`
+ * Tags that it already had keep when they were first given, as in SQLStore.setTags
+ */
+func (store *MemoryStore) setTags(ID string, tags []string) {
+	var given map[string]int64 = make(map[string]int64, len(store.tags[ID]))
+	var kept memoryTag
+	for _, kept = range store.tags[ID] {
+		given[kept.tag] = kept.created
+	}
+
+	delete(store.tags, ID)
+
+	var now int64 = time.Now().Unix()
+	var tag string
+	for _, tag = range uniqueStrings(tags) {
+		var created int64
+		var ok bool
+		if created, ok = given[tag]; !ok {
+			created = now
+		}
+
+		store.tags[ID] = append(store.tags[ID], memoryTag{tag, created})
+	}
+}
+
+/**
+ * Create some new content `content`, along with its tags
+ * Works in the same way as SQLStore.CreateContent
+ */
+func (store *MemoryStore) CreateContent(content types.Content) (err error) {
+	if err = validateContent(content); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var exists bool
+	if _, exists = store.content[content.ID]; exists {
+		err = errorOf(ErrDuplicate, "Content %s already exists", content.ID)
+		return
+	}
+
+	var written types.Content = content
+	written.Tags = nil
+	written.LikeCount, written.DislikeCount, written.RepubCount, written.ViewCount, written.CommentCount = 0, 0, 0, 0, 0
+
+	store.content[written.ID] = &memoryContent{written, store.nextOrder()}
+	store.setTags(written.ID, content.Tags)
+	return
+}
+
+/**
+ * Update only the fields in `patch` of some content of id `ID`, replacing its tags if tags is given
+ * Works in the same way as SQLStore.UpdateContent
+ */
+func (store *MemoryStore) UpdateContent(ID string, patch map[string]interface{}) (err error) {
+	if err = checkPatch(CONTENT_TABLE, patch, "tags"); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var content types.Content
+	var exists bool
+	if content, exists = store.contentOf(ID); !exists {
+		err = errorOf(ErrNotFound, "Content %s does not exist", ID)
+		return
+	}
+
+	if content, err = patchContent(content, patch); err != nil {
+		return
+	}
+
+	var row *memoryContent = store.content[ID]
+	row.content.FileURL, row.content.Mime = content.FileURL, content.Mime
+	row.content.Featured, row.content.Featurable = content.Featured, content.Featurable
+	row.content.Removed, row.content.NSFW = content.Removed, content.NSFW
+
+	var given bool
+	if _, given = patch["tags"]; given {
+		store.setTags(ID, content.Tags)
+	}
+
+	return
+}
+
+/**
+ * Delete some content of id `ID`, along with its tags, votes, repubs and comments
+ */
+func (store *MemoryStore) DeleteContent(ID string) (err error) {
+	store.writeLock()
+	store.deleteContent(ID)
+	store.writeUnlock()
+	return
+}
+
+/**
+ * Read some content of id `ID`
+ */
+func (store *MemoryStore) ReadSingleContent(ID string) (content types.Content, exists bool, err error) {
+	store.lock.RLock()
+	content, exists = store.contentOf(ID)
+	store.lock.RUnlock()
+	return
+}
+
+/**
+ * Read `count` number of contents, before content of id `before`
+ * Works in the same way as SQLStore.ReadManyContent
+ */
+func (store *MemoryStore) ReadManyContent(before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = store.readContentWhere(func(types.Content) bool { return true }, before, count)
+	return
+}
+
+/**
+ * Same as ReadManyContent but for some author of id `ID`
+ */
+func (store *MemoryStore) ReadAuthorContent(ID, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = store.readContentWhere(func(it types.Content) bool { return it.Author == ID }, before, count)
+	return
+}
+
+/**
+ * Read `count` number of contents authored by anyone that some user of id `ID` is subscribed to
+ * Works in the same way as SQLStore.ReadSubscriptionFeed
+ */
+func (store *MemoryStore) ReadSubscriptionFeed(ID, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = store.readContentWhere(func(it types.Content) bool {
+		var subscribed bool
+		_, subscribed = store.subscriptions[[2]string{ID, it.Author}]
+		return subscribed && !it.Removed
+	}, before, count)
+	return
+}
+
+/**
+ * Read `count` number of contents that match some tag query `query`, before content of id `before`
+ * Works in the same way as SQLStore.ReadContentByTags
+ */
+func (store *MemoryStore) ReadContentByTags(query TagQuery, before string, count int) (content []types.Content, size int, err error) {
+	var all []string = uniqueStrings(query.All)
+	var match func(types.Content) bool = func(it types.Content) (ok bool) {
+		if it.Removed {
+			return
+		}
+
+		var has map[string]bool = map[string]bool{}
+		var tag memoryTag
+		for _, tag = range store.tags[it.ID] {
+			has[tag.tag] = true
+		}
+
+		var name string
+		for _, name = range all {
+			if !has[name] {
+				return
+			}
+		}
+
+		for _, name = range query.None {
+			if has[name] {
+				return
+			}
+		}
+
+		ok = len(query.Any) == 0
+		for _, name = range query.Any {
+			ok = ok || has[name]
+		}
+
+		return
+	}
+
+	content, size, err = store.readContentWhere(match, before, count)
+	return
+}
+
+/**
+ * Read `count` number of contents tagged with `tag`, before content of id `before`
+ */
+func (store *MemoryStore) ReadContentByTag(tag, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = store.ReadContentByTags(TagQuery{All: []string{tag}}, before, count)
+	return
+}
+
+/**
+ * Read the `count` most used tags on content tagged since `since`
+ * Works in the same way as SQLStore.ReadPopularTags
+ */
+func (store *MemoryStore) ReadPopularTags(since int64, count int) (tags []types.TagCount, size int, err error) {
+	store.lock.RLock()
+
+	var counts map[string]int = map[string]int{}
+	var ID string
+	var row *memoryContent
+	var tag memoryTag
+	for ID, row = range store.content {
+		if row.content.Removed {
+			continue
+		}
+
+		for _, tag = range store.tags[ID] {
+			if tag.created >= since {
+				counts[tag.tag]++
+			}
+		}
+	}
+
+	store.lock.RUnlock()
+
+	tags = make([]types.TagCount, 0, len(counts))
+	var name string
+	var used int
+	for name, used = range counts {
+		tags = append(tags, types.TagCount{Tag: name, Count: used})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	if len(tags) > count {
+		tags = tags[:count]
+	}
+
+	size = len(tags)
+	return
+}
+
+/**
+ * Set how long a viewer is remembered for
+ */
+func (store *MemoryStore) SetViewWindow(window time.Duration) {
+	store.views.setWindow(window)
+}
+
+/**
+ * Set how many views may be buffered before they're flushed by RecordView
+ */
+func (store *MemoryStore) SetViewFlushSize(size int) {
+	store.views.setSize(size)
+}
+
+/**
+ * Record a view of content of id `ID` by some viewer `viewer`
+ * Works in the same way as SQLStore.RecordView, flushing in the background if the buffer is full
+ */
+func (store *MemoryStore) RecordView(ID, viewer string) (counted bool, err error) {
+	var full bool
+	if counted, full = store.views.record(ID, viewer); full {
+		var flushing *MemoryStore = &MemoryStore{memoryState: store.memoryState}
+		store.views.flushBackground(func() (err error) {
+			flushing.flushViews()
+			return
+		})
+	}
+
+	return
+}
+
+/**
+ * Count every buffered view towards the view_count of its content, once any flush in the background is done
+ */
+func (store *MemoryStore) FlushViews() (err error) {
+	store.views.wait()
+	store.flushViews()
+	return
+}
+
+func (store *MemoryStore) flushViews() {
+	var pending map[string]int64
+	var IDs []string
+	pending, IDs = store.views.take()
+
+	store.writeLock()
+	var ID string
+	var row *memoryContent
+	var ok bool
+	for _, ID = range IDs {
+		if row, ok = store.content[ID]; ok {
+			row.content.ViewCount += int(pending[ID])
+		}
+	}
+
+	store.writeUnlock()
+}
+
+/**
+ * Flush buffered views every `interval` in the background
+ */
+func (store *MemoryStore) FlushViewsEvery(interval time.Duration, failed func(error)) (stop func()) {
+	stop = flushEvery(store.FlushViews, interval, failed)
+	return
+}
@@ -0,0 +1,722 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+
+	"context"
+)
+
+/**
+ * The MemoryStore never blocks on anything but its own lock, so the context variants of its
+ * methods only check that `ctx` is still live before doing the same as their plain variants
+ */
+func (store *MemoryStore) WriteContentContext(ctx context.Context, content map[string]interface{}) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.WriteContent(content)
+	return
+}
+
+func (store *MemoryStore) CreateContentContext(ctx context.Context, content types.Content) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.CreateContent(content)
+	return
+}
+
+func (store *MemoryStore) UpdateContentContext(ctx context.Context, ID string, patch map[string]interface{}) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.UpdateContent(ID, patch)
+	return
+}
+
+func (store *MemoryStore) DeleteContentContext(ctx context.Context, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.DeleteContent(ID)
+	return
+}
+
+func (store *MemoryStore) ReadSingleContentContext(ctx context.Context, ID string) (content types.Content, exists bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	content, exists, err = store.ReadSingleContent(ID)
+	return
+}
+
+func (store *MemoryStore) ReadManyContentContext(ctx context.Context, before string, count int) (content []types.Content, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	content, size, err = store.ReadManyContent(before, count)
+	return
+}
+
+func (store *MemoryStore) ReadAuthorContentContext(ctx context.Context, ID, before string, count int) (content []types.Content, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	content, size, err = store.ReadAuthorContent(ID, before, count)
+	return
+}
+
+func (store *MemoryStore) ReadSubscriptionFeedContext(ctx context.Context, ID, before string, count int) (content []types.Content, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	content, size, err = store.ReadSubscriptionFeed(ID, before, count)
+	return
+}
+
+func (store *MemoryStore) ReadContentByTagsContext(ctx context.Context, query TagQuery, before string, count int) (content []types.Content, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	content, size, err = store.ReadContentByTags(query, before, count)
+	return
+}
+
+func (store *MemoryStore) ReadContentByTagContext(ctx context.Context, tag, before string, count int) (content []types.Content, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	content, size, err = store.ReadContentByTag(tag, before, count)
+	return
+}
+
+func (store *MemoryStore) ReadPopularTagsContext(ctx context.Context, since int64, count int) (tags []types.TagCount, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	tags, size, err = store.ReadPopularTags(since, count)
+	return
+}
+
+func (store *MemoryStore) RecordViewContext(ctx context.Context, ID, viewer string) (counted bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	counted, err = store.RecordView(ID, viewer)
+	return
+}
+
+func (store *MemoryStore) FlushViewsContext(ctx context.Context) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.FlushViews()
+	return
+}
+
+func (store *MemoryStore) WriteUserContext(ctx context.Context, user map[string]interface{}) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.WriteUser(user)
+	return
+}
+
+func (store *MemoryStore) CreateUserContext(ctx context.Context, user types.User) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.CreateUser(user)
+	return
+}
+
+func (store *MemoryStore) UpdateUserContext(ctx context.Context, ID string, patch map[string]interface{}) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.UpdateUser(ID, patch)
+	return
+}
+
+func (store *MemoryStore) DeleteUserContext(ctx context.Context, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.DeleteUser(ID)
+	return
+}
+
+func (store *MemoryStore) ReadSingleUserContext(ctx context.Context, ID string) (user types.User, exists bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	user, exists, err = store.ReadSingleUser(ID)
+	return
+}
+
+func (store *MemoryStore) ReadSingleUserEmailContext(ctx context.Context, email string) (user types.User, exists bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	user, exists, err = store.ReadSingleUserEmail(email)
+	return
+}
+
+func (store *MemoryStore) ReadSingleUserNickContext(ctx context.Context, nick string) (user types.User, exists bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	user, exists, err = store.ReadSingleUserNick(nick)
+	return
+}
+
+func (store *MemoryStore) IncrementPostCountContext(ctx context.Context, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.IncrementPostCount(ID)
+	return
+}
+
+func (store *MemoryStore) IsModeratorContext(ctx context.Context, ID string) (moderator bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	moderator, err = store.IsModerator(ID)
+	return
+}
+
+func (store *MemoryStore) IsAdminContext(ctx context.Context, ID string) (admin bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	admin, err = store.IsAdmin(ID)
+	return
+}
+
+func (store *MemoryStore) SetModeratorContext(ctx context.Context, ID string, state bool) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.SetModerator(ID, state)
+	return
+}
+
+func (store *MemoryStore) SetAdminContext(ctx context.Context, ID string, state bool) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.SetAdmin(ID, state)
+	return
+}
+
+func (store *MemoryStore) CreateSecretContext(ctx context.Context, ID string) (secret string, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	secret, err = store.CreateSecret(ID)
+	return
+}
+
+func (store *MemoryStore) CheckSecretContext(ctx context.Context, ID, secret string) (valid bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	valid, err = store.CheckSecret(ID, secret)
+	return
+}
+
+func (store *MemoryStore) RevokeSecretOfContext(ctx context.Context, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.RevokeSecretOf(ID)
+	return
+}
+
+func (store *MemoryStore) CreateSessionContext(ctx context.Context, ID, device, IP string) (token string, session types.Session, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	token, session, err = store.CreateSession(ID, device, IP)
+	return
+}
+
+func (store *MemoryStore) ReadSessionOfTokenContext(ctx context.Context, token string) (session types.Session, valid bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	session, valid, err = store.ReadSessionOfToken(token)
+	return
+}
+
+func (store *MemoryStore) ReadSessionContext(ctx context.Context, ID string) (session types.Session, exists bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	session, exists, err = store.ReadSession(ID)
+	return
+}
+
+func (store *MemoryStore) ListSessionsContext(ctx context.Context, ID string) (sessions []types.Session, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	sessions, err = store.ListSessions(ID)
+	return
+}
+
+func (store *MemoryStore) RevokeSessionContext(ctx context.Context, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.RevokeSession(ID)
+	return
+}
+
+func (store *MemoryStore) RevokeOtherSessionsContext(ctx context.Context, ID, keep string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.RevokeOtherSessions(ID, keep)
+	return
+}
+
+func (store *MemoryStore) CreateTokenPairContext(ctx context.Context, ID, device, IP string) (pair TokenPair, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	pair, err = store.CreateTokenPair(ID, device, IP)
+	return
+}
+
+func (store *MemoryStore) RefreshTokenPairContext(ctx context.Context, refresh string) (pair TokenPair, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	pair, err = store.RefreshTokenPair(refresh)
+	return
+}
+
+func (store *MemoryStore) CreateTokenContext(ctx context.Context, ID string) (token string, expires int64, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	token, expires, err = store.CreateToken(ID)
+	return
+}
+
+func (store *MemoryStore) ReadTokenStatContext(ctx context.Context, token string) (owner string, valid bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	owner, valid, err = store.ReadTokenStat(token)
+	return
+}
+
+func (store *MemoryStore) RevokeTokenContext(ctx context.Context, token string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.RevokeToken(token)
+	return
+}
+
+func (store *MemoryStore) RevokeTokenOfContext(ctx context.Context, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.RevokeTokenOf(ID)
+	return
+}
+
+func (store *MemoryStore) CreateAPIKeyContext(ctx context.Context, ID, name string, scopes []string, lifetime int64) (token string, key types.APIKey, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	token, key, err = store.CreateAPIKey(ID, name, scopes, lifetime)
+	return
+}
+
+func (store *MemoryStore) ReadAPIKeyOfTokenContext(ctx context.Context, token string) (key types.APIKey, valid bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	key, valid, err = store.ReadAPIKeyOfToken(token)
+	return
+}
+
+func (store *MemoryStore) ListAPIKeysContext(ctx context.Context, ID string) (keys []types.APIKey, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	keys, err = store.ListAPIKeys(ID)
+	return
+}
+
+func (store *MemoryStore) RevokeAPIKeyContext(ctx context.Context, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.RevokeAPIKey(ID)
+	return
+}
+
+func (store *MemoryStore) RevokeAPIKeysOfContext(ctx context.Context, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.RevokeAPIKeysOf(ID)
+	return
+}
+
+func (store *MemoryStore) CheckPasswordContext(ctx context.Context, ID, password string) (valid bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	valid, err = store.CheckPassword(ID, password)
+	return
+}
+
+func (store *MemoryStore) SetPasswordContext(ctx context.Context, ID, password string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.SetPassword(ID, password)
+	return
+}
+
+func (store *MemoryStore) WriteBanContext(ctx context.Context, ban map[string]interface{}) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.WriteBan(ban)
+	return
+}
+
+func (store *MemoryStore) CreateBanContext(ctx context.Context, ban types.Ban) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.CreateBan(ban)
+	return
+}
+
+func (store *MemoryStore) UpdateBanContext(ctx context.Context, ban types.Ban) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.UpdateBan(ban)
+	return
+}
+
+func (store *MemoryStore) ReadSingleBanContext(ctx context.Context, ID string) (ban types.Ban, exists bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	ban, exists, err = store.ReadSingleBan(ID)
+	return
+}
+
+func (store *MemoryStore) ReadBansOfUserContext(ctx context.Context, ID, before string, count int) (bans []types.Ban, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	bans, size, err = store.ReadBansOfUser(ID, before, count)
+	return
+}
+
+func (store *MemoryStore) IsBannedContext(ctx context.Context, ID string) (banned bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	banned, err = store.IsBanned(ID)
+	return
+}
+
+func (store *MemoryStore) WriteReportContext(ctx context.Context, report map[string]interface{}) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.WriteReport(report)
+	return
+}
+
+func (store *MemoryStore) CreateReportContext(ctx context.Context, report types.Report) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.CreateReport(report)
+	return
+}
+
+func (store *MemoryStore) UpdateReportContext(ctx context.Context, report types.Report) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.UpdateReport(report)
+	return
+}
+
+func (store *MemoryStore) ReadManyUnresolvedReportContext(ctx context.Context, before string, count int) (reports []types.Report, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	reports, size, err = store.ReadManyUnresolvedReport(before, count)
+	return
+}
+
+func (store *MemoryStore) ReadSingleReportContext(ctx context.Context, ID string) (report types.Report, exists bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	report, exists, err = store.ReadSingleReport(ID)
+	return
+}
+
+func (store *MemoryStore) SubscribeContext(ctx context.Context, subscriber, subscription string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.Subscribe(subscriber, subscription)
+	return
+}
+
+func (store *MemoryStore) UnsubscribeContext(ctx context.Context, subscriber, subscription string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.Unsubscribe(subscriber, subscription)
+	return
+}
+
+func (store *MemoryStore) IsSubscribedContext(ctx context.Context, subscriber, subscription string) (subscribed bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	subscribed, err = store.IsSubscribed(subscriber, subscription)
+	return
+}
+
+func (store *MemoryStore) ReadSubscribersContext(ctx context.Context, ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	subscriptions, size, err = store.ReadSubscribers(ID, before, count)
+	return
+}
+
+func (store *MemoryStore) ReadSubscriptionsContext(ctx context.Context, ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	subscriptions, size, err = store.ReadSubscriptions(ID, before, count)
+	return
+}
+
+func (store *MemoryStore) VoteContext(ctx context.Context, voter, ID string, value int) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.Vote(voter, ID, value)
+	return
+}
+
+func (store *MemoryStore) ClearVoteContext(ctx context.Context, voter, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.ClearVote(voter, ID)
+	return
+}
+
+func (store *MemoryStore) ReadVoteContext(ctx context.Context, voter, ID string) (value int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	value, err = store.ReadVote(voter, ID)
+	return
+}
+
+func (store *MemoryStore) ReadVotesOfContext(ctx context.Context, voter string, IDs []string) (votes map[string]int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	votes, err = store.ReadVotesOf(voter, IDs)
+	return
+}
+
+func (store *MemoryStore) WriteCommentContext(ctx context.Context, comment types.Comment) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.WriteComment(comment)
+	return
+}
+
+func (store *MemoryStore) EditCommentContext(ctx context.Context, ID, body string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.EditComment(ID, body)
+	return
+}
+
+func (store *MemoryStore) DeleteCommentContext(ctx context.Context, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.DeleteComment(ID)
+	return
+}
+
+func (store *MemoryStore) ReadSingleCommentContext(ctx context.Context, ID string) (comment types.Comment, exists bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	comment, exists, err = store.ReadSingleComment(ID)
+	return
+}
+
+func (store *MemoryStore) ReadCommentsContext(ctx context.Context, ID, before string, count int) (comments []types.Comment, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	comments, size, err = store.ReadComments(ID, before, count)
+	return
+}
+
+func (store *MemoryStore) ReadRepliesContext(ctx context.Context, ID, before string, count int) (comments []types.Comment, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	comments, size, err = store.ReadReplies(ID, before, count)
+	return
+}
+
+func (store *MemoryStore) RepubContext(ctx context.Context, author, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.Repub(author, ID)
+	return
+}
+
+func (store *MemoryStore) UnrepubContext(ctx context.Context, author, ID string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.Unrepub(author, ID)
+	return
+}
+
+func (store *MemoryStore) IsRepubbedContext(ctx context.Context, author, ID string) (repubbed bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	repubbed, err = store.IsRepubbed(author, ID)
+	return
+}
+
+func (store *MemoryStore) ReadAuthorEntriesContext(ctx context.Context, ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	entries, size, err = store.ReadAuthorEntries(ID, before, count)
+	return
+}
+
+func (store *MemoryStore) ReadSubscriptionEntriesContext(ctx context.Context, ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	entries, size, err = store.ReadSubscriptionEntries(ID, before, count)
+	return
+}
+
+func (store *MemoryStore) HealthContext(ctx context.Context) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	err = store.Health()
+	return
+}
@@ -0,0 +1,573 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+
+	"time"
+)
+
+type memorySubscription struct {
+	subscription types.Subscription
+	order        int64
+}
+
+type memoryComment struct {
+	comment types.Comment
+	order   int64
+}
+
+type memoryRepub struct {
+	repub types.Repub
+	order int64
+}
+
+/**
+ * Subscribe some user of id `subscriber` to some user of id `subscription`
+ * Works in the same way as SQLStore.Subscribe
+ */
+func (store *MemoryStore) Subscribe(subscriber, subscription string) (err error) {
+	if subscriber == subscription {
+		err = errorOf(ErrInvalid, "User %s can't subscribe to themselves", subscriber)
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var key [2]string = [2]string{subscriber, subscription}
+	var exists bool
+	if _, exists = store.subscriptions[key]; exists {
+		return
+	}
+
+	if _, exists = store.users[subscription]; !exists {
+		err = errorOf(ErrNotFound, "User %s does not exist", subscription)
+		return
+	}
+
+	store.subscriptions[key] = &memorySubscription{
+		types.Subscription{Subscriber: subscriber, Subscription: subscription, Created: time.Now().Unix()},
+		store.nextOrder(),
+	}
+
+	var row *memoryUser
+	if row, exists = store.users[subscriber]; exists {
+		row.user.SubscriptionCount++
+	}
+
+	if row, exists = store.users[subscription]; exists {
+		row.user.SubscriberCount++
+	}
+
+	return
+}
+
+/**
+ * Unsubscribe some user of id `subscriber` from some user of id `subscription`
+ * Unsubscribing when not subscribed is a no-op
+ */
+func (store *MemoryStore) Unsubscribe(subscriber, subscription string) (err error) {
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var key [2]string = [2]string{subscriber, subscription}
+	var exists bool
+	if _, exists = store.subscriptions[key]; !exists {
+		return
+	}
+
+	delete(store.subscriptions, key)
+
+	var row *memoryUser
+	if row, exists = store.users[subscriber]; exists && row.user.SubscriptionCount > 0 {
+		row.user.SubscriptionCount--
+	}
+
+	if row, exists = store.users[subscription]; exists && row.user.SubscriberCount > 0 {
+		row.user.SubscriberCount--
+	}
+
+	return
+}
+
+/**
+ * Get whether or not some user of id `subscriber` is subscribed to some user of id `subscription`
+ */
+func (store *MemoryStore) IsSubscribed(subscriber, subscription string) (subscribed bool, err error) {
+	store.lock.RLock()
+	_, subscribed = store.subscriptions[[2]string{subscriber, subscription}]
+	store.lock.RUnlock()
+	return
+}
+
+/**
+ * Read a page of subscriptions where the user of id `ID` is on the side `side` (0 subscriber, 1 subscription)
+ * `before` is the user on the other side of the last subscription read
+ */
+func (store *MemoryStore) readSubscriptionsWhere(side int, ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	subscriptions = make([]types.Subscription, 0)
+
+	var cursor int64 = -1
+	if before != "" {
+		var key [2]string
+		key[side], key[1-side] = ID, before
+
+		var row *memorySubscription
+		var ok bool
+		if row, ok = store.subscriptions[key]; !ok {
+			return
+		}
+
+		cursor = row.order
+	}
+
+	var items []memoryOrdered = make([]memoryOrdered, 0)
+	var key [2]string
+	var row *memorySubscription
+	for key, row = range store.subscriptions {
+		if key[side] == ID {
+			items = append(items, memoryOrdered{key[1-side], row.order})
+		}
+	}
+
+	var other string
+	for _, other = range pageOrdered(items, cursor, count) {
+		key[side], key[1-side] = ID, other
+		subscriptions = append(subscriptions, store.subscriptions[key].subscription)
+	}
+
+	size = len(subscriptions)
+	return
+}
+
+/**
+ * Read `count` subscribers of some user of id `ID`, newest first
+ * Works in the same way as SQLStore.ReadSubscribers
+ */
+func (store *MemoryStore) ReadSubscribers(ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	subscriptions, size, err = store.readSubscriptionsWhere(1, ID, before, count)
+	return
+}
+
+/**
+ * Read `count` subscriptions of some user of id `ID`, newest first
+ * Works in the same way as SQLStore.ReadSubscriptions
+ */
+func (store *MemoryStore) ReadSubscriptions(ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	subscriptions, size, err = store.readSubscriptionsWhere(0, ID, before, count)
+	return
+}
+
+/**
+ * Move the like_count or dislike_count of content of id `ID` by `delta`, for a vote `value`
+ * Must be called with the lock held
+ */
+func (store *MemoryStore) countVote(ID string, value, delta int) {
+	var row *memoryContent
+	var ok bool
+	if row, ok = store.content[ID]; !ok {
+		return
+	}
+
+	var counter *int = &row.content.LikeCount
+	if value == VOTE_DISLIKE {
+		counter = &row.content.DislikeCount
+	}
+
+	if *counter+delta >= 0 {
+		*counter += delta
+	}
+}
+
+/**
+ * Set the vote of some user of id `voter` on content of id `ID` to `value`
+ * Works in the same way as SQLStore.Vote
+ */
+func (store *MemoryStore) Vote(voter, ID string, value int) (err error) {
+	if value == VOTE_NONE {
+		err = store.ClearVote(voter, ID)
+		return
+	}
+
+	var ok bool
+	if _, ok = voteIncrements[value]; !ok {
+		err = errorOf(ErrInvalid, "Incorrect vote value %d", value)
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	if _, ok = store.content[ID]; !ok {
+		err = errorOf(ErrNotFound, "Content %s does not exist", ID)
+		return
+	}
+
+	var key [2]string = [2]string{voter, ID}
+	var previous int = store.votes[key]
+	if previous == value {
+		return
+	}
+
+	store.votes[key] = value
+	if previous != VOTE_NONE {
+		store.countVote(ID, previous, -1)
+	}
+
+	store.countVote(ID, value, 1)
+	return
+}
+
+/**
+ * Clear the vote of some user of id `voter` on content of id `ID`, if any
+ */
+func (store *MemoryStore) ClearVote(voter, ID string) (err error) {
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var key [2]string = [2]string{voter, ID}
+	var previous int
+	var ok bool
+	if previous, ok = store.votes[key]; !ok {
+		return
+	}
+
+	delete(store.votes, key)
+	store.countVote(ID, previous, -1)
+	return
+}
+
+/**
+ * Read the vote of some user of id `voter` on content of id `ID`
+ * If no vote exists, value is VOTE_NONE
+ */
+func (store *MemoryStore) ReadVote(voter, ID string) (value int, err error) {
+	store.lock.RLock()
+	value = store.votes[[2]string{voter, ID}]
+	store.lock.RUnlock()
+	return
+}
+
+/**
+ * Read the votes of some user of id `voter` on every content of id in `IDs`
+ * Content that was not voted on is VOTE_NONE
+ */
+func (store *MemoryStore) ReadVotesOf(voter string, IDs []string) (votes map[string]int, err error) {
+	votes = make(map[string]int, len(IDs))
+
+	store.lock.RLock()
+	var ID string
+	for _, ID = range IDs {
+		votes[ID] = store.votes[[2]string{voter, ID}]
+	}
+
+	store.lock.RUnlock()
+	return
+}
+
+/**
+ * Write a new comment `comment`, and count it towards the comment_count of its content
+ * Works in the same way as SQLStore.WriteComment
+ */
+func (store *MemoryStore) WriteComment(comment types.Comment) (err error) {
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var content *memoryContent
+	var exists bool
+	if content, exists = store.content[comment.Content]; !exists {
+		err = errorOf(ErrNotFound, "Content %s does not exist", comment.Content)
+		return
+	}
+
+	var row *memoryComment
+	if comment.Parent != "" {
+		if row, exists = store.comments[comment.Parent]; !exists || row.comment.Content != comment.Content {
+			err = errorOf(ErrNotFound, "Parent comment %s does not exist on content %s", comment.Parent, comment.Content)
+			return
+		}
+	}
+
+	if _, exists = store.comments[comment.ID]; exists {
+		err = errorOf(ErrDuplicate, "Comment %s already exists", comment.ID)
+		return
+	}
+
+	store.comments[comment.ID] = &memoryComment{comment, store.nextOrder()}
+	content.content.CommentCount++
+	return
+}
+
+/**
+ * Edit the body of some comment of id `ID`
+ * Works in the same way as SQLStore.EditComment
+ */
+func (store *MemoryStore) EditComment(ID, body string) (err error) {
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var row *memoryComment
+	var exists bool
+	if row, exists = store.comments[ID]; !exists || row.comment.Deleted {
+		err = errorOf(ErrNotFound, "Comment %s does not exist", ID)
+		return
+	}
+
+	row.comment.Body = body
+	row.comment.Edited = time.Now().Unix()
+	return
+}
+
+/**
+ * Soft-delete some comment of id `ID`
+ * Works in the same way as SQLStore.DeleteComment
+ */
+func (store *MemoryStore) DeleteComment(ID string) (err error) {
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var row *memoryComment
+	var exists bool
+	if row, exists = store.comments[ID]; !exists || row.comment.Deleted {
+		return
+	}
+
+	row.comment.Body = ""
+	row.comment.Deleted = true
+
+	var content *memoryContent
+	if content, exists = store.content[row.comment.Content]; exists && content.content.CommentCount > 0 {
+		content.content.CommentCount--
+	}
+
+	return
+}
+
+/**
+ * Read a single comment of id `ID`
+ */
+func (store *MemoryStore) ReadSingleComment(ID string) (comment types.Comment, exists bool, err error) {
+	store.lock.RLock()
+	var row *memoryComment
+	if row, exists = store.comments[ID]; exists {
+		comment = row.comment
+	}
+
+	store.lock.RUnlock()
+	return
+}
+
+/**
+ * Read a page of comments that match `match`, in the same way as ReadComments
+ */
+func (store *MemoryStore) readCommentsWhere(match func(types.Comment) bool, before string, count int) (comments []types.Comment, size int, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	comments = make([]types.Comment, 0)
+
+	var cursor int64 = -1
+	if before != "" {
+		var row *memoryComment
+		var ok bool
+		if row, ok = store.comments[before]; !ok {
+			return
+		}
+
+		cursor = row.order
+	}
+
+	var items []memoryOrdered = make([]memoryOrdered, 0)
+	var row *memoryComment
+	for _, row = range store.comments {
+		if match(row.comment) {
+			items = append(items, memoryOrdered{row.comment.ID, row.order})
+		}
+	}
+
+	var key string
+	for _, key = range pageOrdered(items, cursor, count) {
+		comments = append(comments, store.comments[key].comment)
+	}
+
+	size = len(comments)
+	return
+}
+
+/**
+ * Read `count` top level comments on some content of id `ID`, before comment of id `before`
+ */
+func (store *MemoryStore) ReadComments(ID, before string, count int) (comments []types.Comment, size int, err error) {
+	comments, size, err = store.readCommentsWhere(func(it types.Comment) bool {
+		return it.Content == ID && it.Parent == ""
+	}, before, count)
+	return
+}
+
+/**
+ * Same as ReadComments, but for the replies to some comment of id `ID`
+ */
+func (store *MemoryStore) ReadReplies(ID, before string, count int) (comments []types.Comment, size int, err error) {
+	comments, size, err = store.readCommentsWhere(func(it types.Comment) bool {
+		return it.Parent == ID
+	}, before, count)
+	return
+}
+
+/**
+ * Find the repub of content of id `ID` by some user of id `author`
+ * Must be called with the lock held
+ */
+func (store *MemoryStore) repubOf(author, ID string) (row *memoryRepub, exists bool) {
+	for _, row = range store.repubs {
+		if row.repub.Author == author && row.repub.Content == ID {
+			exists = true
+			return
+		}
+	}
+
+	row = nil
+	return
+}
+
+/**
+ * Repub some content of id `ID` as some user of id `author`
+ * Works in the same way as SQLStore.Repub
+ */
+func (store *MemoryStore) Repub(author, ID string) (err error) {
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var exists bool
+	if _, exists = store.repubOf(author, ID); exists {
+		return
+	}
+
+	var content *memoryContent
+	if content, exists = store.content[ID]; !exists {
+		err = errorOf(ErrNotFound, "Content %s does not exist", ID)
+		return
+	}
+
+	var repub types.Repub = types.NewRepub(author, ID)
+	store.repubs[repub.ID] = &memoryRepub{repub, store.nextOrder()}
+	content.content.RepubCount++
+	return
+}
+
+/**
+ * Undo the repub of some content of id `ID` by some user of id `author`
+ * Unrepubbing content that was not repubbed is a no-op
+ */
+func (store *MemoryStore) Unrepub(author, ID string) (err error) {
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var row *memoryRepub
+	var exists bool
+	if row, exists = store.repubOf(author, ID); !exists {
+		return
+	}
+
+	delete(store.repubs, row.repub.ID)
+
+	var content *memoryContent
+	if content, exists = store.content[ID]; exists && content.content.RepubCount > 0 {
+		content.content.RepubCount--
+	}
+
+	return
+}
+
+/**
+ * Get whether or not some user of id `author` has repubbed content of id `ID`
+ */
+func (store *MemoryStore) IsRepubbed(author, ID string) (repubbed bool, err error) {
+	store.lock.RLock()
+	_, repubbed = store.repubOf(author, ID)
+	store.lock.RUnlock()
+	return
+}
+
+/**
+ * Read `count` feed entries by authors that `authored` matches, before entry of id `before`
+ * Works in the same way as SQLStore.readFeed, as content and repubs share one order here
+ */
+func (store *MemoryStore) readFeedWhere(authored func(string) bool, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	entries = make([]types.FeedEntry, 0)
+
+	var cursor int64 = -1
+	if before != "" {
+		var content *memoryContent
+		var repub *memoryRepub
+		var ok bool
+		if content, ok = store.content[before]; ok {
+			cursor = content.order
+		} else if repub, ok = store.repubs[before]; ok {
+			cursor = repub.order
+		} else {
+			return
+		}
+	}
+
+	var items []memoryOrdered = make([]memoryOrdered, 0)
+	var content *memoryContent
+	for _, content = range store.content {
+		if authored(content.content.Author) && !content.content.Removed {
+			items = append(items, memoryOrdered{content.content.ID, content.order})
+		}
+	}
+
+	var repub *memoryRepub
+	var exists bool
+	for _, repub = range store.repubs {
+		if content, exists = store.content[repub.repub.Content]; exists && !content.content.Removed && authored(repub.repub.Author) {
+			items = append(items, memoryOrdered{repub.repub.ID, repub.order})
+		}
+	}
+
+	var ID string
+	for _, ID = range pageOrdered(items, cursor, count) {
+		var single types.Content
+		if repub, exists = store.repubs[ID]; !exists {
+			single, _ = store.contentOf(ID)
+			entries = append(entries, types.FeedEntry{ID: ID, Content: single})
+			continue
+		}
+
+		var copied types.Repub = repub.repub
+		single, _ = store.contentOf(copied.Content)
+		entries = append(entries, types.FeedEntry{ID: ID, Content: single, Repub: &copied})
+	}
+
+	size = len(entries)
+	return
+}
+
+/**
+ * Read `count` feed entries of some user of id `ID`, before entry of id `before`
+ * Works in the same way as SQLStore.ReadAuthorEntries
+ */
+func (store *MemoryStore) ReadAuthorEntries(ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	entries, size, err = store.readFeedWhere(func(author string) bool { return author == ID }, before, count)
+	return
+}
+
+/**
+ * Read `count` feed entries from anyone that some user of id `ID` is subscribed to, before entry of id `before`
+ * Works in the same way as SQLStore.ReadSubscriptionEntries
+ */
+func (store *MemoryStore) ReadSubscriptionEntries(ID, before string, count int) (entries []types.FeedEntry, size int, err error) {
+	entries, size, err = store.readFeedWhere(func(author string) bool {
+		var subscribed bool
+		_, subscribed = store.subscriptions[[2]string{ID, author}]
+		return subscribed
+	}, before, count)
+	return
+}
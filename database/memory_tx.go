@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+)
+
+/**
+ * Run `work` as a single unit, in the same way as SQLStore.WithTx
+ * Transactions are run one at a time, and writes outside of them wait for them to be done,
+ * so that everything can be put back the way it was before `work` if it returns an error or panics
+ * `work` must write with the Store that it's given, as writing with this one would wait on itself
+ * Like an AUTO_INCREMENT, the insertion order is never put back
+ * WithTx inside of `work` joins the transaction that it's in
+ */
+func (store *MemoryStore) WithTx(ctx context.Context, work func(Store) error) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	if store.inTx {
+		err = work(store)
+		return
+	}
+
+	store.txLock.Lock()
+	defer store.txLock.Unlock()
+
+	var saved *MemoryStore = store.snapshot()
+
+	defer func() {
+		var recovered interface{}
+		if recovered = recover(); recovered != nil {
+			store.restore(saved)
+			panic(recovered)
+		}
+	}()
+
+	if err = work(&MemoryStore{memoryState: store.memoryState, inTx: true}); err != nil {
+		store.restore(saved)
+	}
+
+	return
+}
+
+/**
+ * Copy everything in the store, so that it can be restored later
+ * Rows are copied rather than shared, as some are changed in place
+ */
+func (store *MemoryStore) snapshot() (saved *MemoryStore) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	saved = NewMemoryStore()
+
+	var ID string
+	var key [2]string
+
+	var content *memoryContent
+	for ID, content = range store.content {
+		var copied memoryContent = *content
+		saved.content[ID] = &copied
+	}
+
+	var tags []memoryTag
+	for ID, tags = range store.tags {
+		saved.tags[ID] = append([]memoryTag(nil), tags...)
+	}
+
+	var user *memoryUser
+	for ID, user = range store.users {
+		var copied memoryUser = *user
+		saved.users[ID] = &copied
+	}
+
+	var bytes []byte
+	for ID, bytes = range store.hashes {
+		saved.hashes[ID] = bytes
+	}
+
+	for ID, bytes = range store.secrets {
+		saved.secrets[ID] = bytes
+	}
+
+	var session *memorySession
+	for ID, session = range store.sessions {
+		var copied memorySession = *session
+		saved.sessions[ID] = &copied
+	}
+
+	var token string
+	var refresh *memoryRefresh
+	for token, refresh = range store.refreshes {
+		var copied memoryRefresh = *refresh
+		saved.refreshes[token] = &copied
+	}
+
+	var apikey *memoryAPIKey
+	for ID, apikey = range store.apikeys {
+		saved.apikeys[ID] = &memoryAPIKey{copyAPIKey(apikey.key), apikey.token, apikey.order}
+	}
+
+	var ban *memoryBan
+	for ID, ban = range store.bans {
+		var copied memoryBan = *ban
+		saved.bans[ID] = &copied
+	}
+
+	var report *memoryReport
+	for ID, report = range store.reports {
+		var copied memoryReport = *report
+		saved.reports[ID] = &copied
+	}
+
+	var subscription *memorySubscription
+	for key, subscription = range store.subscriptions {
+		var copied memorySubscription = *subscription
+		saved.subscriptions[key] = &copied
+	}
+
+	var vote int
+	for key, vote = range store.votes {
+		saved.votes[key] = vote
+	}
+
+	var comment *memoryComment
+	for ID, comment = range store.comments {
+		var copied memoryComment = *comment
+		saved.comments[ID] = &copied
+	}
+
+	var repub *memoryRepub
+	for ID, repub = range store.repubs {
+		var copied memoryRepub = *repub
+		saved.repubs[ID] = &copied
+	}
+
+	return
+}
+
+/**
+ * Put back everything from a `saved` snapshot
+ */
+func (store *MemoryStore) restore(saved *MemoryStore) {
+	store.lock.Lock()
+	store.content, store.tags = saved.content, saved.tags
+	store.users, store.hashes, store.sessions, store.refreshes, store.secrets = saved.users, saved.hashes, saved.sessions, saved.refreshes, saved.secrets
+	store.apikeys = saved.apikeys
+	store.bans, store.reports = saved.bans, saved.reports
+	store.subscriptions, store.votes, store.comments, store.repubs = saved.subscriptions, saved.votes, saved.comments, saved.repubs
+	store.lock.Unlock()
+}
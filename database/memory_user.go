@@ -0,0 +1,1036 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"golang.org/x/crypto/bcrypt"
+
+	"crypto/subtle"
+	"time"
+)
+
+type memoryUser struct {
+	user  types.User
+	order int64
+}
+
+type memorySession struct {
+	session types.Session
+	token   string
+	order   int64
+}
+
+type memoryRefresh struct {
+	session string
+	created int64
+	used    bool
+}
+
+type memoryAPIKey struct {
+	key   types.APIKey
+	token string
+	order int64
+}
+
+type memoryBan struct {
+	ban   types.Ban
+	order int64
+}
+
+type memoryReport struct {
+	report types.Report
+	order  int64
+}
+
+/**
+ * Write some user `user`, replacing any user of the same id
+ * Works in the same way as SQLStore.WriteUser
+ */
+func (store *MemoryStore) WriteUser(user map[string]interface{}) (err error) {
+	if err = checkColumns(USER_TABLE, user); err != nil {
+		return
+	}
+
+	var written types.User
+	if err = written.FromMap(user); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	if err = store.checkUserConflicts(written); err != nil {
+		return
+	}
+
+	store.users[written.ID] = &memoryUser{written, store.nextOrder()}
+	return
+}
+
+/**
+ * Check that no user other than `user` has its nick or email
+ */
+func (store *MemoryStore) checkUserConflicts(user types.User) (err error) {
+	var ID string
+	var row *memoryUser
+	for ID, row = range store.users {
+		switch {
+		case ID == user.ID:
+		case row.user.Nick == user.Nick:
+			err = errorOf(ErrDuplicateNick, "Nick %s is taken", user.Nick)
+			return
+		case row.user.Email == user.Email:
+			err = errorOf(ErrDuplicateEmail, "Email %s is taken", user.Email)
+			return
+		}
+	}
+
+	return
+}
+
+/**
+ * Create some new user `user`
+ * Works in the same way as SQLStore.CreateUser
+ */
+func (store *MemoryStore) CreateUser(user types.User) (err error) {
+	if err = validateUser(user); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var exists bool
+	if _, exists = store.users[user.ID]; exists {
+		err = errorOf(ErrDuplicate, "User %s already exists", user.ID)
+		return
+	}
+
+	if err = store.checkUserConflicts(user); err != nil {
+		return
+	}
+
+	var written types.User = user
+	written.SubscriberCount, written.SubscriptionCount, written.PostCount = 0, 0, 0
+	written.Moderator, written.Admin = false, false
+
+	store.users[written.ID] = &memoryUser{written, store.nextOrder()}
+	return
+}
+
+/**
+ * Update only the fields in `patch` of some user of id `ID`
+ * Works in the same way as SQLStore.UpdateUser
+ */
+func (store *MemoryStore) UpdateUser(ID string, patch map[string]interface{}) (err error) {
+	if err = checkPatch(USER_TABLE, patch); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var row *memoryUser
+	var exists bool
+	if row, exists = store.users[ID]; !exists {
+		err = errorOf(ErrNotFound, "User %s does not exist", ID)
+		return
+	}
+
+	var user types.User
+	if user, err = patchUser(row.user, patch); err != nil {
+		return
+	}
+
+	if err = store.checkUserConflicts(user); err != nil {
+		return
+	}
+
+	row.user.Email, row.user.Nick, row.user.Bio = user.Email, user.Nick, user.Bio
+	return
+}
+
+/**
+ * Delete some user of id `ID`
+ */
+func (store *MemoryStore) DeleteUser(ID string) (err error) {
+	store.writeLock()
+	delete(store.users, ID)
+	store.writeUnlock()
+	return
+}
+
+/**
+ * Read the first user that `match` matches
+ */
+func (store *MemoryStore) readSingleUserWhere(match func(types.User) bool) (user types.User, exists bool, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	var row *memoryUser
+	for _, row = range store.users {
+		if match(row.user) {
+			user, exists = row.user, true
+			return
+		}
+	}
+
+	return
+}
+
+/**
+ * Read some user of id `ID`
+ */
+func (store *MemoryStore) ReadSingleUser(ID string) (user types.User, exists bool, err error) {
+	user, exists, err = store.readSingleUserWhere(func(it types.User) bool { return it.ID == ID })
+	return
+}
+
+/**
+ * Read some user of email `email`
+ */
+func (store *MemoryStore) ReadSingleUserEmail(email string) (user types.User, exists bool, err error) {
+	user, exists, err = store.readSingleUserWhere(func(it types.User) bool { return it.Email == email })
+	return
+}
+
+/**
+ * Read some user of nick `nick`
+ */
+func (store *MemoryStore) ReadSingleUserNick(nick string) (user types.User, exists bool, err error) {
+	user, exists, err = store.readSingleUserWhere(func(it types.User) bool { return it.Nick == nick })
+	return
+}
+
+/**
+ * Change the user of id `ID` with `change`, if they exist
+ */
+func (store *MemoryStore) updateUser(ID string, change func(*types.User)) {
+	store.writeLock()
+
+	var row *memoryUser
+	var ok bool
+	if row, ok = store.users[ID]; ok {
+		change(&row.user)
+	}
+
+	store.writeUnlock()
+}
+
+/**
+ * Increment the post count of user of id `ID` by one
+ */
+func (store *MemoryStore) IncrementPostCount(ID string) (err error) {
+	store.updateUser(ID, func(it *types.User) { it.PostCount++ })
+	return
+}
+
+/**
+ * Get whether or not some user of id `ID` is a moderator or an admin
+ */
+func (store *MemoryStore) IsModerator(ID string) (moderator bool, err error) {
+	var user types.User
+	if user, _, err = store.ReadSingleUser(ID); err == nil {
+		moderator = user.Moderator || user.Admin
+	}
+
+	return
+}
+
+/**
+ * Get whether or not some user of id `ID` is an admin
+ */
+func (store *MemoryStore) IsAdmin(ID string) (admin bool, err error) {
+	var user types.User
+	if user, _, err = store.ReadSingleUser(ID); err == nil {
+		admin = user.Admin
+	}
+
+	return
+}
+
+func (store *MemoryStore) SetModerator(ID string, state bool) (err error) {
+	store.updateUser(ID, func(it *types.User) { it.Moderator = state })
+	return
+}
+
+func (store *MemoryStore) SetAdmin(ID string, state bool) (err error) {
+	store.updateUser(ID, func(it *types.User) { it.Admin = state })
+	return
+}
+
+/**
+ * Create a secret for some user of id `ID`, destroying any existing secret
+ */
+func (store *MemoryStore) CreateSecret(ID string) (secret string, err error) {
+	var hash []byte
+	if secret, hash, err = newToken(SECRET_LENGTH); err != nil {
+		return
+	}
+
+	store.writeLock()
+	store.secrets[ID] = hash
+	store.writeUnlock()
+	return
+}
+
+/**
+ * Check that a secret `secret` for some user of id `ID` matches
+ * Works in the same way as SQLStore.CheckSecret
+ */
+func (store *MemoryStore) CheckSecret(ID, secret string) (valid bool, err error) {
+	var given []byte
+	if given, err = hashOfToken(secret); err != nil {
+		err = nil
+		return
+	}
+
+	store.lock.RLock()
+	var hash []byte
+	var exists bool
+	hash, exists = store.secrets[ID]
+	store.lock.RUnlock()
+
+	valid = exists && subtle.ConstantTimeCompare(hash, given) == 1
+	return
+}
+
+/**
+ * Revoke the secret of some user of id `ID`
+ */
+func (store *MemoryStore) RevokeSecretOf(ID string) (err error) {
+	store.writeLock()
+	delete(store.secrets, ID)
+	store.writeUnlock()
+	return
+}
+
+/**
+ * Set how long tokens and refresh tokens made by this store are valid for
+ * Works in the same way as SQLStore.SetTokenLifetimes
+ */
+func (store *MemoryStore) SetTokenLifetimes(access, refresh time.Duration) {
+	store.lifetimes = store.lifetimes.with(access, refresh)
+}
+
+/**
+ * Have `revoked` told the id of every session that this store revokes
+ * Works in the same way as SQLStore.OnSessionRevoked
+ */
+func (store *MemoryStore) OnSessionRevoked(revoked func(session string, until int64)) {
+	store.revocations.add(revoked)
+}
+
+/**
+ * Tell whatever was given to OnSessionRevoked of every session of id in `sessions`
+ * Must be called without the lock held
+ */
+func (store *MemoryStore) sessionsRevoked(sessions []string) {
+	store.revocations.tell(sessions, time.Now().Unix()+store.lifetimes.access)
+}
+
+/**
+ * Create a session for some user of id `ID`, keeping any others that it has
+ * Works in the same way as SQLStore.CreateSession
+ */
+func (store *MemoryStore) CreateSession(ID, device, IP string) (token string, session types.Session, err error) {
+	if session, err = newSession(ID, device, IP); err != nil {
+		return
+	}
+
+	var hash []byte
+	if token, hash, err = newToken(TOKEN_LENGTH); err != nil {
+		return
+	}
+
+	store.writeLock()
+	store.sessions[session.ID] = &memorySession{session, string(hash), store.nextOrder()}
+	store.writeUnlock()
+	return
+}
+
+/**
+ * Create a token for some user of id `ID` that expires in TOKEN_TTL seconds, unless set otherwise
+ * Works in the same way as SQLStore.CreateToken
+ */
+func (store *MemoryStore) CreateToken(ID string) (token string, expires int64, err error) {
+	var session types.Session
+	if token, session, err = store.CreateSession(ID, "", ""); err == nil {
+		expires = session.Issued + store.lifetimes.access
+	}
+
+	return
+}
+
+/**
+ * Read the session of some token `token`, and whether or not it's valid
+ * Works in the same way as SQLStore.ReadSessionOfToken
+ */
+func (store *MemoryStore) ReadSessionOfToken(token string) (session types.Session, valid bool, err error) {
+	var hash []byte
+	if hash, err = hashOfToken(token); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var now int64 = time.Now().Unix()
+	var row *memorySession
+	for _, row = range store.sessions {
+		if row.token != string(hash) {
+			continue
+		}
+
+		if valid = row.session.Issued <= now && row.session.Issued+store.lifetimes.access >= now; valid && now-row.session.LastUsed >= SESSION_TOUCH_INTERVAL {
+			row.session.LastUsed = now
+		}
+
+		session = row.session
+		return
+	}
+
+	return
+}
+
+/**
+ * Read some session of id `ID`, and whether or not it exists
+ */
+func (store *MemoryStore) ReadSession(ID string) (session types.Session, exists bool, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	var row *memorySession
+	if row, exists = store.sessions[ID]; exists {
+		session = row.session
+	}
+
+	return
+}
+
+/**
+ * Read who some token `token` belongs to, and whether or not it's valid
+ * Works in the same way as SQLStore.ReadTokenStat
+ */
+func (store *MemoryStore) ReadTokenStat(token string) (owner string, valid bool, err error) {
+	var session types.Session
+	session, valid, err = store.ReadSessionOfToken(token)
+	owner = session.Owner
+	return
+}
+
+/**
+ * Read every session of some user of id `ID`, newest first
+ */
+func (store *MemoryStore) ListSessions(ID string) (sessions []types.Session, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	var items []memoryOrdered = make([]memoryOrdered, 0)
+	var key string
+	var row *memorySession
+	for key, row = range store.sessions {
+		if row.session.Owner == ID {
+			items = append(items, memoryOrdered{key, row.order})
+		}
+	}
+
+	var keys []string = pageOrdered(items, -1, len(items))
+	sessions = make([]types.Session, len(keys))
+
+	var index int
+	for index, key = range keys {
+		sessions[index] = store.sessions[key].session
+	}
+
+	return
+}
+
+/**
+ * Revoke some session of id `ID`
+ */
+func (store *MemoryStore) RevokeSession(ID string) (err error) {
+	store.writeLock()
+	store.deleteSessionsWhere(func(row *memorySession) bool {
+		return row.session.ID == ID
+	})
+
+	store.writeUnlock()
+	store.sessionsRevoked([]string{ID})
+	return
+}
+
+/**
+ * Revoke every session of some user of id `ID` but the one of id `keep`
+ */
+func (store *MemoryStore) RevokeOtherSessions(ID, keep string) (err error) {
+	store.writeLock()
+	var revoked []string = store.deleteSessionsWhere(func(row *memorySession) bool {
+		return row.session.Owner == ID && row.session.ID != keep
+	})
+
+	store.writeUnlock()
+	store.sessionsRevoked(revoked)
+	return
+}
+
+/**
+ * Revoke the session of some token `token`
+ */
+func (store *MemoryStore) RevokeToken(token string) (err error) {
+	var hash []byte
+	if hash, err = hashOfToken(token); err != nil {
+		return
+	}
+
+	store.writeLock()
+	var revoked []string = store.deleteSessionsWhere(func(row *memorySession) bool {
+		return row.token == string(hash)
+	})
+
+	store.writeUnlock()
+	store.sessionsRevoked(revoked)
+	return
+}
+
+/**
+ * Revoke every session of some user of id `ID`
+ */
+func (store *MemoryStore) RevokeTokenOf(ID string) (err error) {
+	store.writeLock()
+	var revoked []string = store.deleteSessionsWhere(func(row *memorySession) bool {
+		return row.session.Owner == ID
+	})
+
+	store.writeUnlock()
+	store.sessionsRevoked(revoked)
+	return
+}
+
+/**
+ * Delete every session that `match` matches, along with their refresh tokens, and get their ids
+ * Must be called with the lock held
+ */
+func (store *MemoryStore) deleteSessionsWhere(match func(*memorySession) bool) (revoked []string) {
+	var ID string
+	var row *memorySession
+	for ID, row = range store.sessions {
+		if match(row) {
+			delete(store.sessions, ID)
+			revoked = append(revoked, ID)
+		}
+	}
+
+	var token string
+	var refresh *memoryRefresh
+	var exists bool
+	for token, refresh = range store.refreshes {
+		if _, exists = store.sessions[refresh.session]; !exists {
+			delete(store.refreshes, token)
+		}
+	}
+
+	return
+}
+
+/**
+ * Write a new refresh token of some session of id `ID`, issued at `now`
+ * Must be called with the lock held
+ */
+func (store *MemoryStore) writeRefresh(ID string, now int64) (refresh string, err error) {
+	var hash []byte
+	if refresh, hash, err = newToken(TOKEN_LENGTH); err != nil {
+		return
+	}
+
+	store.refreshes[string(hash)] = &memoryRefresh{ID, now, false}
+	return
+}
+
+/**
+ * Create a session for some user of id `ID`, along with a refresh token
+ * Works in the same way as SQLStore.CreateTokenPair
+ */
+func (store *MemoryStore) CreateTokenPair(ID, device, IP string) (pair TokenPair, err error) {
+	if pair.Token, pair.Session, err = store.CreateSession(ID, device, IP); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	if pair.Refresh, err = store.writeRefresh(pair.Session.ID, pair.Session.Issued); err != nil {
+		delete(store.sessions, pair.Session.ID)
+		pair = TokenPair{}
+		return
+	}
+
+	pair.Expires = pair.Session.Issued + store.lifetimes.access
+	pair.RefreshExpires = pair.Session.Issued + store.lifetimes.refresh
+	return
+}
+
+/**
+ * Trade some refresh token `refresh` for a new token and refresh token of the same session
+ * Works in the same way as SQLStore.RefreshTokenPair
+ */
+func (store *MemoryStore) RefreshTokenPair(refresh string) (pair TokenPair, err error) {
+	var hash []byte
+	if hash, err = hashOfToken(refresh); err != nil {
+		return
+	}
+
+	var revoked []string
+	defer func() { store.sessionsRevoked(revoked) }()
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var row *memoryRefresh
+	var ok bool
+	if row, ok = store.refreshes[string(hash)]; !ok {
+		err = errorOf(ErrInvalidToken, "Refresh token does not exist")
+		return
+	}
+
+	if row.used {
+		revoked = store.deleteSessionsWhere(func(session *memorySession) bool {
+			return session.session.ID == row.session
+		})
+
+		err = errorOf(ErrReusedToken, "Refresh token was already used, its session is revoked")
+		return
+	}
+
+	var now int64 = time.Now().Unix()
+	if row.created+store.lifetimes.refresh < now {
+		err = errorOf(ErrInvalidToken, "Refresh token expired at %d", row.created+store.lifetimes.refresh)
+		return
+	}
+
+	var session *memorySession
+	if session, ok = store.sessions[row.session]; !ok {
+		err = errorOf(ErrInvalidToken, "Session %s was revoked", row.session)
+		return
+	}
+
+	var token string
+	var tokenHash []byte
+	if token, tokenHash, err = newToken(TOKEN_LENGTH); err != nil {
+		return
+	}
+
+	if pair.Refresh, err = store.writeRefresh(row.session, now); err != nil {
+		return
+	}
+
+	row.used = true
+	session.token = string(tokenHash)
+	session.session.Issued, session.session.LastUsed = now, now
+
+	var key string
+	var other *memoryRefresh
+	for key, other = range store.refreshes {
+		if other.session == row.session && other.used && other.created < now-store.lifetimes.refresh {
+			delete(store.refreshes, key)
+		}
+	}
+
+	pair.Session = session.session
+	pair.Token = token
+	pair.Expires = now + store.lifetimes.access
+	pair.RefreshExpires = now + store.lifetimes.refresh
+	return
+}
+
+/**
+ * Get a copy of `key` that doesn't share its scopes
+ */
+func copyAPIKey(key types.APIKey) (copied types.APIKey) {
+	copied = key
+	copied.Scopes = append(make([]string, 0, len(key.Scopes)), key.Scopes...)
+	return
+}
+
+/**
+ * Create an API key for some user of id `ID` named `name`, that grants `scopes`
+ * Works in the same way as SQLStore.CreateAPIKey
+ */
+func (store *MemoryStore) CreateAPIKey(ID, name string, scopes []string, lifetime int64) (token string, key types.APIKey, err error) {
+	if key, err = newAPIKey(ID, name, scopes, lifetime); err != nil {
+		return
+	}
+
+	var hash []byte
+	if token, hash, err = newToken(TOKEN_LENGTH); err != nil {
+		return
+	}
+
+	store.writeLock()
+	store.apikeys[key.ID] = &memoryAPIKey{copyAPIKey(key), string(hash), store.nextOrder()}
+	store.writeUnlock()
+	return
+}
+
+/**
+ * Read the API key of some token `token`, and whether or not it's valid
+ * Works in the same way as SQLStore.ReadAPIKeyOfToken
+ */
+func (store *MemoryStore) ReadAPIKeyOfToken(token string) (key types.APIKey, valid bool, err error) {
+	var hash []byte
+	if hash, err = hashOfToken(token); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var now int64 = time.Now().Unix()
+	var row *memoryAPIKey
+	for _, row = range store.apikeys {
+		if row.token != string(hash) {
+			continue
+		}
+
+		if valid = row.key.Expires == 0 || row.key.Expires >= now; valid && now-row.key.LastUsed >= SESSION_TOUCH_INTERVAL {
+			row.key.LastUsed = now
+		}
+
+		key = copyAPIKey(row.key)
+		return
+	}
+
+	return
+}
+
+/**
+ * Read every API key of some user of id `ID`, newest first
+ */
+func (store *MemoryStore) ListAPIKeys(ID string) (keys []types.APIKey, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	var items []memoryOrdered = make([]memoryOrdered, 0)
+	var key string
+	var row *memoryAPIKey
+	for key, row = range store.apikeys {
+		if row.key.Owner == ID {
+			items = append(items, memoryOrdered{key, row.order})
+		}
+	}
+
+	var ordered []string = pageOrdered(items, -1, len(items))
+	keys = make([]types.APIKey, len(ordered))
+
+	var index int
+	for index, key = range ordered {
+		keys[index] = copyAPIKey(store.apikeys[key].key)
+	}
+
+	return
+}
+
+/**
+ * Revoke some API key of id `ID`
+ */
+func (store *MemoryStore) RevokeAPIKey(ID string) (err error) {
+	store.writeLock()
+	delete(store.apikeys, ID)
+	store.writeUnlock()
+	return
+}
+
+/**
+ * Revoke every API key of some user of id `ID`
+ */
+func (store *MemoryStore) RevokeAPIKeysOf(ID string) (err error) {
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var key string
+	var row *memoryAPIKey
+	for key, row = range store.apikeys {
+		if row.key.Owner == ID {
+			delete(store.apikeys, key)
+		}
+	}
+
+	return
+}
+
+/**
+ * Check that password `password` matches the hash for user of id `ID`
+ */
+func (store *MemoryStore) CheckPassword(ID, password string) (valid bool, err error) {
+	store.lock.RLock()
+	var hash []byte
+	var exists bool
+	hash, exists = store.hashes[ID]
+	store.lock.RUnlock()
+
+	valid = exists && bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+	return
+}
+
+/**
+ * Set a password `password` for some user of id `ID`
+ */
+func (store *MemoryStore) SetPassword(ID, password string) (err error) {
+	var hash []byte
+	if hash, err = bcrypt.GenerateFromPassword([]byte(password), BCRYPT_ITERS); err != nil {
+		return
+	}
+
+	store.writeLock()
+	store.hashes[ID] = hash
+	store.writeUnlock()
+	return
+}
+
+/**
+ * Write some ban `ban`, replacing any ban of the same id
+ */
+func (store *MemoryStore) WriteBan(ban map[string]interface{}) (err error) {
+	if err = checkColumns(BAN_TABLE, ban); err != nil {
+		return
+	}
+
+	var written types.Ban
+	if err = written.FromMap(ban); err != nil {
+		return
+	}
+
+	store.writeLock()
+	store.bans[written.ID] = &memoryBan{written, store.nextOrder()}
+	store.writeUnlock()
+	return
+}
+
+/**
+ * Create some new ban `ban`
+ * Works in the same way as SQLStore.CreateBan
+ */
+func (store *MemoryStore) CreateBan(ban types.Ban) (err error) {
+	if err = validateBan(ban); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var exists bool
+	if _, exists = store.bans[ban.ID]; exists {
+		err = errorOf(ErrDuplicate, "Ban %s already exists", ban.ID)
+		return
+	}
+
+	store.bans[ban.ID] = &memoryBan{ban, store.nextOrder()}
+	return
+}
+
+/**
+ * Update some ban of the same id as `ban`
+ * Works in the same way as SQLStore.UpdateBan
+ */
+func (store *MemoryStore) UpdateBan(ban types.Ban) (err error) {
+	if err = validateBan(ban); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var row *memoryBan
+	var exists bool
+	if row, exists = store.bans[ban.ID]; !exists {
+		err = errorOf(ErrNotFound, "Ban %s does not exist", ban.ID)
+		return
+	}
+
+	row.ban.Reason, row.ban.Expires, row.ban.Forever = ban.Reason, ban.Expires, ban.Forever
+	return
+}
+
+/**
+ * Read a single ban of id `ID`
+ */
+func (store *MemoryStore) ReadSingleBan(ID string) (ban types.Ban, exists bool, err error) {
+	store.lock.RLock()
+	var row *memoryBan
+	if row, exists = store.bans[ID]; exists {
+		ban = row.ban
+	}
+
+	store.lock.RUnlock()
+	return
+}
+
+/**
+ * Read a slice of bans of some user of id `ID`, newest first
+ */
+func (store *MemoryStore) ReadBansOfUser(ID, before string, count int) (bans []types.Ban, size int, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	bans = make([]types.Ban, 0)
+
+	var cursor int64 = -1
+	if before != "" {
+		var row *memoryBan
+		var ok bool
+		if row, ok = store.bans[before]; !ok {
+			return
+		}
+
+		cursor = row.order
+	}
+
+	var items []memoryOrdered = make([]memoryOrdered, 0)
+	var row *memoryBan
+	for _, row = range store.bans {
+		if row.ban.Banned == ID {
+			items = append(items, memoryOrdered{row.ban.ID, row.order})
+		}
+	}
+
+	var key string
+	for _, key = range pageOrdered(items, cursor, count) {
+		bans = append(bans, store.bans[key].ban)
+	}
+
+	size = len(bans)
+	return
+}
+
+/**
+ * Get whether or not a user is banned, either by a permanent ban, or an expirable ban
+ */
+func (store *MemoryStore) IsBanned(ID string) (banned bool, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	var now int64 = time.Now().Unix()
+	var row *memoryBan
+	for _, row = range store.bans {
+		if row.ban.Banned == ID && (row.ban.Forever || row.ban.Expires > now) {
+			banned = true
+			return
+		}
+	}
+
+	return
+}
+
+/**
+ * Create or update a report for some user
+ */
+func (store *MemoryStore) WriteReport(report map[string]interface{}) (err error) {
+	if err = checkColumns(REPORT_TABLE, report); err != nil {
+		return
+	}
+
+	var written types.Report
+	if err = written.FromMap(report); err != nil {
+		return
+	}
+
+	store.writeLock()
+	store.reports[written.ID] = &memoryReport{written, store.nextOrder()}
+	store.writeUnlock()
+	return
+}
+
+/**
+ * Create some new report `report`
+ * Works in the same way as SQLStore.CreateReport
+ */
+func (store *MemoryStore) CreateReport(report types.Report) (err error) {
+	if err = validateReport(report); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var exists bool
+	if _, exists = store.reports[report.ID]; exists {
+		err = errorOf(ErrDuplicate, "Report %s already exists", report.ID)
+		return
+	}
+
+	store.reports[report.ID] = &memoryReport{report, store.nextOrder()}
+	return
+}
+
+/**
+ * Update some report of the same id as `report`
+ * Works in the same way as SQLStore.UpdateReport
+ */
+func (store *MemoryStore) UpdateReport(report types.Report) (err error) {
+	if err = validateReport(report); err != nil {
+		return
+	}
+
+	store.writeLock()
+	defer store.writeUnlock()
+
+	var row *memoryReport
+	var exists bool
+	if row, exists = store.reports[report.ID]; !exists {
+		err = errorOf(ErrNotFound, "Report %s does not exist", report.ID)
+		return
+	}
+
+	row.report.Resolved, row.report.Resolution = report.Resolved, report.Resolution
+	return
+}
+
+/**
+ * Read a slice of unresolved reports by order of most recent
+ */
+func (store *MemoryStore) ReadManyUnresolvedReport(before string, count int) (reports []types.Report, size int, err error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	reports = make([]types.Report, 0)
+
+	var cursor int64 = -1
+	if before != "" {
+		var row *memoryReport
+		var ok bool
+		if row, ok = store.reports[before]; !ok {
+			return
+		}
+
+		cursor = row.order
+	}
+
+	var items []memoryOrdered = make([]memoryOrdered, 0)
+	var row *memoryReport
+	for _, row = range store.reports {
+		if !row.report.Resolved {
+			items = append(items, memoryOrdered{row.report.ID, row.order})
+		}
+	}
+
+	var key string
+	for _, key = range pageOrdered(items, cursor, count) {
+		reports = append(reports, store.reports[key].report)
+	}
+
+	size = len(reports)
+	return
+}
+
+/**
+ * Lookup single report by its ID
+ */
+func (store *MemoryStore) ReadSingleReport(ID string) (report types.Report, exists bool, err error) {
+	store.lock.RLock()
+	var row *memoryReport
+	if row, exists = store.reports[ID]; exists {
+		report = row.report
+	}
+
+	store.lock.RUnlock()
+	return
+}
@@ -0,0 +1,609 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	MIGRATION_TABLE      = "migrations"
+	MIGRATION_DEFINITION = `
+			version BIGINT UNSIGNED UNIQUE PRIMARY KEY NOT NULL,
+			applied BIGINT UNSIGNED NOT NULL`
+
+	// TOKEN_TABLE as the first migration creates it, before the second moves its tokens into SESSION_TABLE
+	TOKEN_DEFINITION_1 = `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			token BINARY(24) UNIQUE,
+			created BIGINT UNSIGNED NOT NULL`
+
+	// SESSION_TABLE as the second migration creates it, before the third adds issued
+	SESSION_DEFINITION_2 = `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			owner CHAR(36) NOT NULL,
+			token BINARY(24) UNIQUE NOT NULL,
+			device CHAR(64) NOT NULL,
+			ip CHAR(45) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			last_used BIGINT UNSIGNED NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`
+	SESSION_FIELDS_2 = "id, owner, token, device, ip, created, last_used"
+
+	// SESSION_TABLE and REFRESH_TABLE as the third migration leaves them, before the fourth hashes their tokens
+	SESSION_DEFINITION_3 = SESSION_DEFINITION_2 + `,
+			issued BIGINT UNSIGNED NOT NULL DEFAULT 0`
+	REFRESH_DEFINITION_3 = `
+			token BINARY(24) UNIQUE PRIMARY KEY NOT NULL,
+			session CHAR(36) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			used BOOLEAN NOT NULL`
+
+	// SECRET_TABLE as the first migration creates it, before the fourth hashes its secrets
+	SECRET_DEFINITION_1 = `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			secret BINARY(128) UNIQUE`
+
+	// SECRET_TABLE, SESSION_TABLE, and REFRESH_TABLE as the fourth migration creates them, to hold hashes
+	SECRET_DEFINITION_4 = `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			secret BINARY(32) UNIQUE NOT NULL`
+	SESSION_DEFINITION_4 = `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			owner CHAR(36) NOT NULL,
+			token BINARY(32) UNIQUE NOT NULL,
+			device CHAR(64) NOT NULL,
+			ip CHAR(45) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			last_used BIGINT UNSIGNED NOT NULL,
+			issued BIGINT UNSIGNED NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`
+	REFRESH_DEFINITION_4 = `
+			token BINARY(32) UNIQUE PRIMARY KEY NOT NULL,
+			session CHAR(36) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			used BOOLEAN NOT NULL`
+
+	// APIKEY_TABLE as the fifth migration creates it
+	APIKEY_DEFINITION_5 = `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			owner CHAR(36) NOT NULL,
+			token BINARY(32) UNIQUE NOT NULL,
+			name CHAR(64) NOT NULL,
+			scopes CHAR(255) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			expires BIGINT UNSIGNED NOT NULL,
+			last_used BIGINT UNSIGNED NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`
+
+	// VOTE_TABLE as the first migration creates it, before the sixth drops its foreign key,
+	// which deleted every vote on content that was written again with REPLACE INTO
+	VOTE_DEFINITION_1 = `
+			voter CHAR(36) NOT NULL,
+			content CHAR(36) NOT NULL,
+			value TINYINT NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			CONSTRAINT no_dupe_votes UNIQUE(voter, content),
+			CONSTRAINT content_bound_votes FOREIGN KEY (content) REFERENCES ` + CONTENT_TABLE + `(id) ON DELETE CASCADE`
+	VOTE_FIELDS_1 = "voter, content, value, created"
+
+	// VOTE_TABLE as the sixth migration copies it
+	VOTE_DEFINITION_6 = `
+			voter CHAR(36) NOT NULL,
+			content CHAR(36) NOT NULL,
+			value TINYINT NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			CONSTRAINT unique_votes UNIQUE(voter, content)`
+
+	// REPUB_TABLE as the first migration creates it, before the seventh places each repub in the feed
+	REPUB_DEFINITION_1 = `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			content CHAR(36) NOT NULL,
+			author CHAR(36) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT,
+			CONSTRAINT no_dupe_repubs UNIQUE(author, content)`
+	REPUB_FIELDS_1 = "id, content, author, created"
+)
+
+/**
+ * One statement of a migration
+ * Migrations are run again from the start if they fail partway through, and MariaDB keeps
+ * whatever of them changed a table, so each step must leave things as they are if it's already been done
+ * Most say so themselves, with IF EXISTS or IF NOT EXISTS, and the rest are run only if
+ * ifTable exists, and if it doesn't have unlessColumn yet
+ */
+type migrationStep struct {
+	statement    string
+	ifTable      string
+	unlessColumn string
+}
+
+/**
+ * One step of the schema, as the statements that apply it and the statements that undo it
+ * Statements are written for MariaDB, and rewritten for the dialect that they're run on
+ * Nothing that a migration runs may change once it's been released, so they're written out
+ * here rather than taken from tables, which is only ever the latest schema
+ */
+type migration struct {
+	up   []migrationStep
+	down []migrationStep
+}
+
+var (
+	// migrations[n] takes the schema from version n to version n+1
+	// Only ever append to this, as databases in the wild remember how far they've gotten
+	migrations []migration = []migration{
+		migrationOfTables(tableOrdered, tablesOfVersion1),
+		migrationOfSessions(),
+		migrationOfRefresh(),
+		migrationOfReplaced([]string{SECRET_TABLE, SESSION_TABLE, REFRESH_TABLE}, map[string]string{
+			SECRET_TABLE:  SECRET_DEFINITION_4,
+			SESSION_TABLE: SESSION_DEFINITION_4,
+			REFRESH_TABLE: REFRESH_DEFINITION_4,
+		}, map[string]string{
+			SECRET_TABLE:  SECRET_DEFINITION_1,
+			SESSION_TABLE: SESSION_DEFINITION_3,
+			REFRESH_TABLE: REFRESH_DEFINITION_3,
+		}),
+		migrationOfTables([]string{APIKEY_TABLE}, map[string]string{APIKEY_TABLE: APIKEY_DEFINITION_5}),
+		migrationOfVotes(),
+		migrationOfFeedIndex(),
+	}
+
+	// Every table that the first migration creates, in an order that they can be created in
+	tableOrdered []string = []string{
+		USER_TABLE,
+		CONTENT_TABLE,
+		AUTH_TABLE,
+		TOKEN_TABLE,
+		SECRET_TABLE,
+		SUBSCRIPTION_TABLE,
+		BAN_TABLE,
+		REPORT_TABLE,
+		TAG_TABLE,
+		VOTE_TABLE,
+		COMMENT_TABLE,
+		REPUB_TABLE,
+	}
+
+	// Definitions of every table of tableOrdered as the first migration creates them
+	tablesOfVersion1 map[string]string = map[string]string{
+		CONTENT_TABLE: `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			file_url CHAR(64) NOT NULL,
+			author CHAR(36) NOT NULL,
+			mime CHAR(255) NOT NULL,
+			like_count BIGINT UNSIGNED NOT NULL,
+			dislike_count BIGINT UNSIGNED NOT NULL,
+			repub_count BIGINT UNSIGNED NOT NULL,
+			view_count BIGINT UNSIGNED NOT NULL,
+			comment_count BIGINT UNSIGNED NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			featured BOOLEAN,
+			featurable BOOLEAN,
+			removed BOOLEAN,
+			nsfw BOOLEAN,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`,
+		USER_TABLE: `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			email CHAR(254) UNIQUE NOT NULL,
+			nick CHAR(16) UNIQUE NOT NULL,
+			bio CHAR(255) NOT NULL,
+			subscriber_count BIGINT UNSIGNED NOT NULL,
+			subscription_count BIGINT UNSIGNED NOT NULL,
+			post_count BIGINT UNSIGNED NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			moderator BOOLEAN NOT NULL,
+			admin BOOLEAN NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`,
+		AUTH_TABLE: `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			hash BINARY(60) NOT NULL`,
+		TOKEN_TABLE:  TOKEN_DEFINITION_1,
+		SECRET_TABLE: SECRET_DEFINITION_1,
+		TAG_TABLE: `
+			id CHAR(36) NOT NULL,
+			tag CHAR(64) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT,
+			CONSTRAINT no_dupe_tags UNIQUE(id, tag),
+			CONSTRAINT content_bound_tags FOREIGN KEY (id) REFERENCES ` + CONTENT_TABLE + `(id) ON DELETE CASCADE`,
+		COMMENT_TABLE: `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			content CHAR(36) NOT NULL,
+			author CHAR(36) NOT NULL,
+			parent CHAR(36) NOT NULL,
+			body TEXT NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			edited BIGINT UNSIGNED NOT NULL,
+			deleted BOOLEAN NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`,
+		REPUB_TABLE: REPUB_DEFINITION_1,
+		VOTE_TABLE:  VOTE_DEFINITION_1,
+		SUBSCRIPTION_TABLE: `
+			subscriber CHAR(36) NOT NULL,
+			subscription CHAR(36) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT,
+			CONSTRAINT no_dupe_subscriptions UNIQUE(subscriber, subscription)`,
+		BAN_TABLE: `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			banner CHAR(36) NOT NULL,
+			banned CHAR(36) NOT NULL,
+			reason CHAR(255),
+			expires BIGINT UNSIGNED NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			forever BOOLEAN,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`,
+		REPORT_TABLE: `
+			id CHAR(36) UNIQUE PRIMARY KEY NOT NULL,
+			reporter CHAR(36) NOT NULL,
+			reported CHAR(36) NOT NULL,
+			type CHAR(31) NOT NULL,
+			reason CHAR(255) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			resolved BOOLEAN NOT NULL,
+			resolution CHAR(255) NOT NULL,
+			order_index BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT`,
+	}
+)
+
+/**
+ * Make steps of `statements` that need nothing checked before they're run again
+ */
+func migrationSteps(statements ...string) (steps []migrationStep) {
+	steps = make([]migrationStep, len(statements))
+
+	var index int
+	var statement string
+	for index, statement = range statements {
+		steps[index] = migrationStep{statement: statement}
+	}
+
+	return
+}
+
+/**
+ * A migration that creates every table in `ordered` as `definitions` has them, and drops them in reverse
+ * Tables are created only if they don't exist, so that databases made before migrations were
+ * tracked are taken to be at version 1 by the first migration, which creates tableOrdered
+ */
+func migrationOfTables(ordered []string, definitions map[string]string) (created migration) {
+	created = migration{
+		up:   make([]migrationStep, len(ordered)),
+		down: make([]migrationStep, len(ordered)),
+	}
+
+	var index int
+	var table string
+	for index, table = range ordered {
+		created.up[index] = migrationStep{statement: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, definitions[table])}
+	}
+
+	for index, table = range listStringReverse(ordered) {
+		created.down[index] = migrationStep{statement: "DROP TABLE IF EXISTS " + table}
+	}
+
+	return
+}
+
+/**
+ * The second migration, which moves tokens from TOKEN_TABLE, of one per user, into SESSION_TABLE, of many per user
+ * Each token becomes a session of the id of its owner, as there was only the one
+ * Undoing it keeps only the newest session of each user
+ */
+func migrationOfSessions() (created migration) {
+	created = migration{
+		up: []migrationStep{
+			{statement: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", SESSION_TABLE, SESSION_DEFINITION_2)},
+			{
+				statement: "INSERT INTO " + SESSION_TABLE + " (id, owner, token, device, ip, created, last_used) SELECT id, id, token, '', '', created, created FROM " + TOKEN_TABLE + " WHERE token IS NOT NULL AND id NOT IN (SELECT id FROM " + SESSION_TABLE + ")",
+				ifTable:   TOKEN_TABLE,
+			},
+			{statement: "DROP TABLE IF EXISTS " + TOKEN_TABLE},
+		},
+		down: []migrationStep{
+			{statement: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", TOKEN_TABLE, TOKEN_DEFINITION_1)},
+			{
+				statement: "INSERT INTO " + TOKEN_TABLE + " (id, token, created) SELECT owner, token, created FROM " + SESSION_TABLE + " AS newest WHERE order_index=(SELECT MAX(order_index) FROM " + SESSION_TABLE + " WHERE owner=newest.owner) AND owner NOT IN (SELECT id FROM " + TOKEN_TABLE + ")",
+				ifTable:   SESSION_TABLE,
+			},
+			{statement: "DROP TABLE IF EXISTS " + SESSION_TABLE},
+		},
+	}
+
+	return
+}
+
+/**
+ * The third migration, which creates REFRESH_TABLE and adds when the token of each session was issued,
+ * as refreshing a session gives it a new token
+ * Undoing it copies SESSION_TABLE without issued rather than dropping the column, which SQLite can't do,
+ * and numbers the order of its rows anew so that every dialect counts on from the last of them
+ */
+func migrationOfRefresh() (created migration) {
+	var previous string = SESSION_TABLE + "_2"
+
+	created = migration{
+		up: []migrationStep{
+			{
+				statement:    "ALTER TABLE " + SESSION_TABLE + " ADD COLUMN issued BIGINT UNSIGNED NOT NULL DEFAULT 0",
+				ifTable:      SESSION_TABLE,
+				unlessColumn: "issued",
+			},
+			{statement: "UPDATE " + SESSION_TABLE + " SET issued=created"},
+			{statement: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", REFRESH_TABLE, REFRESH_DEFINITION_3)},
+		},
+		down: []migrationStep{
+			{statement: "DROP TABLE IF EXISTS " + REFRESH_TABLE},
+			{statement: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", previous, SESSION_DEFINITION_2)},
+			{
+				statement: "INSERT INTO " + previous + " (" + SESSION_FIELDS_2 + ") SELECT " + SESSION_FIELDS_2 + " FROM " + SESSION_TABLE + " WHERE id NOT IN (SELECT id FROM " + previous + ") ORDER BY order_index ASC",
+				ifTable:   SESSION_TABLE,
+			},
+			{statement: "DROP TABLE IF EXISTS " + SESSION_TABLE},
+			{statement: "ALTER TABLE " + previous + " RENAME TO " + SESSION_TABLE, ifTable: previous},
+		},
+	}
+
+	return
+}
+
+/**
+ * A migration that drops every table in `ordered` and creates it again as `after` has them,
+ * for changes that can't keep what those tables held, such as the fourth, which invalidates every session,
+ * refresh token, and secret that was stored in plaintext
+ * Undoing it does the same, creating them as `before` has them
+ */
+func migrationOfReplaced(ordered []string, after, before map[string]string) (created migration) {
+	created = migration{
+		up:   make([]migrationStep, 0, 2*len(ordered)),
+		down: make([]migrationStep, 0, 2*len(ordered)),
+	}
+
+	var table string
+	for _, table = range listStringReverse(ordered) {
+		created.up = append(created.up, migrationStep{statement: "DROP TABLE IF EXISTS " + table})
+		created.down = append(created.down, migrationStep{statement: "DROP TABLE IF EXISTS " + table})
+	}
+
+	for _, table = range ordered {
+		created.up = append(created.up, migrationStep{statement: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, after[table])})
+		created.down = append(created.down, migrationStep{statement: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, before[table])})
+	}
+
+	return
+}
+
+/**
+ * The sixth migration, which copies VOTE_TABLE without its foreign key on CONTENT_TABLE, so that votes
+ * outlive content being written again, and are deleted along with it by DeleteContent instead
+ * Undoing it copies back only the votes of content that exists, as the foreign key would have it
+ * Its unique constraint is named anew, as Postgres names constraints across every table
+ */
+func migrationOfVotes() (created migration) {
+	var copied string = VOTE_TABLE + "_6"
+	var uncopied string = " WHERE NOT EXISTS (SELECT 1 FROM " + copied + " WHERE " + copied + ".voter=" + VOTE_TABLE + ".voter AND " + copied + ".content=" + VOTE_TABLE + ".content)"
+
+	created = migration{
+		up: []migrationStep{
+			{statement: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", copied, VOTE_DEFINITION_6)},
+			{
+				statement: "INSERT INTO " + copied + " (" + VOTE_FIELDS_1 + ") SELECT " + VOTE_FIELDS_1 + " FROM " + VOTE_TABLE + uncopied,
+				ifTable:   VOTE_TABLE,
+			},
+			{statement: "DROP TABLE IF EXISTS " + VOTE_TABLE},
+			{statement: "ALTER TABLE " + copied + " RENAME TO " + VOTE_TABLE, ifTable: copied},
+		},
+		down: []migrationStep{
+			{statement: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", copied, VOTE_DEFINITION_1)},
+			{
+				statement: "INSERT INTO " + copied + " (" + VOTE_FIELDS_1 + ") SELECT " + VOTE_FIELDS_1 + " FROM " + VOTE_TABLE + uncopied + " AND content IN (SELECT id FROM " + CONTENT_TABLE + ")",
+				ifTable:   VOTE_TABLE,
+			},
+			{statement: "DROP TABLE IF EXISTS " + VOTE_TABLE},
+			{statement: "ALTER TABLE " + copied + " RENAME TO " + VOTE_TABLE, ifTable: copied},
+		},
+	}
+
+	return
+}
+
+/**
+ * The seventh migration, which gives each repub the order_index of the newest content when it was made,
+ * so that feeds order repubs among content by order_index rather than by their created time
+ * Repubs made before it are placed after the newest content that was created before them
+ * Undoing it copies REPUB_TABLE without feed_index through a table without constraints,
+ * as Postgres won't have two tables with a constraint of the same name
+ */
+func migrationOfFeedIndex() (created migration) {
+	var previous string = REPUB_TABLE + "_7"
+
+	created = migration{
+		up: []migrationStep{
+			{
+				statement:    "ALTER TABLE " + REPUB_TABLE + " ADD COLUMN feed_index BIGINT UNSIGNED NOT NULL DEFAULT 0",
+				ifTable:      REPUB_TABLE,
+				unlessColumn: "feed_index",
+			},
+			{statement: "UPDATE " + REPUB_TABLE + " SET feed_index=(SELECT COALESCE(MAX(order_index), 0) FROM " + CONTENT_TABLE + " WHERE " + CONTENT_TABLE + ".created<=" + REPUB_TABLE + ".created)"},
+		},
+		down: []migrationStep{
+			{statement: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", previous, `
+			id CHAR(36) NOT NULL,
+			content CHAR(36) NOT NULL,
+			author CHAR(36) NOT NULL,
+			created BIGINT UNSIGNED NOT NULL,
+			order_index BIGINT UNSIGNED NOT NULL`)},
+			{
+				statement: "INSERT INTO " + previous + " (" + REPUB_FIELDS_1 + ", order_index) SELECT " + REPUB_FIELDS_1 + ", order_index FROM " + REPUB_TABLE + " WHERE id NOT IN (SELECT id FROM " + previous + ")",
+				ifTable:   REPUB_TABLE,
+			},
+			{statement: "DROP TABLE IF EXISTS " + REPUB_TABLE},
+			{statement: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", REPUB_TABLE, REPUB_DEFINITION_1)},
+			{
+				statement: "INSERT INTO " + REPUB_TABLE + " (" + REPUB_FIELDS_1 + ") SELECT " + REPUB_FIELDS_1 + " FROM " + previous + " WHERE id NOT IN (SELECT id FROM " + REPUB_TABLE + ") ORDER BY order_index ASC",
+				ifTable:   previous,
+			},
+			{statement: "DROP TABLE IF EXISTS " + previous},
+		},
+	}
+
+	return
+}
+
+/**
+ * The schema version that this library is written against
+ */
+func SchemaLatest() (version int) {
+	version = len(migrations)
+	return
+}
+
+/**
+ * Create MIGRATION_TABLE, if it doesn't exist yet
+ */
+func (store *SQLStore) createMigrationTable(ctx context.Context) (err error) {
+	_, err = store.db().ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", MIGRATION_TABLE, store.handle.dialect.table(MIGRATION_DEFINITION)))
+	return
+}
+
+/**
+ * Read the schema version that the database has been migrated to, or 0 if it never has been
+ * Uses 2 queries
+ * 		create table:	CREATE TABLE IF NOT EXISTS MIGRATION_TABLE
+ * 		get version:	SELECT version FROM MIGRATION_TABLE ORDER BY version DESC LIMIT 1
+ */
+func (store *SQLStore) SchemaVersionContext(ctx context.Context) (version int, err error) {
+	if err = store.createMigrationTable(ctx); err != nil {
+		return
+	}
+
+	if err = store.db().QueryRowxContext(ctx, READ_SCHEMA_VERSION).Scan(&version); err == sql.ErrNoRows {
+		err = nil
+	}
+
+	return
+}
+
+func (store *SQLStore) SchemaVersion() (version int, err error) {
+	version, err = store.SchemaVersionContext(context.Background())
+	return
+}
+
+/**
+ * Migrate the schema up or down to version `target`, one version at a time
+ * Each version is applied inside of its own transaction along with the record of it,
+ * though MariaDB commits implicitly after each statement that changes a table,
+ * so a version that fails partway through there is picked up where it left off by the next run
+ * Fails without doing anything if either `target` or the database is newer than this library
+ */
+func (store *SQLStore) MigrateContext(ctx context.Context, target int) (err error) {
+	if target < 0 || target > SchemaLatest() {
+		err = fmt.Errorf("Unknown schema version %d, latest is %d", target, SchemaLatest())
+		return
+	}
+
+	var version int
+	if version, err = store.SchemaVersionContext(ctx); err != nil {
+		return
+	}
+
+	if version > SchemaLatest() {
+		err = fmt.Errorf("Database schema version %d is newer than latest %d", version, SchemaLatest())
+		return
+	}
+
+	for version < target {
+		if err = store.applyMigration(ctx, version+1, migrations[version].up, true); err != nil {
+			return
+		}
+
+		version++
+	}
+
+	for version > target {
+		if err = store.applyMigration(ctx, version, migrations[version-1].down, false); err != nil {
+			return
+		}
+
+		version--
+	}
+
+	return
+}
+
+func (store *SQLStore) Migrate(target int) (err error) {
+	err = store.MigrateContext(context.Background(), target)
+	return
+}
+
+/**
+ * Run the `steps` of migration to or from `version`, and record that it was or wasn't applied
+ * Steps that an earlier run already took are skipped by their ifTable and unlessColumn
+ */
+func (store *SQLStore) applyMigration(ctx context.Context, version int, steps []migrationStep, up bool) (err error) {
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		var step migrationStep
+		var taken bool
+		for _, step = range steps {
+			if taken, err = store.migrationTaken(ctx, tx, step); err != nil {
+				return
+			}
+
+			if taken {
+				continue
+			}
+
+			if _, err = tx.ExecContext(ctx, store.handle.dialect.table(step.statement)); err != nil {
+				return
+			}
+		}
+
+		if up {
+			_, err = tx.ExecContext(ctx, WRITE_SCHEMA_VERSION, version, time.Now().Unix())
+		} else {
+			_, err = tx.ExecContext(ctx, DELETE_SCHEMA_VERSION, version)
+		}
+
+		return
+	})
+
+	if err != nil {
+		err = fmt.Errorf("Migrating schema version %d: %s", version, err)
+	}
+
+	return
+}
+
+/**
+ * Check whether some migration step `step` has nothing left to do, as its ifTable doesn't exist
+ * or already has its unlessColumn
+ * Uses up to 2 queries
+ * 		table exists:	SELECT COUNT(*) FROM information_schema.tables WHERE table_name=ifTable
+ * 		column exists:	SELECT COUNT(*) FROM information_schema.columns WHERE table_name=ifTable AND column_name=unlessColumn
+ */
+func (store *SQLStore) migrationTaken(ctx context.Context, tx *dialectTx, step migrationStep) (taken bool, err error) {
+	if step.ifTable == "" {
+		return
+	}
+
+	var count int
+	if err = tx.QueryRowxContext(ctx, store.handle.dialect.tableExists, step.ifTable).Scan(&count); err != nil {
+		return
+	}
+
+	if count == 0 || step.unlessColumn == "" {
+		taken = count == 0
+		return
+	}
+
+	if err = tx.QueryRowxContext(ctx, store.handle.dialect.columnExists, step.ifTable, step.unlessColumn).Scan(&count); err != nil {
+		return
+	}
+
+	taken = count != 0
+	return
+}
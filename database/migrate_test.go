@@ -0,0 +1,515 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func schemaVersionOK(test *testing.T, store *SQLStore, want int) {
+	var version int
+	var err error
+	if version, err = store.SchemaVersion(); err != nil {
+		test.Fatal(err)
+	}
+
+	if version != want {
+		test.Errorf("schema version mismatch! have: %d, want: %d", version, want)
+	}
+}
+
+func Test_SchemaVersion(test *testing.T) {
+	var version int
+	var err error
+	if version, err = SchemaVersion(); err != nil {
+		test.Fatal(err)
+	}
+
+	if version != SchemaLatest() {
+		test.Errorf("connected schema at %d, not %d", version, SchemaLatest())
+	}
+}
+
+func Test_Migrate(test *testing.T) {
+	var store *SQLStore = openSQLite(test)
+	schemaVersionOK(test, store, 0)
+
+	var err error
+	if err = store.Migrate(SchemaLatest()); err != nil {
+		test.Fatal(err)
+	}
+
+	schemaVersionOK(test, store, SchemaLatest())
+
+	if err = store.WriteContent(mapMod(writableContent, map[string]interface{}{"id": uuid.New().String()})); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = store.Migrate(0); err != nil {
+		test.Fatal(err)
+	}
+
+	schemaVersionOK(test, store, 0)
+
+	if err = store.WriteContent(mapMod(writableContent, map[string]interface{}{"id": uuid.New().String()})); err == nil {
+		test.Errorf("content written after its table was migrated away")
+	}
+}
+
+func Test_Migrate_sessions(test *testing.T) {
+	var store *SQLStore = openSQLite(test)
+
+	var err error
+	if err = store.Migrate(1); err != nil {
+		test.Fatal(err)
+	}
+
+	var ID string = uuid.New().String()
+	var bytes []byte
+	if bytes, err = randomBytes(TOKEN_LENGTH); err != nil {
+		test.Fatal(err)
+	}
+
+	if _, err = store.handle.Exec("INSERT INTO "+TOKEN_TABLE+" (id, token, created) VALUES (?, ?, ?)", ID, bytes, time.Now().Unix()); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = store.Migrate(3); err != nil {
+		test.Fatal(err)
+	}
+
+	var owner string
+	if err = store.handle.QueryRowx("SELECT owner FROM "+SESSION_TABLE+" WHERE token=?", bytes).Scan(&owner); err != nil {
+		test.Fatal(err)
+	}
+
+	if owner != ID {
+		test.Errorf("migrated token is owned by %s, not %s", owner, ID)
+	}
+
+	if err = store.Migrate(1); err != nil {
+		test.Fatal(err)
+	}
+
+	var migrated []byte
+	if err = store.handle.QueryRowx("SELECT token FROM "+TOKEN_TABLE+" WHERE id=?", ID).Scan(&migrated); err != nil {
+		test.Fatal(err)
+	}
+
+	if string(migrated) != string(bytes) {
+		test.Errorf("token of %s was not migrated back", ID)
+	}
+}
+
+func Test_Migrate_hashes(test *testing.T) {
+	var count int
+	for count = 0; count <= len(migrations[3].up); count++ {
+		migrateHashesOK(test, count)
+	}
+}
+
+// Migrate to 4 after `count` of its steps were already taken, and check that nothing made before it still works
+func migrateHashesOK(test *testing.T, count int) {
+	var store *SQLStore = openSQLite(test)
+
+	var err error
+	if err = store.Migrate(3); err != nil {
+		test.Fatal(err)
+	}
+
+	var ID string = uuid.New().String()
+	var token, refresh, secret []byte
+	if token, err = randomBytes(TOKEN_LENGTH); err != nil {
+		test.Fatal(err)
+	}
+
+	if refresh, err = randomBytes(TOKEN_LENGTH); err != nil {
+		test.Fatal(err)
+	}
+
+	if secret, err = randomBytes(SECRET_LENGTH); err != nil {
+		test.Fatal(err)
+	}
+
+	var now int64 = time.Now().Unix()
+	if _, err = store.handle.Exec("INSERT INTO "+SESSION_TABLE+" (id, owner, token, device, ip, created, last_used, issued) VALUES (?, ?, ?, '', '', ?, ?, ?)", ID, ID, token, now, now, now); err != nil {
+		test.Fatal(err)
+	}
+
+	if _, err = store.handle.Exec("INSERT INTO "+REFRESH_TABLE+" (token, session, created, used) VALUES (?, ?, ?, ?)", refresh, ID, now, false); err != nil {
+		test.Fatal(err)
+	}
+
+	if _, err = store.handle.Exec("INSERT INTO "+SECRET_TABLE+" (id, secret) VALUES (?, ?)", ID, secret); err != nil {
+		test.Fatal(err)
+	}
+
+	takeSteps(test, store, migrations[3].up, count)
+	if err = store.Migrate(4); err != nil {
+		test.Fatal(err)
+	}
+
+	var valid bool
+	if _, valid, err = store.ReadSessionOfToken(base64.URLEncoding.EncodeToString(token)); err != nil {
+		test.Fatal(err)
+	}
+
+	if valid {
+		test.Errorf("plaintext token is still valid after %d steps", count)
+	}
+
+	if _, err = store.RefreshTokenPair(base64.URLEncoding.EncodeToString(refresh)); err == nil {
+		test.Errorf("plaintext refresh token is still valid after %d steps", count)
+	}
+
+	if valid, err = store.CheckSecret(ID, base64.URLEncoding.EncodeToString(secret)); err != nil {
+		test.Fatal(err)
+	}
+
+	if valid {
+		test.Errorf("plaintext secret is still valid after %d steps", count)
+	}
+
+	var sessions []types.Session
+	if sessions, err = store.ListSessions(ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if len(sessions) != 0 {
+		test.Errorf("plaintext sessions were kept after %d steps: %#v", count, sessions)
+	}
+
+	if err = store.Migrate(3); err != nil {
+		test.Fatal(err)
+	}
+}
+
+func Test_Migrate_votes(test *testing.T) {
+	var store *SQLStore = openSQLite(test)
+
+	var err error
+	if err = store.Migrate(5); err != nil {
+		test.Fatal(err)
+	}
+
+	var ID string = uuid.New().String()
+	var voter string = uuid.New().String()
+	if err = store.WriteContent(mapMod(writableContent, map[string]interface{}{"id": ID})); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = store.Vote(voter, ID, VOTE_DISLIKE); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = store.Migrate(6); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = store.WriteContent(mapMod(writableContent, map[string]interface{}{"id": ID})); err != nil {
+		test.Fatal(err)
+	}
+
+	var value int
+	if value, err = store.ReadVote(voter, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if value != VOTE_DISLIKE {
+		test.Errorf("vote of %s was not migrated, have: %d", voter, value)
+	}
+
+	if err = store.Migrate(5); err != nil {
+		test.Fatal(err)
+	}
+
+	if value, err = store.ReadVote(voter, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if value != VOTE_DISLIKE {
+		test.Errorf("vote of %s was not migrated back, have: %d", voter, value)
+	}
+}
+
+func Test_Migrate_feedIndex(test *testing.T) {
+	var store *SQLStore = openSQLite(test)
+
+	var err error
+	if err = store.Migrate(6); err != nil {
+		test.Fatal(err)
+	}
+
+	var ID, author string = uuid.New().String(), uuid.New().String()
+	if err = store.WriteContent(mapMod(writableContent, map[string]interface{}{"id": ID})); err != nil {
+		test.Fatal(err)
+	}
+
+	if _, err = store.handle.Exec("INSERT INTO "+REPUB_TABLE+" ("+REPUB_FIELDS_1+") VALUES (?, ?, ?, ?)", uuid.New().String(), ID, author, time.Now().Unix()); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = store.Migrate(7); err != nil {
+		test.Fatal(err)
+	}
+
+	var original string = uuid.New().String()
+	if err = store.WriteContent(mapMod(writableContent, map[string]interface{}{"id": original, "author": author})); err != nil {
+		test.Fatal(err)
+	}
+
+	var entries []types.FeedEntry
+	if entries, _, err = store.ReadAuthorEntries(author, "", 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if len(entries) != 2 || entries[0].ID != original || entries[1].Content.ID != ID {
+		test.Errorf("migrated repub is out of order! have: %#v", entries)
+	}
+
+	if err = store.Migrate(6); err != nil {
+		test.Fatal(err)
+	}
+
+	var repubbed bool
+	if repubbed, err = store.IsRepubbed(author, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if !repubbed {
+		test.Errorf("repub of %s was not migrated back", ID)
+	}
+}
+
+func Test_Migrate_again(test *testing.T) {
+	var store *SQLStore = freshSQLite(test)
+
+	var err error
+	if err = store.Migrate(SchemaLatest()); err != nil {
+		test.Fatal(err)
+	}
+
+	schemaVersionOK(test, store, SchemaLatest())
+}
+
+func Test_Migrate_rollback(test *testing.T) {
+	var backup []migration = migrations
+	defer func() { migrations = backup }()
+
+	migrations = append(migrations, migration{
+		up:   migrationSteps("CREATE TABLE halfway (id CHAR(36))", "GAS GAS GAS"),
+		down: migrationSteps("DROP TABLE halfway"),
+	})
+
+	var store *SQLStore = openSQLite(test)
+
+	var err error
+	if err = store.Migrate(SchemaLatest()); err == nil {
+		test.Fatal("bad migration produced no error")
+	}
+
+	schemaVersionOK(test, store, SchemaLatest()-1)
+
+	if _, err = store.handle.Exec("SELECT * FROM halfway"); err == nil {
+		test.Errorf("table of a failed migration was left behind")
+	}
+}
+
+// Take the first `count` of `steps` as a run of them that failed partway through would have
+func takeSteps(test *testing.T, store *SQLStore, steps []migrationStep, count int) {
+	var err error
+	var step migrationStep
+	for _, step = range steps[:count] {
+		err = store.withTx(context.Background(), func(tx *dialectTx) (err error) {
+			var taken bool
+			if taken, err = store.migrationTaken(context.Background(), tx, step); err != nil || taken {
+				return
+			}
+
+			_, err = tx.Exec(store.handle.dialect.table(step.statement))
+			return
+		})
+
+		if err != nil {
+			test.Fatal(err)
+		}
+	}
+}
+
+func Test_Migrate_resume(test *testing.T) {
+	var store *SQLStore
+	var version, count int
+	var err error
+	for version = 1; version <= SchemaLatest(); version++ {
+		for count = 0; count <= len(migrations[version-1].up); count++ {
+			store = openSQLite(test)
+			if err = store.Migrate(version - 1); err != nil {
+				test.Fatal(err)
+			}
+
+			takeSteps(test, store, migrations[version-1].up, count)
+			if err = store.Migrate(version); err != nil {
+				test.Errorf("resuming migration to %d after %d steps: %s", version, count, err)
+			}
+		}
+
+		for count = 0; count <= len(migrations[version-1].down); count++ {
+			store = openSQLite(test)
+			if err = store.Migrate(version); err != nil {
+				test.Fatal(err)
+			}
+
+			takeSteps(test, store, migrations[version-1].down, count)
+			if err = store.Migrate(version - 1); err != nil {
+				test.Errorf("resuming migration from %d after %d steps: %s", version, count, err)
+			}
+		}
+	}
+}
+
+func Test_Migrate_resumeVotes(test *testing.T) {
+	var store *SQLStore = openSQLite(test)
+
+	var err error
+	if err = store.Migrate(5); err != nil {
+		test.Fatal(err)
+	}
+
+	var ID string = uuid.New().String()
+	var voter string = uuid.New().String()
+	if err = store.WriteContent(mapMod(writableContent, map[string]interface{}{"id": ID})); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = store.Vote(voter, ID, VOTE_LIKE); err != nil {
+		test.Fatal(err)
+	}
+
+	var steps []migrationStep = migrations[5].up
+	takeSteps(test, store, steps, len(steps)-1)
+
+	if err = store.Migrate(6); err != nil {
+		test.Fatal(err)
+	}
+
+	var value int
+	if value, err = store.ReadVote(voter, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if value != VOTE_LIKE {
+		test.Errorf("vote of %s was lost resuming the migration, have: %d", voter, value)
+	}
+}
+
+// Migrating all the way up should arrive at tables, which migrations never read
+func Test_Migrate_latest(test *testing.T) {
+	var migrated *SQLStore = freshSQLite(test)
+	var created *SQLStore = openSQLite(test)
+
+	var table string
+	var err error
+	for _, table = range tableLatest {
+		if _, err = created.handle.Exec(created.handle.dialect.table("CREATE TABLE " + table + " (" + tables[table] + ")")); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	var query string = "SELECT name, type, \"notnull\", COALESCE(dflt_value, ''), pk FROM pragma_table_info(?)"
+	var have, want [][]interface{}
+	for _, table = range tableLatest {
+		if have, err = sqliteColumnsOf(migrated, query, table); err != nil {
+			test.Fatal(err)
+		}
+
+		if want, err = sqliteColumnsOf(created, query, table); err != nil {
+			test.Fatal(err)
+		}
+
+		if fmt.Sprint(have) != fmt.Sprint(want) {
+			test.Errorf("migrated %s mismatch! have: %v, want: %v", table, have, want)
+		}
+	}
+}
+
+func sqliteColumnsOf(store *SQLStore, query, table string) (columns [][]interface{}, err error) {
+	var rows *sqlx.Rows
+	if rows, err = store.handle.Queryx(query, table); err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	var column []interface{}
+	for rows.Next() {
+		if column, err = rows.SliceScan(); err != nil {
+			return
+		}
+
+		columns = append(columns, column)
+	}
+
+	err = rows.Err()
+	return
+}
+
+func Test_Migrate_err(test *testing.T) {
+	var store *SQLStore = freshSQLite(test)
+
+	var err error
+	for _, target := range []int{-1, SchemaLatest() + 1} {
+		if err = store.Migrate(target); err == nil {
+			test.Errorf("migrating to %d produced no error", target)
+		}
+	}
+
+	if _, err = store.handle.Exec(WRITE_SCHEMA_VERSION, SchemaLatest()+1, 0); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = store.Migrate(0); err == nil {
+		test.Errorf("migrating a newer database produced no error")
+	}
+
+	schemaVersionOK(test, store, SchemaLatest()+1)
+}
+
+func Test_Connect_newerSchema(test *testing.T) {
+	var path string = filepath.Join(test.TempDir(), "brane.db")
+
+	var handle *sqlx.DB
+	var err error
+	if handle, err = sqlx.Open("sqlite3", sqliteDSN(path)); err != nil {
+		test.Fatal(err)
+	}
+
+	var store *SQLStore = NewSQLStore(handle)
+	store.Create()
+
+	if _, err = store.handle.Exec(WRITE_SCHEMA_VERSION, SchemaLatest()+1, 0); err != nil {
+		test.Fatal(err)
+	}
+
+	handle.Close()
+
+	var backup *SQLStore = connected
+	defer func() {
+		if recover() == nil {
+			test.Errorf("connecting to a newer schema did not panic")
+		}
+
+		if connected != backup {
+			connected = backup
+			test.Errorf("connected was set to a newer schema")
+		}
+	}()
+
+	Connect("sqlite://" + path)
+}
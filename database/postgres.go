@@ -0,0 +1,222 @@
+package database
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"crypto/tls"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	POSTGRES_ORDER_INDEX   = "BIGSERIAL UNIQUE NOT NULL"
+	POSTGRES_TABLE_EXISTS  = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema=current_schema() AND table_name=?"
+	POSTGRES_COLUMN_EXISTS = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema=current_schema() AND table_name=? AND column_name=?"
+)
+
+var (
+	postgresDialect *dialect = &dialect{
+		driver:       "postgres",
+		dsn:          postgresDSN,
+		statement:    postgresStatement,
+		table:        postgresTable,
+		prepare:      func(*sqlx.DB) {},
+		configure:    postgresConfigure,
+		translate:    postgresTranslate,
+		stale:        postgresStale,
+		replaceKeys:  postgresReplaceKeys,
+		tableExists:  POSTGRES_TABLE_EXISTS,
+		columnExists: POSTGRES_COLUMN_EXISTS,
+	}
+
+	postgresTypes *strings.Replacer = strings.NewReplacer(
+		"BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT", POSTGRES_ORDER_INDEX,
+		" UNSIGNED", "",
+		"TINYINT", "SMALLINT",
+		"CHAR(", "VARCHAR(",
+	)
+
+	postgresBinary  *regexp.Regexp = regexp.MustCompile(`BINARY\(\d+\)`)
+	postgresReplace *regexp.Regexp = regexp.MustCompile(`^REPLACE INTO (\w+) \(([^)]*)\)`)
+
+	// What REPLACE INTO conflicts on, for tables that are written to with a fixed statement
+	postgresConflicts map[string][]string = map[string][]string{
+		AUTH_TABLE:   []string{"id"},
+		SECRET_TABLE: []string{"id"},
+		TAG_TABLE:    []string{"id", "tag"},
+	}
+
+	// Every unique column of tables that are written to with makeSQLInsertable
+	postgresReplaceKeys map[string][]string = map[string][]string{
+		CONTENT_TABLE: []string{"id"},
+		USER_TABLE:    []string{"id", "email", "nick"},
+		BAN_TABLE:     []string{"id"},
+		REPORT_TABLE:  []string{"id"},
+	}
+)
+
+/**
+ * Put the scheme back on a postgres:// address, as lib/pq takes connection URLs as they are
+ */
+func postgresDSN(address string) (dsn string) {
+	dsn = "postgres://" + address
+	return
+}
+
+/**
+ * Configure a postgres:// URL
+ * lib/pq negotiates TLS itself from the URL, so a tls.Config is sent as the sslmode that it asks for:
+ * require if it skips verifying the server, and verify-full otherwise
+ * Its RootCAs and Certificates can't be carried over, and are given as sslrootcert, sslcert, and sslkey in the address,
+ * and an sslmode already in the address is left as it is
+ * As lib/pq has no read timeout, ReadTimeout is sent as the statement_timeout of each connection
+ */
+func postgresConfigure(dsn string, config Config) (configured string, err error) {
+	configured = dsn
+	if config.TLS == nil && config.ReadTimeout <= 0 {
+		return
+	}
+
+	var parsed *url.URL
+	if parsed, err = url.Parse(dsn); err != nil {
+		return
+	}
+
+	var query url.Values = parsed.Query()
+	if config.TLS != nil && query.Get("sslmode") == "" {
+		query.Set("sslmode", postgresSSLMode(config.TLS))
+	}
+
+	if config.ReadTimeout > 0 {
+		query.Set("statement_timeout", strconv.FormatInt(config.ReadTimeout.Milliseconds(), 10))
+	}
+
+	parsed.RawQuery = query.Encode()
+	configured = parsed.String()
+	return
+}
+
+/**
+ * Get the sslmode that asks lib/pq for TLS like `config`
+ */
+func postgresSSLMode(config *tls.Config) (mode string) {
+	mode = "verify-full"
+	if config.InsecureSkipVerify {
+		mode = "require"
+	}
+
+	return
+}
+
+/**
+ * Translate a lib/pq error by its SQLSTATE
+ * Unique violations name the constraint, like users_nick_key, that they failed on
+ */
+func postgresTranslate(err error) (translated error) {
+	translated = err
+
+	var driverErr *pq.Error
+	if !errors.As(err, &driverErr) {
+		return
+	}
+
+	switch driverErr.Code {
+	case "23505":
+		translated = &Error{duplicateOf(driverErr.Constraint + " " + driverErr.Detail), err}
+	case "23503":
+		translated = &Error{ErrNotFound, err}
+	case "23502", "22001", "42703":
+		translated = &Error{ErrInvalid, err}
+	}
+
+	return
+}
+
+/**
+ * Check whether a lib/pq error is of a prepared statement that the server has forgotten,
+ * or whose cached plan was made for tables that have since changed
+ */
+func postgresStale(err error) (stale bool) {
+	var driverErr *pq.Error
+	if errors.As(err, &driverErr) {
+		stale = driverErr.Code == "26000" || (driverErr.Code == "0A000" && strings.Contains(driverErr.Message, "cached plan"))
+	}
+
+	return
+}
+
+/**
+ * Rewrite a MariaDB statement for Postgres
+ * Postgres numbers its placeholders, has ON CONFLICT in place of INSERT IGNORE and REPLACE INTO,
+ * and has no LIMIT on DELETE
+ */
+func postgresStatement(statement string) (rewritten string) {
+	rewritten = statement
+
+	switch {
+	case strings.HasPrefix(rewritten, "DELETE "):
+		rewritten = strings.TrimSuffix(rewritten, " LIMIT 1")
+	case strings.HasPrefix(rewritten, "INSERT IGNORE INTO "):
+		rewritten = "INSERT INTO " + strings.TrimPrefix(rewritten, "INSERT IGNORE INTO ") + " ON CONFLICT DO NOTHING"
+	case strings.HasPrefix(rewritten, "REPLACE INTO "):
+		rewritten = postgresUpsert(rewritten)
+	}
+
+	rewritten = sqlx.Rebind(sqlx.DOLLAR, rewritten)
+	return
+}
+
+/**
+ * Rewrite a REPLACE INTO statement as an upsert on the key that it would conflict on
+ * Every column that isn't part of that key is overwritten
+ */
+func postgresUpsert(statement string) (rewritten string) {
+	var match []string = postgresReplace.FindStringSubmatch(statement)
+	if match == nil {
+		rewritten = statement
+		return
+	}
+
+	var target []string
+	var ok bool
+	if target, ok = postgresConflicts[match[1]]; !ok {
+		target = []string{"id"}
+	}
+
+	var targeted map[string]bool = make(map[string]bool, len(target))
+	var column string
+	for _, column = range target {
+		targeted[column] = true
+	}
+
+	var updates []string = make([]string, 0)
+	for _, column = range strings.Split(match[2], ",") {
+		column = strings.TrimSpace(column)
+		if !targeted[column] {
+			updates = append(updates, column+"=EXCLUDED."+column)
+		}
+	}
+
+	rewritten = "INSERT INTO " + strings.TrimPrefix(statement, "REPLACE INTO ") + " ON CONFLICT (" + strings.Join(target, ", ") + ")"
+	if len(updates) == 0 {
+		rewritten += " DO NOTHING"
+	} else {
+		rewritten += " DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+
+	return
+}
+
+/**
+ * Rewrite a MariaDB table definition for Postgres
+ * order_index is backed by a sequence, CHAR becomes VARCHAR so that values aren't padded with spaces,
+ * and BINARY becomes BYTEA
+ */
+func postgresTable(definition string) (rewritten string) {
+	rewritten = postgresBinary.ReplaceAllString(postgresTypes.Replace(definition), "BYTEA")
+	return
+}
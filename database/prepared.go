@@ -0,0 +1,112 @@
+package database
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"context"
+)
+
+const (
+	// What database/sql returns for a statement that's run after being closed,
+	// as happens when some other goroutine forgets it as stale in between
+	STATEMENT_CLOSED = "sql: statement is closed"
+)
+
+var (
+	// Set of preparedStatements, to look up statements by as they're run
+	preparable map[string]bool = stringSet(preparedStatements)
+)
+
+func stringSet(them []string) (set map[string]bool) {
+	set = make(map[string]bool, len(them))
+
+	var it string
+	for _, it = range them {
+		set[it] = true
+	}
+
+	return
+}
+
+/**
+ * Get the prepared statement of `statement`, if it's been prepared on this handle
+ */
+func (handle *dialectDB) cached(statement string) (stmt *sqlx.Stmt) {
+	var cached interface{}
+	var ok bool
+	if cached, ok = handle.prepared.Load(statement); ok {
+		stmt = cached.(*sqlx.Stmt)
+	}
+
+	return
+}
+
+/**
+ * Get the prepared statement of `statement`, preparing it if this is its first use
+ * Each is prepared once per handle and shared by every goroutine, and database/sql prepares it
+ * again on every connection that it's run on, so it outlives connections that are lost and remade
+ * Returns nil if `statement` isn't one of preparedStatements or can't be prepared,
+ * in which case it should be run as it is
+ */
+func (handle *dialectDB) stmt(ctx context.Context, statement string) (stmt *sqlx.Stmt) {
+	if !preparable[statement] {
+		return
+	}
+
+	if stmt = handle.cached(statement); stmt != nil {
+		return
+	}
+
+	var err error
+	if stmt, err = handle.DB.PreparexContext(ctx, handle.rewrite(statement)); err != nil {
+		stmt = nil
+		return
+	}
+
+	var cached interface{}
+	var loaded bool
+	if cached, loaded = handle.prepared.LoadOrStore(statement, stmt); loaded {
+		stmt.Close()
+		stmt = cached.(*sqlx.Stmt)
+	}
+
+	return
+}
+
+/**
+ * Check whether `err`, of running the prepared `stmt` of `statement`, means that it must be prepared again
+ * If it does, `stmt` is forgotten so that its next use prepares it again, and `statement` should be run as it is
+ */
+func (handle *dialectDB) stale(statement string, stmt *sqlx.Stmt, err error) (stale bool) {
+	if err == nil {
+		return
+	}
+
+	if err.Error() == STATEMENT_CLOSED {
+		stale = true
+		return
+	}
+
+	if stale = handle.dialect.stale(err); stale {
+		if handle.cached(statement) == stmt {
+			handle.prepared.Delete(statement)
+		}
+
+		stmt.Close()
+	}
+
+	return
+}
+
+/**
+ * Close every prepared statement of this handle, and then the handle itself
+ */
+func (handle *dialectDB) Close() (err error) {
+	handle.prepared.Range(func(statement, stmt interface{}) bool {
+		stmt.(*sqlx.Stmt).Close()
+		return true
+	})
+
+	err = handle.DB.Close()
+	return
+}
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func Test_dialectDB_stmt(test *testing.T) {
+	var stmt *sqlx.Stmt = connected.handle.stmt(context.Background(), READ_SESSION_OF_TOKEN)
+	if stmt == nil {
+		test.Fatal("READ_SESSION_OF_TOKEN was not prepared")
+	}
+
+	if connected.handle.stmt(context.Background(), READ_SESSION_OF_TOKEN) != stmt {
+		test.Errorf("READ_SESSION_OF_TOKEN was prepared twice")
+	}
+
+	if connected.handle.stmt(context.Background(), READ_TAGS_OF_MANY_ID+"(?)") != nil {
+		test.Errorf("statement built at runtime was prepared")
+	}
+}
+
+func Test_dialectDB_stale(test *testing.T) {
+	var staleDialect dialect = *connected.handle.dialect
+	staleDialect.stale = func(error) bool { return true }
+
+	var opened *sqlx.DB
+	var err error
+	if _, opened, err = openHandle(CONNECTION, Config{MaxOpenConns: 1}); err != nil {
+		test.Fatal(err)
+	}
+
+	var handle *dialectDB = &dialectDB{DB: opened, dialect: &staleDialect}
+	defer handle.Close()
+
+	var stmt *sqlx.Stmt = handle.stmt(context.Background(), READ_SESSION_OF_TOKEN)
+	if stmt == nil {
+		test.Fatal("READ_SESSION_OF_TOKEN was not prepared")
+	}
+
+	defer stmt.Close()
+
+	if !handle.stale(READ_SESSION_OF_TOKEN, stmt, errors.New("forgotten")) {
+		test.Fatal("stale error was not stale")
+	}
+
+	if handle.cached(READ_SESSION_OF_TOKEN) != nil {
+		test.Errorf("stale statement was not forgotten")
+	}
+
+	if handle.stmt(context.Background(), READ_SESSION_OF_TOKEN) == stmt {
+		test.Errorf("stale statement was not prepared again")
+	}
+}
+
+func Test_dialectDB_translate(test *testing.T) {
+	var invalidDialect dialect = *connected.handle.dialect
+	invalidDialect.stale = func(error) bool { return false }
+	invalidDialect.translate = func(err error) error { return &Error{ErrInvalid, err} }
+
+	var opened *sqlx.DB
+	var err error
+	if _, opened, err = openHandle(CONNECTION, Config{MaxOpenConns: 1}); err != nil {
+		test.Fatal(err)
+	}
+
+	var handle *dialectDB = &dialectDB{DB: opened, dialect: &invalidDialect}
+	defer handle.Close()
+
+	if handle.stmt(context.Background(), READ_SESSION_OF_TOKEN) == nil {
+		test.Fatal("READ_SESSION_OF_TOKEN was not prepared")
+	}
+
+	// Without its argument, so that each fails
+	var rows *sqlx.Rows
+	if rows, err = handle.QueryxContext(context.Background(), READ_SESSION_OF_TOKEN); !errors.Is(err, ErrInvalid) {
+		test.Errorf("Queryx error was not translated: %v", err)
+	}
+
+	if rows != nil {
+		rows.Close()
+	}
+
+	var owner string
+	if err = handle.QueryRowxContext(context.Background(), READ_SESSION_OF_TOKEN).Scan(&owner); !errors.Is(err, ErrInvalid) {
+		test.Errorf("QueryRowx error was not translated: %v", err)
+	}
+
+	var tx *dialectTx
+	if tx, err = handle.Beginx(); err != nil {
+		test.Fatal(err)
+	}
+
+	defer tx.Rollback()
+
+	if err = tx.QueryRowxContext(context.Background(), READ_SESSION_OF_TOKEN).Scan(&owner); !errors.Is(err, ErrInvalid) {
+		test.Errorf("QueryRowx error in a transaction was not translated: %v", err)
+	}
+}
+
+func Test_dialectDB_closed(test *testing.T) {
+	var ID string = uuid.New().String()
+
+	var token string
+	var err error
+	if token, _, err = CreateToken(ID); err != nil {
+		test.Fatal(err)
+	}
+
+	connected.handle.stmt(context.Background(), READ_SESSION_OF_TOKEN).Close()
+
+	var owner string
+	if owner, _, err = ReadTokenStat(token); err != nil {
+		test.Fatal(err)
+	}
+
+	if owner != ID {
+		test.Errorf("owner mismatch! have: %s, want: %s", owner, ID)
+	}
+
+	connected.handle.prepared.Delete(READ_SESSION_OF_TOKEN)
+}
+
+func Test_mariadbStale(test *testing.T) {
+	if !mariadbStale(&mysql.MySQLError{Number: 1243}) {
+		test.Errorf("unknown statement handler was not stale")
+	}
+
+	if mariadbStale(&mysql.MySQLError{Number: 1062}) {
+		test.Errorf("duplicate entry was stale")
+	}
+}
+
+func Test_postgresStale(test *testing.T) {
+	if !postgresStale(&pq.Error{Code: "0A000", Message: "cached plan must not change result type"}) {
+		test.Errorf("changed cached plan was not stale")
+	}
+
+	if postgresStale(&pq.Error{Code: "0A000", Message: "unsupported"}) {
+		test.Errorf("unsupported feature was stale")
+	}
+}
+
+func Benchmark_ReadTokenStat(bench *testing.B) {
+	var token string
+	var err error
+	if token, _, err = CreateToken(uuid.New().String()); err != nil {
+		bench.Fatal(err)
+	}
+
+	bench.ResetTimer()
+
+	var index int
+	for index = 0; index < bench.N; index++ {
+		if _, _, err = ReadTokenStat(token); err != nil {
+			bench.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_ReadTokenStat_unprepared(bench *testing.B) {
+	var token string
+	var err error
+	if token, _, err = CreateToken(uuid.New().String()); err != nil {
+		bench.Fatal(err)
+	}
+
+	var bytes []byte
+	if bytes, err = base64.URLEncoding.DecodeString(token); err != nil {
+		bench.Fatal(err)
+	}
+
+	var statement string = connected.handle.rewrite(READ_SESSION_OF_TOKEN)
+	var session types.Session
+
+	bench.ResetTimer()
+
+	var index int
+	for index = 0; index < bench.N; index++ {
+		if err = connected.handle.DB.QueryRowx(statement, hashToken(bytes)).StructScan(&session); err != nil {
+			bench.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	REPLICA_CHECK_INTERVAL = 5 * time.Second
+	REPLICA_CHECK_TIMEOUT  = time.Second
+	REPLICA_PIN_WINDOW     = 2 * time.Second
+)
+
+type readPrimaryKey struct{}
+type ownWritesKey struct{}
+
+/**
+ * When something was last written with a context from ReadOwnWrites, in unix nanoseconds
+ */
+type ownWrites struct {
+	written int64
+}
+
+/**
+ * Read replicas of the primary database, handed out round-robin to reads that may be a little stale
+ * Replicas are pinged every interval, and ones that fail are skipped until they answer again
+ * Reads with a context from ReadOwnWrites stay on the primary for `pin` after anything is written with it,
+ * so that the replicas can catch up
+ */
+type replicaSet struct {
+	handles []*dialectDB
+	healthy []int32
+	next    uint32
+	pin     time.Duration
+	stop    chan struct{}
+	stopped sync.Once
+}
+
+/**
+ * Make a SQLStore that writes to `primary`, and reads what may be a little stale from `replicas`
+ * The replicas are checked before this returns, and every REPLICA_CHECK_INTERVAL after until Close
+ * Reads go to the primary whenever every replica is down, and for REPLICA_PIN_WINDOW after every write
+ * with a context from ReadOwnWrites, so that the caller that wrote sees what it wrote
+ */
+func NewReplicatedSQLStore(primary *sqlx.DB, replicas ...*sqlx.DB) (store *SQLStore) {
+	store = NewSQLStore(primary)
+	store.watchReplicas(replicas, REPLICA_CHECK_INTERVAL, REPLICA_PIN_WINDOW)
+	return
+}
+
+/**
+ * Start routing reads to `replicas`, checking on them every `interval`,
+ * and keeping reads on the primary for `pin` after every write with a context from ReadOwnWrites
+ */
+func (store *SQLStore) watchReplicas(replicas []*sqlx.DB, interval, pin time.Duration) {
+	if len(replicas) == 0 {
+		return
+	}
+
+	var set *replicaSet = &replicaSet{
+		handles: make([]*dialectDB, len(replicas)),
+		healthy: make([]int32, len(replicas)),
+		pin:     pin,
+		stop:    make(chan struct{}),
+	}
+
+	var index int
+	var replica *sqlx.DB
+	for index, replica = range replicas {
+		set.handles[index] = newDialectDB(replica)
+	}
+
+	set.check()
+	go set.watch(interval)
+	store.replicas = set
+}
+
+/**
+ * Ping every replica, and mark each as healthy or not
+ */
+func (set *replicaSet) check() {
+	var index int
+	var handle *dialectDB
+	for index, handle = range set.handles {
+		var ctx context.Context
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), REPLICA_CHECK_TIMEOUT)
+
+		if handle.PingContext(ctx) == nil {
+			atomic.StoreInt32(&set.healthy[index], 1)
+		} else {
+			atomic.StoreInt32(&set.healthy[index], 0)
+		}
+
+		cancel()
+	}
+}
+
+/**
+ * Check the replicas every `interval`, until the set is closed
+ */
+func (set *replicaSet) watch(interval time.Duration) {
+	var ticker *time.Ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-set.stop:
+			return
+		case <-ticker.C:
+			set.check()
+		}
+	}
+}
+
+/**
+ * Get the next healthy replica, or nil if none are
+ */
+func (set *replicaSet) pick() (handle *dialectDB) {
+	var size uint32 = uint32(len(set.handles))
+	var start uint32 = atomic.AddUint32(&set.next, 1)
+
+	var offset uint32
+	for offset = 0; offset < size; offset++ {
+		var index uint32 = (start + offset) % size
+		if atomic.LoadInt32(&set.healthy[index]) == 1 {
+			handle = set.handles[index]
+			return
+		}
+	}
+
+	return
+}
+
+/**
+ * Stop checking on the replicas, and close them
+ */
+func (set *replicaSet) close() (err error) {
+	set.stopped.Do(func() { close(set.stop) })
+
+	var handle *dialectDB
+	for _, handle = range set.handles {
+		var closeErr error
+		if closeErr = handle.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return
+}
+
+/**
+ * Have reads made with `ctx` go to the primary rather than a replica,
+ * for reading back something that was just written before the replicas have caught up
+ */
+func ReadPrimary(ctx context.Context) (primary context.Context) {
+	primary = context.WithValue(ctx, readPrimaryKey{}, true)
+	return
+}
+
+/**
+ * Have reads made with `ctx` go to the primary for the pin window after anything is written with it,
+ * so that some caller, such as a request or a session, reads back what it wrote
+ * Reads with other contexts aren't held to the primary by those writes
+ */
+func ReadOwnWrites(ctx context.Context) (own context.Context) {
+	own = context.WithValue(ctx, ownWritesKey{}, &ownWrites{})
+	return
+}
+
+/**
+ * Remember that something was just written with `ctx`, if it's from ReadOwnWrites
+ */
+func wroteWith(ctx context.Context) {
+	var own *ownWrites
+	if own, _ = ctx.Value(ownWritesKey{}).(*ownWrites); own != nil {
+		atomic.StoreInt64(&own.written, time.Now().UnixNano())
+	}
+}
+
+/**
+ * Check whether anything was written with `ctx` within the last `window`, if it's from ReadOwnWrites
+ */
+func wroteWithin(ctx context.Context, window time.Duration) (within bool) {
+	var own *ownWrites
+	if own, _ = ctx.Value(ownWritesKey{}).(*ownWrites); own != nil {
+		within = time.Since(time.Unix(0, atomic.LoadInt64(&own.written))) < window
+	}
+
+	return
+}
+
+/**
+ * Get a store that does what may be a little stale on a replica, if there's a healthy one to use
+ * Transactions, reads with a context from ReadPrimary, and reads with a context from ReadOwnWrites
+ * within the pin window of the last write with it always stay on the primary
+ * Only reads of what others have written are routed to replicas, namely content, tags, feeds,
+ * comments, subscriptions, and users by id or nick; auth, privileges, and the caller's own votes,
+ * subscriptions and repubs are always read from the primary
+ */
+func (store *SQLStore) reader(ctx context.Context) (reading *SQLStore) {
+	reading = store
+	if store.tx != nil || store.replicas == nil || ctx.Value(readPrimaryKey{}) != nil {
+		return
+	}
+
+	if wroteWithin(ctx, store.replicas.pin) {
+		return
+	}
+
+	var handle *dialectDB
+	if handle = store.replicas.pick(); handle != nil {
+		reading = &SQLStore{
+			handle:      handle,
+			views:       store.views,
+			lifetimes:   store.lifetimes,
+			revocations: store.revocations,
+		}
+	}
+
+	return
+}
+
+/**
+ * Close the database, and any replicas of it
+ */
+func (store *SQLStore) Close() (err error) {
+	if store.replicas != nil {
+		err = store.replicas.close()
+	}
+
+	var closeErr error
+	if closeErr = store.handle.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	return
+}
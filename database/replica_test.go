@@ -0,0 +1,187 @@
+package database
+
+import (
+	"github.com/google/uuid"
+
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func replicaWriteContent(test *testing.T, ctx context.Context, store *SQLStore) (ID string) {
+	ID = uuid.New().String()
+
+	var err error
+	if err = store.WriteContentContext(ctx, mapMod(writableContent, map[string]interface{}{"id": ID})); err != nil {
+		test.Fatal(err)
+	}
+
+	return
+}
+
+func replicaExists(test *testing.T, ctx context.Context, store *SQLStore, ID string) (exists bool) {
+	var err error
+	if _, exists, err = store.ReadSingleContentContext(ctx, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	return
+}
+
+func Test_NewReplicatedSQLStore(test *testing.T) {
+	var primary, replica *SQLStore = freshSQLite(test), freshSQLite(test)
+	var store *SQLStore = NewReplicatedSQLStore(primary.handle.DB, replica.handle.DB)
+	defer store.replicas.close()
+
+	var own context.Context = ReadOwnWrites(context.Background())
+	var written string = replicaWriteContent(test, own, store)
+	var replicated string = replicaWriteContent(test, context.Background(), replica)
+
+	if !replicaExists(test, own, store, written) {
+		test.Errorf("read of %s right after writing it didn't go to the primary", written)
+	}
+
+	if replicaExists(test, context.Background(), store, written) {
+		test.Errorf("read of %s by another caller went to the primary", written)
+	}
+
+	if replicaExists(test, ReadOwnWrites(context.Background()), store, written) {
+		test.Errorf("read of %s by another caller reading its own writes went to the primary", written)
+	}
+
+	if !replicaExists(test, context.Background(), store, replicated) {
+		test.Errorf("read of %s didn't go to the replica", replicated)
+	}
+
+	if !replicaExists(test, ReadPrimary(context.Background()), store, written) {
+		test.Errorf("read of %s with ReadPrimary didn't go to the primary", written)
+	}
+
+	var err error
+	err = store.WithTx(context.Background(), func(tx Store) (err error) {
+		var exists bool
+		if _, exists, err = tx.ReadSingleContentContext(context.Background(), written); err == nil && !exists {
+			test.Errorf("read of %s inside of a transaction didn't go to the primary", written)
+		}
+
+		return
+	})
+
+	if err != nil {
+		test.Fatal(err)
+	}
+}
+
+func Test_NewReplicatedSQLStore_roundRobin(test *testing.T) {
+	var primary, first, second *SQLStore = freshSQLite(test), freshSQLite(test), freshSQLite(test)
+	var store *SQLStore = NewReplicatedSQLStore(primary.handle.DB, first.handle.DB, second.handle.DB)
+	defer store.replicas.close()
+
+	var ID string = replicaWriteContent(test, context.Background(), first)
+
+	var found, count int
+	for count = 0; count < 4; count++ {
+		if replicaExists(test, context.Background(), store, ID) {
+			found++
+		}
+	}
+
+	if found != 2 {
+		test.Errorf("%s found in %d of 4 reads, not every other", ID, found)
+	}
+}
+
+func Test_NewReplicatedSQLStore_down(test *testing.T) {
+	var primary, replica *SQLStore = freshSQLite(test), freshSQLite(test)
+	var store *SQLStore = NewReplicatedSQLStore(primary.handle.DB, replica.handle.DB)
+	defer store.replicas.close()
+
+	var ID string = replicaWriteContent(test, context.Background(), store)
+
+	replica.handle.Close()
+	store.replicas.check()
+
+	if !replicaExists(test, context.Background(), store, ID) {
+		test.Errorf("read of %s didn't fall back to the primary with its replica down", ID)
+	}
+}
+
+func Test_Open_replicas(test *testing.T) {
+	var directory string = test.TempDir()
+	var primaryAddress string = "sqlite://" + filepath.Join(directory, "primary.db")
+	var replicaAddress string = "sqlite://" + filepath.Join(directory, "replica.db")
+
+	var replica *SQLStore
+	var err error
+	if replica, err = Open(Config{Address: replicaAddress, Migrate: true}); err != nil {
+		test.Fatal(err)
+	}
+
+	defer replica.Close()
+
+	var store *SQLStore
+	if store, err = Open(Config{Address: primaryAddress, Migrate: true, Replicas: []string{replicaAddress}, ReplicaCheckInterval: time.Hour}); err != nil {
+		test.Fatal(err)
+	}
+
+	defer store.Close()
+
+	var ID string = replicaWriteContent(test, context.Background(), replica)
+	if !replicaExists(test, context.Background(), store, ID) {
+		test.Errorf("read of %s didn't go to the replica", ID)
+	}
+}
+
+func Test_Open_replicaPinWindow(test *testing.T) {
+	var directory string = test.TempDir()
+	var primaryAddress string = "sqlite://" + filepath.Join(directory, "primary.db")
+	var replicaAddress string = "sqlite://" + filepath.Join(directory, "replica.db")
+
+	var replica *SQLStore
+	var err error
+	if replica, err = Open(Config{Address: replicaAddress, Migrate: true}); err != nil {
+		test.Fatal(err)
+	}
+
+	defer replica.Close()
+
+	var store *SQLStore
+	if store, err = Open(Config{Address: primaryAddress, Migrate: true, Replicas: []string{replicaAddress}, ReplicaPinWindow: 50 * time.Millisecond}); err != nil {
+		test.Fatal(err)
+	}
+
+	defer store.Close()
+
+	var own context.Context = ReadOwnWrites(context.Background())
+	var ID string = replicaWriteContent(test, own, store)
+	if !replicaExists(test, own, store, ID) {
+		test.Errorf("read of %s right after writing it didn't go to the primary", ID)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if replicaExists(test, own, store, ID) {
+		test.Errorf("read of %s after the pin window didn't go to the replica", ID)
+	}
+
+	err = store.WithTx(own, func(tx Store) (err error) {
+		err = tx.WriteContentContext(own, mapMod(writableContent, map[string]interface{}{"id": ID + "-tx"}))
+		return
+	})
+
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	if !replicaExists(test, own, store, ID+"-tx") {
+		test.Errorf("read of %s right after writing it in a transaction didn't go to the primary", ID+"-tx")
+	}
+}
+
+func Test_Open_replicasMismatch(test *testing.T) {
+	var err error
+	if _, err = Open(Config{Address: "sqlite://:memory:", Replicas: []string{"root@tcp(127.0.0.1:1)/brane"}}); err == nil {
+		test.Errorf("opened with a replica of a different database")
+	}
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+
+	"context"
+	"database/sql"
+)
+
+/**
+ * Repub some content of id `ID` as some user of id `author`
+ * Repubbing the same content twice is a no-op
+ * The repub is placed in feeds after the newest content, by the order_index of that content
+ * Fails with ErrNotFound if the content doesn't exist, as nothing was counted
+ * Done in one transaction of up to 2 queries
+ * 		write repub: 	INSERT IGNORE INTO REPUB_TABLE (id, content, author, created, feed_index) VALUES (..., (SELECT MAX(order_index) FROM CONTENT_TABLE))
+ * 		count repub: 	UPDATE CONTENT_TABLE SET repub_count=repub_count+1 WHERE id=ID
+ */
+func (store *SQLStore) RepubContext(ctx context.Context, author, ID string) (err error) {
+	var repub types.Repub = types.NewRepub(author, ID)
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		var result sql.Result
+		if result, err = tx.ExecContext(ctx, WRITE_REPUB, repub.ID, repub.Content, repub.Author, repub.Created); err != nil {
+			return
+		}
+
+		var affected int64
+		if affected, err = result.RowsAffected(); err != nil || affected == 0 {
+			return
+		}
+
+		if result, err = tx.ExecContext(ctx, INCREMENT_CONTENT_REPUB_COUNT_OF_ID, ID); err != nil {
+			return
+		}
+
+		if affected, err = result.RowsAffected(); err == nil && affected == 0 {
+			err = errorOf(ErrNotFound, "Content %s does not exist", ID)
+		}
+
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) Repub(author, ID string) (err error) {
+	err = store.RepubContext(context.Background(), author, ID)
+	return
+}
+
+/**
+ * Undo the repub of some content of id `ID` by some user of id `author`
+ * Unrepubbing content that was not repubbed is a no-op
+ * Done in one transaction of up to 2 queries
+ * 		delete repub: 	DELETE FROM REPUB_TABLE WHERE author=author AND content=ID
+ * 		uncount repub: 	UPDATE CONTENT_TABLE SET repub_count=repub_count-1 WHERE id=ID
+ */
+func (store *SQLStore) UnrepubContext(ctx context.Context, author, ID string) (err error) {
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		var result sql.Result
+		if result, err = tx.ExecContext(ctx, DELETE_REPUB, author, ID); err != nil {
+			return
+		}
+
+		var affected int64
+		if affected, err = result.RowsAffected(); err != nil || affected == 0 {
+			return
+		}
+
+		_, err = tx.ExecContext(ctx, DECREMENT_CONTENT_REPUB_COUNT_OF_ID, ID)
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) Unrepub(author, ID string) (err error) {
+	err = store.UnrepubContext(context.Background(), author, ID)
+	return
+}
+
+/**
+ * Get whether or not some user of id `author` has repubbed content of id `ID`
+ * Done in one query
+ */
+func (store *SQLStore) IsRepubbedContext(ctx context.Context, author, ID string) (repubbed bool, err error) {
+	var count int
+	if err = store.db().QueryRowxContext(ctx, READ_REPUB_COUNT, author, ID).Scan(&count); err != nil {
+		return
+	}
+
+	repubbed = count != 0
+	return
+}
+
+func (store *SQLStore) IsRepubbed(author, ID string) (repubbed bool, err error) {
+	repubbed, err = store.IsRepubbedContext(context.Background(), author, ID)
+	return
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	SQLITE_ORDER_INDEX   = "INTEGER PRIMARY KEY AUTOINCREMENT"
+	SQLITE_TABLE_EXISTS  = "SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?"
+	SQLITE_COLUMN_EXISTS = "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name=?"
+)
+
+var (
+	sqliteDialect *dialect = &dialect{
+		driver:    "sqlite3",
+		dsn:       sqliteDSN,
+		statement: sqliteStatement,
+		table:     sqliteTable,
+		prepare:   sqlitePrepare,
+		configure: sqliteConfigure,
+		translate: sqliteTranslate,
+		// statements of SQLite are prepared again by SQLite itself when the schema changes
+		stale:        func(error) bool { return false },
+		tableExists:  SQLITE_TABLE_EXISTS,
+		columnExists: SQLITE_COLUMN_EXISTS,
+	}
+
+	sqliteStatements *strings.Replacer = strings.NewReplacer(
+		"INSERT IGNORE INTO", "INSERT OR IGNORE INTO",
+		" FOR UPDATE", "",
+	)
+)
+
+/**
+ * Make a go-sqlite3 data source out of the path in a sqlite:// address
+ * Foreign keys are turned on, as tags rely on them to cascade
+ */
+func sqliteDSN(address string) (dsn string) {
+	dsn = "file:" + address
+
+	if strings.Contains(address, "?") {
+		dsn += "&_foreign_keys=1"
+	} else {
+		dsn += "?_foreign_keys=1"
+	}
+
+	return
+}
+
+/**
+ * Rewrite a MariaDB statement for SQLite
+ * SQLite has INSERT OR IGNORE in place of INSERT IGNORE, and no row locks or LIMIT on DELETE
+ */
+func sqliteStatement(statement string) (rewritten string) {
+	rewritten = sqliteStatements.Replace(statement)
+
+	if strings.HasPrefix(rewritten, "DELETE ") {
+		rewritten = strings.TrimSuffix(rewritten, " LIMIT 1")
+	}
+
+	return
+}
+
+/**
+ * Rewrite a MariaDB table definition for SQLite
+ * SQLite only auto increments an INTEGER PRIMARY KEY, so order_index becomes the primary key
+ * and the id that was the primary key is left unique
+ */
+func sqliteTable(definition string) (rewritten string) {
+	rewritten = strings.Replace(definition, "BIGINT UNSIGNED UNIQUE NOT NULL AUTO_INCREMENT", SQLITE_ORDER_INDEX, 1)
+
+	if rewritten != definition {
+		rewritten = strings.Replace(rewritten, "UNIQUE PRIMARY KEY", "UNIQUE", 1)
+	}
+
+	return
+}
+
+/**
+ * SQLite allows one writer at a time, and an in-memory database lives only as long as its connection,
+ * so everything goes through a single connection
+ */
+func sqlitePrepare(handle *sqlx.DB) {
+	handle.SetMaxOpenConns(1)
+}
+
+/**
+ * SQLite is a local file, so it has no TLS, and reads never wait on the network
+ * It's kept to one open connection by sqlitePrepare, so asking for more is an error rather than being ignored
+ */
+func sqliteConfigure(dsn string, config Config) (configured string, err error) {
+	if config.TLS != nil {
+		err = fmt.Errorf("TLS can't be used with SQLite")
+		return
+	}
+
+	if config.MaxOpenConns > 1 {
+		err = fmt.Errorf("SQLite is kept to one open connection, not %d", config.MaxOpenConns)
+		return
+	}
+
+	configured = dsn
+	return
+}
+
+/**
+ * Translate a go-sqlite3 error by its extended code
+ * Columns that don't exist are a plain SQLITE_ERROR, so they're found by message
+ */
+func sqliteTranslate(err error) (translated error) {
+	translated = err
+
+	var driverErr sqlite3.Error
+	if !errors.As(err, &driverErr) {
+		return
+	}
+
+	switch {
+	case driverErr.ExtendedCode == sqlite3.ErrConstraintUnique || driverErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+		translated = &Error{duplicateOf(driverErr.Error()), err}
+	case driverErr.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+		translated = &Error{ErrNotFound, err}
+	case driverErr.ExtendedCode == sqlite3.ErrConstraintNotNull || driverErr.ExtendedCode == sqlite3.ErrConstraintCheck:
+		translated = &Error{ErrInvalid, err}
+	case driverErr.Code == sqlite3.ErrError && strings.Contains(driverErr.Error(), "no column named"):
+		translated = &Error{ErrInvalid, err}
+	}
+
+	return
+}
@@ -44,24 +44,124 @@ reason,
 created,
 resolved,
 resolution`
+	COMMENT_FIELDS = `
+id,
+content,
+author,
+parent,
+body,
+created,
+edited,
+deleted`
+	SUBSCRIPTION_FIELDS = `
+subscriber,
+subscription,
+created`
+	SESSION_FIELDS = `
+id,
+owner,
+device,
+ip,
+created,
+last_used,
+issued`
+	APIKEY_FIELDS = `
+id,
+owner,
+name,
+scopes,
+created,
+expires,
+last_used`
+
+	// Columns that may be given to a patch, the rest being kept by their own functions
+	CONTENT_PATCHABLE_FIELDS = "file_url, mime, featured, featurable, removed, nsfw"
+	USER_PATCHABLE_FIELDS    = "email, nick, bio"
 
-	READ_INDEX_OF_CONTENT                = "SELECT order_index FROM " + CONTENT_TABLE + " WHERE id=? LIMIT 1"
-	READ_CONTENT_ID                      = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE id=? LIMIT 1"
-	READ_MANY_CONTENT_AFTER_ID           = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE order_index<(" + READ_INDEX_OF_CONTENT + ") ORDER BY order_index DESC LIMIT ?"
-	READ_MANY_CONTENT                    = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " ORDER BY order_index DESC LIMIT ?"
-	READ_MANY_CONTENT_OF_AUTHOR          = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE author=? ORDER BY order_index DESC LIMIT ?"
-	READ_MANY_CONTENT_OF_AUTHOR_AFTER_ID = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE author=? AND order_index<(" + READ_INDEX_OF_CONTENT + ") ORDER BY order_index DESC LIMIT ?"
-	DELETE_CONTENT_ID                    = "DELETE FROM " + CONTENT_TABLE + " WHERE id=? LIMIT 1"
+	READ_INDEX_OF_CONTENT                       = "SELECT order_index FROM " + CONTENT_TABLE + " WHERE id=? LIMIT 1"
+	READ_CONTENT_ID                             = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE id=? LIMIT 1"
+	READ_MANY_CONTENT_AFTER_ID                  = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE order_index<(" + READ_INDEX_OF_CONTENT + ") ORDER BY order_index DESC LIMIT ?"
+	READ_MANY_CONTENT                           = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " ORDER BY order_index DESC LIMIT ?"
+	READ_MANY_CONTENT_OF_AUTHOR                 = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE author=? ORDER BY order_index DESC LIMIT ?"
+	READ_MANY_CONTENT_OF_AUTHOR_AFTER_ID        = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE author=? AND order_index<(" + READ_INDEX_OF_CONTENT + ") ORDER BY order_index DESC LIMIT ?"
+	READ_MANY_CONTENT_OF_SUBSCRIPTIONS          = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE author IN (SELECT subscription FROM " + SUBSCRIPTION_TABLE + " WHERE subscriber=?) AND removed IS NOT TRUE ORDER BY order_index DESC LIMIT ?"
+	READ_MANY_CONTENT_OF_SUBSCRIPTIONS_AFTER_ID = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE author IN (SELECT subscription FROM " + SUBSCRIPTION_TABLE + " WHERE subscriber=?) AND removed IS NOT TRUE AND order_index<(" + READ_INDEX_OF_CONTENT + ") ORDER BY order_index DESC LIMIT ?"
+	READ_CONTENT_OF_MANY_ID                     = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE id IN "
+	INCREMENT_CONTENT_VIEW_COUNT_OF_ID          = "UPDATE " + CONTENT_TABLE + " SET view_count=view_count+? WHERE id=?"
+	DELETE_CONTENT_ID                           = "DELETE FROM " + CONTENT_TABLE + " WHERE id=? LIMIT 1"
+	WRITE_CONTENT                               = "INSERT INTO " + CONTENT_TABLE + " (" + CONTENT_FIELDS + ") VALUES (?, ?, ?, ?, 0, 0, 0, 0, 0, ?, ?, ?, ?, ?)"
 
-	READ_TAGS_OF_ID       = "SELECT tag FROM " + TAG_TABLE + " WHERE id=?"
-	READ_TAGS_OF_MANY_ID  = "SELECT id, tag FROM " + TAG_TABLE + " WHERE id IN "
-	WRITE_TAGS_OF_MANY_ID = "REPLACE INTO " + TAG_TABLE + " (id, tag, created) VALUES "
-	DELETE_TAGS_OF_ID     = "DELETE FROM " + TAG_TABLE + " WHERE id=?"
+	READ_TAGS_OF_ID          = "SELECT tag FROM " + TAG_TABLE + " WHERE id=?"
+	READ_TAGS_OF_MANY_ID     = "SELECT id, tag FROM " + TAG_TABLE + " WHERE id IN "
+	WRITE_TAGS_OF_MANY_ID    = "INSERT IGNORE INTO " + TAG_TABLE + " (id, tag, created) VALUES "
+	DELETE_TAGS_OF_ID        = "DELETE FROM " + TAG_TABLE + " WHERE id=?"
+	DELETE_TAGS_OF_ID_EXCEPT = "DELETE FROM " + TAG_TABLE + " WHERE id=? AND tag NOT IN "
+
+	READ_MANY_LIVE_CONTENT  = "SELECT " + CONTENT_FIELDS + " FROM " + CONTENT_TABLE + " WHERE removed IS NOT TRUE"
+	FILTER_TAGGED_ALL       = " AND id IN (SELECT id FROM " + TAG_TABLE + " WHERE tag IN "
+	FILTER_TAGGED_ALL_COUNT = " GROUP BY id HAVING COUNT(DISTINCT tag)=?)"
+	FILTER_TAGGED_ANY       = " AND id IN (SELECT id FROM " + TAG_TABLE + " WHERE tag IN "
+	FILTER_TAGGED_NONE      = " AND id NOT IN (SELECT id FROM " + TAG_TABLE + " WHERE tag IN "
+	FILTER_CONTENT_AFTER_ID = " AND order_index<(" + READ_INDEX_OF_CONTENT + ")"
+	ORDER_CONTENT_LIMIT     = " ORDER BY order_index DESC LIMIT ?"
+	READ_POPULAR_TAGS_SINCE = "SELECT " + TAG_TABLE + ".tag AS tag, COUNT(*) AS count FROM " + TAG_TABLE + " JOIN " + CONTENT_TABLE + " ON " + CONTENT_TABLE + ".id=" + TAG_TABLE + ".id WHERE " + TAG_TABLE + ".created>=? AND " + CONTENT_TABLE + ".removed IS NOT TRUE GROUP BY " + TAG_TABLE + ".tag ORDER BY count DESC, tag ASC LIMIT ?"
+
+	READ_VOTE_OF_CONTENT                  = "SELECT value FROM " + VOTE_TABLE + " WHERE voter=? AND content=? LIMIT 1"
+	READ_VOTE_OF_CONTENT_FOR_UPDATE       = READ_VOTE_OF_CONTENT + " FOR UPDATE"
+	READ_VOTES_OF_MANY_CONTENT            = "SELECT content, value FROM " + VOTE_TABLE + " WHERE voter=? AND content IN "
+	WRITE_VOTE                            = "INSERT INTO " + VOTE_TABLE + " (voter, content, value, created) VALUES (?, ?, ?, ?)"
+	UPDATE_VOTE                           = "UPDATE " + VOTE_TABLE + " SET value=?, created=? WHERE voter=? AND content=?"
+	DELETE_VOTE                           = "DELETE FROM " + VOTE_TABLE + " WHERE voter=? AND content=?"
+	DELETE_VOTES_OF_CONTENT               = "DELETE FROM " + VOTE_TABLE + " WHERE content=?"
+	COUNT_CONTENT_VOTES_OF_ID             = "UPDATE " + CONTENT_TABLE + " SET like_count=(SELECT COUNT(*) FROM " + VOTE_TABLE + " WHERE content=? AND value=1), dislike_count=(SELECT COUNT(*) FROM " + VOTE_TABLE + " WHERE content=? AND value=-1) WHERE id=?"
+	INCREMENT_CONTENT_LIKE_COUNT_OF_ID    = "UPDATE " + CONTENT_TABLE + " SET like_count=like_count+1 WHERE id=?"
+	DECREMENT_CONTENT_LIKE_COUNT_OF_ID    = "UPDATE " + CONTENT_TABLE + " SET like_count=like_count-1 WHERE id=? AND like_count>0"
+	INCREMENT_CONTENT_DISLIKE_COUNT_OF_ID = "UPDATE " + CONTENT_TABLE + " SET dislike_count=dislike_count+1 WHERE id=?"
+	DECREMENT_CONTENT_DISLIKE_COUNT_OF_ID = "UPDATE " + CONTENT_TABLE + " SET dislike_count=dislike_count-1 WHERE id=? AND dislike_count>0"
+
+	SUBSCRIPTIONS_OF_ID                 = "SELECT subscription FROM " + SUBSCRIPTION_TABLE + " WHERE subscriber=?"
+	FEED_ORIGINALS_OF_AUTHOR            = "SELECT id AS entry, id AS original, '' AS reposter, created, order_index AS feed_index, 0 AS repub_index FROM " + CONTENT_TABLE + " WHERE author=? AND removed IS NOT TRUE"
+	FEED_REPUBS_OF_AUTHOR               = "SELECT " + REPUB_TABLE + ".id AS entry, " + REPUB_TABLE + ".content AS original, " + REPUB_TABLE + ".author AS reposter, " + REPUB_TABLE + ".created AS created, " + REPUB_TABLE + ".feed_index AS feed_index, " + REPUB_TABLE + ".order_index AS repub_index FROM " + REPUB_TABLE + " JOIN " + CONTENT_TABLE + " ON " + CONTENT_TABLE + ".id=" + REPUB_TABLE + ".content WHERE " + REPUB_TABLE + ".author=? AND " + CONTENT_TABLE + ".removed IS NOT TRUE"
+	FEED_ORIGINALS_OF_SUBSCRIPTIONS     = "SELECT id AS entry, id AS original, '' AS reposter, created, order_index AS feed_index, 0 AS repub_index FROM " + CONTENT_TABLE + " WHERE author IN (" + SUBSCRIPTIONS_OF_ID + ") AND removed IS NOT TRUE"
+	FEED_REPUBS_OF_SUBSCRIPTIONS        = "SELECT " + REPUB_TABLE + ".id AS entry, " + REPUB_TABLE + ".content AS original, " + REPUB_TABLE + ".author AS reposter, " + REPUB_TABLE + ".created AS created, " + REPUB_TABLE + ".feed_index AS feed_index, " + REPUB_TABLE + ".order_index AS repub_index FROM " + REPUB_TABLE + " JOIN " + CONTENT_TABLE + " ON " + CONTENT_TABLE + ".id=" + REPUB_TABLE + ".content WHERE " + REPUB_TABLE + ".author IN (" + SUBSCRIPTIONS_OF_ID + ") AND " + CONTENT_TABLE + ".removed IS NOT TRUE"
+	FEED_SELECT                         = "SELECT entry, original, reposter, created FROM "
+	FEED_ORIGINALS_BEFORE               = " AND order_index<?"
+	FEED_REPUBS_BEFORE                  = " AND (" + REPUB_TABLE + ".feed_index<? OR (" + REPUB_TABLE + ".feed_index=? AND " + REPUB_TABLE + ".order_index<?))"
+	FEED_ORDER                          = " ORDER BY feed_index DESC, repub_index DESC LIMIT ?"
+	READ_INDEX_OF_FEED_ENTRY            = "SELECT order_index AS feed_index, 0 AS repub_index FROM " + CONTENT_TABLE + " WHERE id=? UNION ALL SELECT feed_index, order_index AS repub_index FROM " + REPUB_TABLE + " WHERE id=?"
+	READ_FEED_OF_AUTHOR                 = FEED_SELECT + "(" + FEED_ORIGINALS_OF_AUTHOR + " UNION ALL " + FEED_REPUBS_OF_AUTHOR + ") AS feed" + FEED_ORDER
+	READ_FEED_OF_AUTHOR_AFTER_ID        = FEED_SELECT + "(" + FEED_ORIGINALS_OF_AUTHOR + FEED_ORIGINALS_BEFORE + " UNION ALL " + FEED_REPUBS_OF_AUTHOR + FEED_REPUBS_BEFORE + ") AS feed" + FEED_ORDER
+	READ_FEED_OF_SUBSCRIPTIONS          = FEED_SELECT + "(" + FEED_ORIGINALS_OF_SUBSCRIPTIONS + " UNION ALL " + FEED_REPUBS_OF_SUBSCRIPTIONS + ") AS feed" + FEED_ORDER
+	READ_FEED_OF_SUBSCRIPTIONS_AFTER_ID = FEED_SELECT + "(" + FEED_ORIGINALS_OF_SUBSCRIPTIONS + FEED_ORIGINALS_BEFORE + " UNION ALL " + FEED_REPUBS_OF_SUBSCRIPTIONS + FEED_REPUBS_BEFORE + ") AS feed" + FEED_ORDER
+
+	WRITE_REPUB                         = "INSERT IGNORE INTO " + REPUB_TABLE + " (id, content, author, created, feed_index) VALUES (?, ?, ?, ?, (SELECT COALESCE(MAX(order_index), 0) FROM " + CONTENT_TABLE + "))"
+	DELETE_REPUB                        = "DELETE FROM " + REPUB_TABLE + " WHERE author=? AND content=?"
+	DELETE_REPUBS_OF_CONTENT            = "DELETE FROM " + REPUB_TABLE + " WHERE content=?"
+	READ_REPUB_COUNT                    = "SELECT COUNT(*) FROM " + REPUB_TABLE + " WHERE author=? AND content=? LIMIT 1"
+	INCREMENT_CONTENT_REPUB_COUNT_OF_ID = "UPDATE " + CONTENT_TABLE + " SET repub_count=repub_count+1 WHERE id=?"
+	DECREMENT_CONTENT_REPUB_COUNT_OF_ID = "UPDATE " + CONTENT_TABLE + " SET repub_count=repub_count-1 WHERE id=? AND repub_count>0"
+
+	READ_INDEX_OF_COMMENT                 = "SELECT order_index FROM " + COMMENT_TABLE + " WHERE id=? LIMIT 1"
+	READ_COMMENT_OF_ID                    = "SELECT " + COMMENT_FIELDS + " FROM " + COMMENT_TABLE + " WHERE id=? LIMIT 1"
+	READ_CONTENT_OF_COMMENT               = "SELECT content FROM " + COMMENT_TABLE + " WHERE id=? LIMIT 1"
+	READ_CONTENT_OF_LIVE_COMMENT          = "SELECT content FROM " + COMMENT_TABLE + " WHERE id=? AND NOT deleted LIMIT 1 FOR UPDATE"
+	READ_COMMENTS_OF_CONTENT              = "SELECT " + COMMENT_FIELDS + " FROM " + COMMENT_TABLE + " WHERE content=? AND parent='' ORDER BY order_index DESC LIMIT ?"
+	READ_COMMENTS_OF_CONTENT_AFTER_ID     = "SELECT " + COMMENT_FIELDS + " FROM " + COMMENT_TABLE + " WHERE content=? AND parent='' AND order_index<(" + READ_INDEX_OF_COMMENT + ") ORDER BY order_index DESC LIMIT ?"
+	READ_REPLIES_OF_COMMENT               = "SELECT " + COMMENT_FIELDS + " FROM " + COMMENT_TABLE + " WHERE parent=? ORDER BY order_index DESC LIMIT ?"
+	READ_REPLIES_OF_COMMENT_AFTER_ID      = "SELECT " + COMMENT_FIELDS + " FROM " + COMMENT_TABLE + " WHERE parent=? AND order_index<(" + READ_INDEX_OF_COMMENT + ") ORDER BY order_index DESC LIMIT ?"
+	WRITE_COMMENT                         = "INSERT INTO " + COMMENT_TABLE + " (" + COMMENT_FIELDS + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	WRITE_COMMENT_BODY_OF_ID              = "UPDATE " + COMMENT_TABLE + " SET body=?, edited=? WHERE id=? AND NOT deleted"
+	DELETE_COMMENT_OF_ID                  = "UPDATE " + COMMENT_TABLE + " SET body='', deleted=TRUE WHERE id=?"
+	DELETE_COMMENTS_OF_CONTENT            = "DELETE FROM " + COMMENT_TABLE + " WHERE content=?"
+	INCREMENT_CONTENT_COMMENT_COUNT_OF_ID = "UPDATE " + CONTENT_TABLE + " SET comment_count=comment_count+1 WHERE id=?"
+	DECREMENT_CONTENT_COMMENT_COUNT_OF_ID = "UPDATE " + CONTENT_TABLE + " SET comment_count=comment_count-1 WHERE id=? AND comment_count>0"
 
 	READ_USER_OF_ID                 = "SELECT " + USER_FIELDS + " FROM " + USER_TABLE + " WHERE id=? LIMIT 1"
 	READ_USER_OF_EMAIL              = "SELECT " + USER_FIELDS + " FROM " + USER_TABLE + " WHERE email=? LIMIT 1"
 	READ_USER_OF_NICK               = "SELECT " + USER_FIELDS + " FROM " + USER_TABLE + " WHERE nick=? LIMIT 1"
+	READ_USER_CONFLICTS             = "SELECT nick, email FROM " + USER_TABLE + " WHERE (nick=? OR email=?) AND id<>? FOR UPDATE"
 	DELETE_USER_OF_ID               = "DELETE FROM " + USER_TABLE + " WHERE id=? LIMIT 1"
+	WRITE_USER                      = "INSERT INTO " + USER_TABLE + " (" + USER_FIELDS + ") VALUES (?, ?, ?, ?, 0, 0, 0, ?, FALSE, FALSE)"
 	INCREMENT_USER_POST_COUNT_OF_ID = "UPDATE " + USER_TABLE + " SET post_count=post_count+1 WHERE id=?"
 	READ_ANY_PRIVILEGE_OF_ID        = "SELECT admin, moderator FROM " + USER_TABLE + " WHERE id=?"
 	READ_MODERATOR_OF_ID            = "SELECT moderator FROM " + USER_TABLE + " WHERE id=?"
@@ -69,26 +169,198 @@ resolution`
 	WRITE_MODERATOR_OF_ID           = "UPDATE " + USER_TABLE + " SET moderator=? WHERE id=?"
 	WRITE_ADMIN_OF_ID               = "UPDATE " + USER_TABLE + " SET admin=? WHERE id=?"
 
+	INCREMENT_USER_SUBSCRIBER_COUNT_OF_ID   = "UPDATE " + USER_TABLE + " SET subscriber_count=subscriber_count+1 WHERE id=?"
+	DECREMENT_USER_SUBSCRIBER_COUNT_OF_ID   = "UPDATE " + USER_TABLE + " SET subscriber_count=subscriber_count-1 WHERE id=? AND subscriber_count>0"
+	INCREMENT_USER_SUBSCRIPTION_COUNT_OF_ID = "UPDATE " + USER_TABLE + " SET subscription_count=subscription_count+1 WHERE id=?"
+	DECREMENT_USER_SUBSCRIPTION_COUNT_OF_ID = "UPDATE " + USER_TABLE + " SET subscription_count=subscription_count-1 WHERE id=? AND subscription_count>0"
+
+	READ_INDEX_OF_SUBSCRIPTION        = "SELECT order_index FROM " + SUBSCRIPTION_TABLE + " WHERE subscriber=? AND subscription=? LIMIT 1"
+	READ_SUBSCRIPTION_COUNT           = "SELECT COUNT(*) FROM " + SUBSCRIPTION_TABLE + " WHERE subscriber=? AND subscription=? LIMIT 1"
+	READ_SUBSCRIBERS_OF_ID            = "SELECT " + SUBSCRIPTION_FIELDS + " FROM " + SUBSCRIPTION_TABLE + " WHERE subscription=? ORDER BY order_index DESC LIMIT ?"
+	READ_SUBSCRIBERS_OF_ID_AFTER_ID   = "SELECT " + SUBSCRIPTION_FIELDS + " FROM " + SUBSCRIPTION_TABLE + " WHERE subscription=? AND order_index<(" + READ_INDEX_OF_SUBSCRIPTION + ") ORDER BY order_index DESC LIMIT ?"
+	READ_SUBSCRIPTIONS_OF_ID          = "SELECT " + SUBSCRIPTION_FIELDS + " FROM " + SUBSCRIPTION_TABLE + " WHERE subscriber=? ORDER BY order_index DESC LIMIT ?"
+	READ_SUBSCRIPTIONS_OF_ID_AFTER_ID = "SELECT " + SUBSCRIPTION_FIELDS + " FROM " + SUBSCRIPTION_TABLE + " WHERE subscriber=? AND order_index<(" + READ_INDEX_OF_SUBSCRIPTION + ") ORDER BY order_index DESC LIMIT ?"
+	WRITE_SUBSCRIPTION                = "INSERT IGNORE INTO " + SUBSCRIPTION_TABLE + " (subscriber, subscription, created) VALUES (?, ?, ?)"
+	DELETE_SUBSCRIPTION               = "DELETE FROM " + SUBSCRIPTION_TABLE + " WHERE subscriber=? AND subscription=? LIMIT 1"
+
 	READ_INDEX_OF_BAN          = "SELECT order_index FROM " + BAN_TABLE + " WHERE id=? LIMIT 1"
 	READ_BAN_OF_ID             = "SELECT " + BAN_FIELDS + " FROM " + BAN_TABLE + " WHERE id=? LIMIT 1"
 	READ_BANS_OF_USER          = "SELECT " + BAN_FIELDS + " FROM " + BAN_TABLE + " WHERE banned=? ORDER BY order_index DESC LIMIT ?"
 	READ_BANS_OF_USER_AFTER_ID = "SELECT " + BAN_FIELDS + " FROM " + BAN_TABLE + " WHERE banned=? AND order_index<(" + READ_INDEX_OF_BAN + ") ORDER BY order_index DESC LIMIT ?"
 	READ_BANS_OF_USER_COUNT    = "SELECT COUNT(id) FROM " + BAN_TABLE + " WHERE (banned=? AND forever) OR (banned=? AND expires>?) LIMIT 1"
+	WRITE_BAN                  = "INSERT INTO " + BAN_TABLE + " (" + BAN_FIELDS + ") VALUES (?, ?, ?, ?, ?, ?, ?)"
+	UPDATE_BAN_OF_ID           = "UPDATE " + BAN_TABLE + " SET reason=?, expires=?, forever=? WHERE id=?"
 
 	READ_REPORT_OF_ID                = "SELECT " + REPORT_FIELDS + " FROM " + REPORT_TABLE + " WHERE id=?"
 	READ_INDEX_OF_REPORT             = "SELECT order_index FROM " + REPORT_TABLE + " WHERE id=? LIMIT 1"
-	READ_REPORTS_UNRESOLVED          = "SELECT " + REPORT_FIELDS + " FROM " + REPORT_TABLE + " WHERE resolved=0 ORDER BY order_index DESC LIMIT ?"
-	READ_REPORTS_UNRESOLVED_AFTER_ID = "SELECT " + REPORT_FIELDS + " FROM " + REPORT_TABLE + " WHERE resolved=0 AND order_index<(" + READ_INDEX_OF_REPORT + ") ORDER BY order_index DESC LIMIT ?"
+	READ_REPORTS_UNRESOLVED          = "SELECT " + REPORT_FIELDS + " FROM " + REPORT_TABLE + " WHERE NOT resolved ORDER BY order_index DESC LIMIT ?"
+	READ_REPORTS_UNRESOLVED_AFTER_ID = "SELECT " + REPORT_FIELDS + " FROM " + REPORT_TABLE + " WHERE NOT resolved AND order_index<(" + READ_INDEX_OF_REPORT + ") ORDER BY order_index DESC LIMIT ?"
+	WRITE_REPORT                     = "INSERT INTO " + REPORT_TABLE + " (" + REPORT_FIELDS + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	UPDATE_REPORT_OF_ID              = "UPDATE " + REPORT_TABLE + " SET resolved=?, resolution=? WHERE id=?"
 
 	WRITE_SECRET_OF_ID  = "REPLACE INTO " + SECRET_TABLE + " (id, secret) VALUES (?, ?)"
 	READ_SECRET_OF_ID   = "SELECT secret FROM " + SECRET_TABLE + " WHERE id=? LIMIT 1"
 	DELETE_SECRET_OF_ID = "DELETE FROM " + SECRET_TABLE + " WHERE id=? LIMIT 1"
 
-	WRITE_TOKEN_OF_ID  = "REPLACE INTO " + TOKEN_TABLE + " (id, token, created) VALUES (?, ?, ?)"
-	READ_TOKEN_STAT    = "SELECT id, created FROM " + TOKEN_TABLE + " WHERE token=? LIMIT 1"
-	DELETE_TOKEN       = "DELETE FROM " + TOKEN_TABLE + " WHERE token=?"
-	DELETE_TOKEN_OF_ID = "DELETE FROM " + TOKEN_TABLE + " WHERE id=?"
+	WRITE_SESSION                    = "INSERT INTO " + SESSION_TABLE + " (id, owner, token, device, ip, created, last_used, issued) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	READ_SESSION_OF_ID               = "SELECT " + SESSION_FIELDS + " FROM " + SESSION_TABLE + " WHERE id=? LIMIT 1"
+	READ_SESSION_OF_TOKEN            = "SELECT " + SESSION_FIELDS + " FROM " + SESSION_TABLE + " WHERE token=? LIMIT 1"
+	READ_SESSIONS_OF_OWNER           = "SELECT " + SESSION_FIELDS + " FROM " + SESSION_TABLE + " WHERE owner=? ORDER BY order_index DESC"
+	READ_SESSION_IDS_OF_TOKEN        = "SELECT id FROM " + SESSION_TABLE + " WHERE token=?"
+	READ_SESSION_IDS_OF_OWNER        = "SELECT id FROM " + SESSION_TABLE + " WHERE owner=?"
+	READ_SESSION_IDS_OF_OWNER_EXCEPT = "SELECT id FROM " + SESSION_TABLE + " WHERE owner=? AND id<>?"
+	TOUCH_SESSION_OF_ID              = "UPDATE " + SESSION_TABLE + " SET last_used=? WHERE id=?"
+	DELETE_SESSIONS_OF_MANY_ID       = "DELETE FROM " + SESSION_TABLE + " WHERE id IN "
+	ROTATE_SESSION_OF_ID             = "UPDATE " + SESSION_TABLE + " SET token=?, issued=?, last_used=? WHERE id=?"
+
+	WRITE_REFRESH                  = "INSERT INTO " + REFRESH_TABLE + " (token, session, created, used) VALUES (?, ?, ?, FALSE)"
+	READ_REFRESH_OF_TOKEN          = "SELECT session, created FROM " + REFRESH_TABLE + " WHERE token=? LIMIT 1"
+	USE_REFRESH_OF_TOKEN           = "UPDATE " + REFRESH_TABLE + " SET used=TRUE WHERE token=? AND NOT used"
+	DELETE_REFRESH_USED_OF_SESSION = "DELETE FROM " + REFRESH_TABLE + " WHERE session=? AND used AND created<?"
+	DELETE_REFRESH_OF_MANY_SESSION = "DELETE FROM " + REFRESH_TABLE + " WHERE session IN "
+
+	WRITE_APIKEY            = "INSERT INTO " + APIKEY_TABLE + " (id, owner, token, name, scopes, created, expires, last_used) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	READ_APIKEY_OF_TOKEN    = "SELECT " + APIKEY_FIELDS + " FROM " + APIKEY_TABLE + " WHERE token=? LIMIT 1"
+	READ_APIKEYS_OF_OWNER   = "SELECT " + APIKEY_FIELDS + " FROM " + APIKEY_TABLE + " WHERE owner=? ORDER BY order_index DESC"
+	TOUCH_APIKEY_OF_ID      = "UPDATE " + APIKEY_TABLE + " SET last_used=? WHERE id=?"
+	DELETE_APIKEY_OF_ID     = "DELETE FROM " + APIKEY_TABLE + " WHERE id=? LIMIT 1"
+	DELETE_APIKEYS_OF_OWNER = "DELETE FROM " + APIKEY_TABLE + " WHERE owner=?"
 
 	READ_HASH_OF_ID  = "SELECT hash FROM " + AUTH_TABLE + " WHERE id=? LIMIT 1"
 	WRITE_HASH_OF_ID = "REPLACE INTO " + AUTH_TABLE + " (id, hash) VALUES (?, ?)"
+
+	READ_SCHEMA_VERSION   = "SELECT version FROM " + MIGRATION_TABLE + " ORDER BY version DESC LIMIT 1"
+	WRITE_SCHEMA_VERSION  = "INSERT INTO " + MIGRATION_TABLE + " (version, applied) VALUES (?, ?)"
+	DELETE_SCHEMA_VERSION = "DELETE FROM " + MIGRATION_TABLE + " WHERE version=?"
+)
+
+var (
+	// Statements that every handle prepares on their first use, and keeps for as long as it's open
+	// Fragments, and statements that are finished at runtime like those of IN lists, are left out
+	preparedStatements []string = []string{
+		READ_CONTENT_ID,
+		READ_MANY_CONTENT_AFTER_ID,
+		READ_MANY_CONTENT,
+		READ_MANY_CONTENT_OF_AUTHOR,
+		READ_MANY_CONTENT_OF_AUTHOR_AFTER_ID,
+		READ_MANY_CONTENT_OF_SUBSCRIPTIONS,
+		READ_MANY_CONTENT_OF_SUBSCRIPTIONS_AFTER_ID,
+		INCREMENT_CONTENT_VIEW_COUNT_OF_ID,
+		DELETE_CONTENT_ID,
+		WRITE_CONTENT,
+
+		READ_TAGS_OF_ID,
+		DELETE_TAGS_OF_ID,
+		READ_POPULAR_TAGS_SINCE,
+
+		READ_VOTE_OF_CONTENT,
+		READ_VOTE_OF_CONTENT_FOR_UPDATE,
+		WRITE_VOTE,
+		UPDATE_VOTE,
+		DELETE_VOTE,
+		DELETE_VOTES_OF_CONTENT,
+		COUNT_CONTENT_VOTES_OF_ID,
+		INCREMENT_CONTENT_LIKE_COUNT_OF_ID,
+		DECREMENT_CONTENT_LIKE_COUNT_OF_ID,
+		INCREMENT_CONTENT_DISLIKE_COUNT_OF_ID,
+		DECREMENT_CONTENT_DISLIKE_COUNT_OF_ID,
+
+		READ_INDEX_OF_FEED_ENTRY,
+		READ_FEED_OF_AUTHOR,
+		READ_FEED_OF_AUTHOR_AFTER_ID,
+		READ_FEED_OF_SUBSCRIPTIONS,
+		READ_FEED_OF_SUBSCRIPTIONS_AFTER_ID,
+
+		WRITE_REPUB,
+		DELETE_REPUB,
+		DELETE_REPUBS_OF_CONTENT,
+		READ_REPUB_COUNT,
+		INCREMENT_CONTENT_REPUB_COUNT_OF_ID,
+		DECREMENT_CONTENT_REPUB_COUNT_OF_ID,
+
+		READ_COMMENT_OF_ID,
+		READ_CONTENT_OF_COMMENT,
+		READ_CONTENT_OF_LIVE_COMMENT,
+		READ_COMMENTS_OF_CONTENT,
+		READ_COMMENTS_OF_CONTENT_AFTER_ID,
+		READ_REPLIES_OF_COMMENT,
+		READ_REPLIES_OF_COMMENT_AFTER_ID,
+		WRITE_COMMENT,
+		WRITE_COMMENT_BODY_OF_ID,
+		DELETE_COMMENT_OF_ID,
+		DELETE_COMMENTS_OF_CONTENT,
+		INCREMENT_CONTENT_COMMENT_COUNT_OF_ID,
+		DECREMENT_CONTENT_COMMENT_COUNT_OF_ID,
+
+		READ_USER_OF_ID,
+		READ_USER_OF_EMAIL,
+		READ_USER_OF_NICK,
+		READ_USER_CONFLICTS,
+		DELETE_USER_OF_ID,
+		WRITE_USER,
+		INCREMENT_USER_POST_COUNT_OF_ID,
+		READ_ANY_PRIVILEGE_OF_ID,
+		READ_MODERATOR_OF_ID,
+		READ_ADMIN_OF_ID,
+		WRITE_MODERATOR_OF_ID,
+		WRITE_ADMIN_OF_ID,
+
+		INCREMENT_USER_SUBSCRIBER_COUNT_OF_ID,
+		DECREMENT_USER_SUBSCRIBER_COUNT_OF_ID,
+		INCREMENT_USER_SUBSCRIPTION_COUNT_OF_ID,
+		DECREMENT_USER_SUBSCRIPTION_COUNT_OF_ID,
+
+		READ_SUBSCRIPTION_COUNT,
+		READ_SUBSCRIBERS_OF_ID,
+		READ_SUBSCRIBERS_OF_ID_AFTER_ID,
+		READ_SUBSCRIPTIONS_OF_ID,
+		READ_SUBSCRIPTIONS_OF_ID_AFTER_ID,
+		WRITE_SUBSCRIPTION,
+		DELETE_SUBSCRIPTION,
+
+		READ_BAN_OF_ID,
+		READ_BANS_OF_USER,
+		READ_BANS_OF_USER_AFTER_ID,
+		READ_BANS_OF_USER_COUNT,
+		WRITE_BAN,
+		UPDATE_BAN_OF_ID,
+
+		READ_REPORT_OF_ID,
+		READ_REPORTS_UNRESOLVED,
+		READ_REPORTS_UNRESOLVED_AFTER_ID,
+		WRITE_REPORT,
+		UPDATE_REPORT_OF_ID,
+
+		WRITE_SECRET_OF_ID,
+		READ_SECRET_OF_ID,
+		DELETE_SECRET_OF_ID,
+
+		WRITE_SESSION,
+		READ_SESSION_OF_TOKEN,
+		READ_SESSIONS_OF_OWNER,
+		READ_SESSION_IDS_OF_TOKEN,
+		READ_SESSION_IDS_OF_OWNER,
+		READ_SESSION_IDS_OF_OWNER_EXCEPT,
+		TOUCH_SESSION_OF_ID,
+		READ_SESSION_OF_ID,
+		ROTATE_SESSION_OF_ID,
+		WRITE_REFRESH,
+		READ_REFRESH_OF_TOKEN,
+		USE_REFRESH_OF_TOKEN,
+		DELETE_REFRESH_USED_OF_SESSION,
+
+		WRITE_APIKEY,
+		READ_APIKEY_OF_TOKEN,
+		READ_APIKEYS_OF_OWNER,
+		TOUCH_APIKEY_OF_ID,
+		DELETE_APIKEY_OF_ID,
+		DELETE_APIKEYS_OF_OWNER,
+
+		READ_HASH_OF_ID,
+		WRITE_HASH_OF_ID,
+
+		READ_SCHEMA_VERSION,
+		WRITE_SCHEMA_VERSION,
+		DELETE_SCHEMA_VERSION,
+	}
 )
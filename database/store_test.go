@@ -0,0 +1,72 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"testing"
+)
+
+func Test_NewSQLStore(test *testing.T) {
+	var found *dialect
+	var dsn string
+	var err error
+	if found, dsn, err = dialectOfAddress(CONNECTION); err != nil {
+		test.Fatal(err)
+	}
+
+	var handle *sqlx.DB
+	if handle, err = sqlx.Open(found.driver, dsn); err != nil {
+		test.Fatal(err)
+	}
+
+	defer handle.Close()
+
+	var store *SQLStore = NewSQLStore(handle)
+	if err = store.Health(); err != nil {
+		test.Fatal(err)
+	}
+
+	var ID string = uuid.New().String()
+	if err = store.WriteContent(mapMod(writableContent, map[string]interface{}{"id": ID})); err != nil {
+		test.Fatal(err)
+	}
+
+	var fetched types.Content
+	var exists bool
+	if fetched, exists, err = ReadSingleContent(ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if !exists || fetched.ID != ID {
+		test.Errorf("content %s written by another store was not read", ID)
+	}
+}
+
+func Test_NewSQLStore_views(test *testing.T) {
+	var ID string = populateSingle(test)
+	var store *SQLStore = NewSQLStore(connected.handle.DB)
+	var viewer string = uuid.New().String()
+
+	var err error
+	if _, err = RecordView(ID, viewer); err != nil {
+		test.Fatal(err)
+	}
+
+	var counted bool
+	if counted, err = store.RecordView(ID, viewer); err != nil {
+		test.Fatal(err)
+	}
+
+	if !counted {
+		test.Errorf("view buffered by the connected store was seen by another store")
+	}
+
+	if err = store.FlushViews(); err != nil {
+		test.Fatal(err)
+	}
+
+	viewCountOK(test, ID, 1)
+	FlushViews()
+}
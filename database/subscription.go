@@ -0,0 +1,190 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/jmoiron/sqlx"
+
+	"context"
+	"database/sql"
+	"time"
+)
+
+/**
+ * Subscribe some user of id `subscriber` to some user of id `subscription`
+ * Subscribing twice is a no-op
+ * Fails with ErrInvalid if `subscriber` is `subscription`, or ErrNotFound if there's no user of id `subscription`,
+ * in which case nothing is written
+ * Done in one transaction of up to 3 queries
+ * 		write sub: 			INSERT IGNORE INTO SUBSCRIPTION_TABLE (subscriber, subscription, created) VALUES (...)
+ * 		count subscription: UPDATE USER_TABLE SET subscriber_count=subscriber_count+1 WHERE id=subscription
+ * 		count subscriber: 	UPDATE USER_TABLE SET subscription_count=subscription_count+1 WHERE id=subscriber
+ */
+func (store *SQLStore) SubscribeContext(ctx context.Context, subscriber, subscription string) (err error) {
+	if subscriber == subscription {
+		err = errorOf(ErrInvalid, "User %s can't subscribe to themselves", subscriber)
+		return
+	}
+
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		var result sql.Result
+		if result, err = tx.ExecContext(ctx, WRITE_SUBSCRIPTION, subscriber, subscription, time.Now().Unix()); err != nil {
+			return
+		}
+
+		var affected int64
+		if affected, err = result.RowsAffected(); err != nil || affected == 0 {
+			return
+		}
+
+		if result, err = tx.ExecContext(ctx, INCREMENT_USER_SUBSCRIBER_COUNT_OF_ID, subscription); err != nil {
+			return
+		}
+
+		if affected, err = result.RowsAffected(); err != nil {
+			return
+		}
+
+		if affected == 0 {
+			err = errorOf(ErrNotFound, "User %s does not exist", subscription)
+			return
+		}
+
+		_, err = tx.ExecContext(ctx, INCREMENT_USER_SUBSCRIPTION_COUNT_OF_ID, subscriber)
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) Subscribe(subscriber, subscription string) (err error) {
+	err = store.SubscribeContext(context.Background(), subscriber, subscription)
+	return
+}
+
+/**
+ * Unsubscribe some user of id `subscriber` from some user of id `subscription`
+ * Unsubscribing when not subscribed is a no-op
+ * Done in one transaction of up to 3 queries
+ * 		delete sub: 		DELETE FROM SUBSCRIPTION_TABLE WHERE subscriber=subscriber AND subscription=subscription LIMIT 1
+ * 		count subscriber: 	UPDATE USER_TABLE SET subscription_count=subscription_count-1 WHERE id=subscriber
+ * 		count subscription: UPDATE USER_TABLE SET subscriber_count=subscriber_count-1 WHERE id=subscription
+ */
+func (store *SQLStore) UnsubscribeContext(ctx context.Context, subscriber, subscription string) (err error) {
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		var result sql.Result
+		if result, err = tx.ExecContext(ctx, DELETE_SUBSCRIPTION, subscriber, subscription); err != nil {
+			return
+		}
+
+		var affected int64
+		if affected, err = result.RowsAffected(); err != nil || affected == 0 {
+			return
+		}
+
+		if _, err = tx.ExecContext(ctx, DECREMENT_USER_SUBSCRIPTION_COUNT_OF_ID, subscriber); err != nil {
+			return
+		}
+
+		_, err = tx.ExecContext(ctx, DECREMENT_USER_SUBSCRIBER_COUNT_OF_ID, subscription)
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) Unsubscribe(subscriber, subscription string) (err error) {
+	err = store.UnsubscribeContext(context.Background(), subscriber, subscription)
+	return
+}
+
+/**
+ * Get whether or not some user of id `subscriber` is subscribed to some user of id `subscription`
+ * Done in one query
+ */
+func (store *SQLStore) IsSubscribedContext(ctx context.Context, subscriber, subscription string) (subscribed bool, err error) {
+	var count int
+	if err = store.db().QueryRowxContext(ctx, READ_SUBSCRIPTION_COUNT, subscriber, subscription).Scan(&count); err != nil {
+		return
+	}
+
+	subscribed = count != 0
+	return
+}
+
+func (store *SQLStore) IsSubscribed(subscriber, subscription string) (subscribed bool, err error) {
+	subscribed, err = store.IsSubscribedContext(context.Background(), subscriber, subscription)
+	return
+}
+
+func readManySubscription(rows *sqlx.Rows, count int) (subscriptions []types.Subscription, size int, err error) {
+	defer rows.Close()
+
+	subscriptions = make([]types.Subscription, count)
+	size = 0
+	for rows.Next() {
+		if err = rows.StructScan(&subscriptions[size]); err != nil {
+			break
+		}
+
+		size++
+	}
+
+	subscriptions = subscriptions[:size]
+	return
+}
+
+/**
+ * Read `count` subscribers of some user of id `ID`, newest first
+ * To read the next page, `before` should be the subscriber of the last subscription read
+ * If the first set of subscribers should be read, `before` may be empty
+ * Done in one query
+ */
+func (store *SQLStore) ReadSubscribersContext(ctx context.Context, ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	store = store.reader(ctx)
+	var rows *sqlx.Rows
+	if before == "" {
+		rows, err = store.db().QueryxContext(ctx, READ_SUBSCRIBERS_OF_ID, ID, count)
+	} else {
+		rows, err = store.db().QueryxContext(ctx, READ_SUBSCRIBERS_OF_ID_AFTER_ID, ID, before, ID, count)
+	}
+
+	if err != nil {
+		return
+	}
+
+	subscriptions, size, err = readManySubscription(rows, count)
+	return
+}
+
+func (store *SQLStore) ReadSubscribers(ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	subscriptions, size, err = store.ReadSubscribersContext(context.Background(), ID, before, count)
+	return
+}
+
+/**
+ * Read `count` subscriptions of some user of id `ID`, newest first
+ * To read the next page, `before` should be the subscription of the last subscription read
+ * If the first set of subscriptions should be read, `before` may be empty
+ * Done in one query
+ */
+func (store *SQLStore) ReadSubscriptionsContext(ctx context.Context, ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	store = store.reader(ctx)
+	var rows *sqlx.Rows
+	if before == "" {
+		rows, err = store.db().QueryxContext(ctx, READ_SUBSCRIPTIONS_OF_ID, ID, count)
+	} else {
+		rows, err = store.db().QueryxContext(ctx, READ_SUBSCRIPTIONS_OF_ID_AFTER_ID, ID, ID, before, count)
+	}
+
+	if err != nil {
+		return
+	}
+
+	subscriptions, size, err = readManySubscription(rows, count)
+	return
+}
+
+func (store *SQLStore) ReadSubscriptions(ID, before string, count int) (subscriptions []types.Subscription, size int, err error) {
+	subscriptions, size, err = store.ReadSubscriptionsContext(context.Background(), ID, before, count)
+	return
+}
@@ -0,0 +1,297 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/google/uuid"
+
+	"errors"
+	"testing"
+)
+
+func subscribable(test *testing.T) (made types.User) {
+	var id string = uuid.New().String()
+	made = types.NewUser(id[:16], "", id+"@imonke.io")
+
+	var err error
+	if err = WriteUser(made.Map()); err != nil {
+		test.Fatal(err)
+	}
+
+	return
+}
+
+func subscriptionCountOK(test *testing.T, ID string, subscribers, subscriptions int) {
+	var fetched types.User
+	var err error
+	if fetched, _, err = ReadSingleUser(ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if fetched.SubscriberCount != subscribers {
+		test.Errorf("subscriber count mismatch for %s! have: %d, want: %d", ID, fetched.SubscriberCount, subscribers)
+	}
+
+	if fetched.SubscriptionCount != subscriptions {
+		test.Errorf("subscription count mismatch for %s! have: %d, want: %d", ID, fetched.SubscriptionCount, subscriptions)
+	}
+}
+
+func Test_Subscribe(test *testing.T) {
+	var subscriber, subscription types.User = subscribable(test), subscribable(test)
+	defer DeleteUser(subscriber.ID)
+	defer DeleteUser(subscription.ID)
+
+	var err error
+	if err = Subscribe(subscriber.ID, subscription.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	var subscribed bool
+	if subscribed, err = IsSubscribed(subscriber.ID, subscription.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if !subscribed {
+		test.Errorf("%s is not subscribed to %s", subscriber.ID, subscription.ID)
+	}
+
+	if subscribed, err = IsSubscribed(subscription.ID, subscriber.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if subscribed {
+		test.Errorf("%s is subscribed back to %s", subscription.ID, subscriber.ID)
+	}
+
+	subscriptionCountOK(test, subscriber.ID, 0, 1)
+	subscriptionCountOK(test, subscription.ID, 1, 0)
+}
+
+func Test_Subscribe_twice(test *testing.T) {
+	var subscriber, subscription types.User = subscribable(test), subscribable(test)
+	defer DeleteUser(subscriber.ID)
+	defer DeleteUser(subscription.ID)
+
+	var err error
+	if err = Subscribe(subscriber.ID, subscription.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = Subscribe(subscriber.ID, subscription.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	subscriptionCountOK(test, subscriber.ID, 0, 1)
+	subscriptionCountOK(test, subscription.ID, 1, 0)
+}
+
+func Test_Subscribe_self(test *testing.T) {
+	var subscriber types.User = subscribable(test)
+	defer DeleteUser(subscriber.ID)
+
+	var err error
+	if err = Subscribe(subscriber.ID, subscriber.ID); !errors.Is(err, ErrInvalid) {
+		test.Errorf("subscribed to themselves, err: %v", err)
+	}
+
+	subscriptionCountOK(test, subscriber.ID, 0, 0)
+}
+
+func Test_Subscribe_nobody(test *testing.T) {
+	var subscriber types.User = subscribable(test)
+	defer DeleteUser(subscriber.ID)
+
+	var nobody string = uuid.New().String()
+
+	var err error
+	if err = Subscribe(subscriber.ID, nobody); !errors.Is(err, ErrNotFound) {
+		test.Errorf("subscribed to nobody, err: %v", err)
+	}
+
+	var subscribed bool
+	if subscribed, err = IsSubscribed(subscriber.ID, nobody); err != nil {
+		test.Fatal(err)
+	}
+
+	if subscribed {
+		test.Errorf("subscription to nobody was kept")
+	}
+
+	subscriptionCountOK(test, subscriber.ID, 0, 0)
+}
+
+func Test_Unsubscribe(test *testing.T) {
+	var subscriber, subscription types.User = subscribable(test), subscribable(test)
+	defer DeleteUser(subscriber.ID)
+	defer DeleteUser(subscription.ID)
+
+	var err error
+	if err = Subscribe(subscriber.ID, subscription.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = Unsubscribe(subscriber.ID, subscription.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	var subscribed bool
+	if subscribed, err = IsSubscribed(subscriber.ID, subscription.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if subscribed {
+		test.Errorf("%s is still subscribed to %s", subscriber.ID, subscription.ID)
+	}
+
+	subscriptionCountOK(test, subscriber.ID, 0, 0)
+	subscriptionCountOK(test, subscription.ID, 0, 0)
+}
+
+func Test_Unsubscribe_notSubscribed(test *testing.T) {
+	var subscriber, subscription types.User = subscribable(test), subscribable(test)
+	defer DeleteUser(subscriber.ID)
+	defer DeleteUser(subscription.ID)
+
+	var err error
+	if err = Unsubscribe(subscriber.ID, subscription.ID); err != nil {
+		test.Fatal(err)
+	}
+
+	subscriptionCountOK(test, subscriber.ID, 0, 0)
+	subscriptionCountOK(test, subscription.ID, 0, 0)
+}
+
+func Test_IsSubscribed_nobody(test *testing.T) {
+	var subscribed bool
+	var err error
+	if subscribed, err = IsSubscribed(uuid.New().String(), uuid.New().String()); err != nil {
+		test.Fatal(err)
+	}
+
+	if subscribed {
+		test.Errorf("random uuid is subscribed to another")
+	}
+}
+
+func Test_ReadSubscribers(test *testing.T) {
+	EmptyTable(SUBSCRIPTION_TABLE)
+
+	var subscription string = subscribable(test).ID
+	var index, population int = 0, 20
+	for index != population {
+		Subscribe(uuid.New().String(), subscription)
+		Subscribe(uuid.New().String(), subscribable(test).ID)
+		index++
+	}
+
+	var count int = 10
+	var subscriptions []types.Subscription
+	var size int
+	var err error
+	if subscriptions, size, err = ReadSubscribers(subscription, "", count); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != count {
+		test.Errorf("size mismatch! have: %d, want: %d", size, count)
+	}
+
+	if len(subscriptions) != size {
+		test.Errorf("block size mismatch! have: %d, want: %d", len(subscriptions), size)
+	}
+
+	var single types.Subscription
+	for _, single = range subscriptions {
+		if single.Subscription != subscription {
+			test.Errorf("subscription mismatch! have: %s, want: %s", single.Subscription, subscription)
+		}
+	}
+}
+
+func Test_ReadSubscribers_after(test *testing.T) {
+	EmptyTable(SUBSCRIPTION_TABLE)
+
+	var subscription string = subscribable(test).ID
+	var index, population int = 0, 20
+	for index != population {
+		Subscribe(uuid.New().String(), subscription)
+		index++
+	}
+
+	var count, offset int = 10, 5
+	var first, second []types.Subscription
+	var err error
+	if first, _, err = ReadSubscribers(subscription, "", count); err != nil {
+		test.Fatal(err)
+	}
+
+	if second, _, err = ReadSubscribers(subscription, first[offset].Subscriber, count); err != nil {
+		test.Fatal(err)
+	}
+
+	var single types.Subscription
+	for index, single = range first[offset+1:] {
+		if single.Subscriber != second[index].Subscriber {
+			test.Errorf("subscribers not aligned! have: %s, want: %s", second[index].Subscriber, single.Subscriber)
+		}
+	}
+}
+
+func Test_ReadSubscriptions(test *testing.T) {
+	EmptyTable(SUBSCRIPTION_TABLE)
+
+	var subscriber string = uuid.New().String()
+	var index, population int = 0, 5
+	for index != population {
+		Subscribe(subscriber, subscribable(test).ID)
+		Subscribe(uuid.New().String(), subscribable(test).ID)
+		index++
+	}
+
+	var subscriptions []types.Subscription
+	var size int
+	var err error
+	if subscriptions, size, err = ReadSubscriptions(subscriber, "", 20); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != population {
+		test.Errorf("size mismatch! have: %d, want: %d", size, population)
+	}
+
+	var single types.Subscription
+	for _, single = range subscriptions {
+		if single.Subscriber != subscriber {
+			test.Errorf("subscriber mismatch! have: %s, want: %s", single.Subscriber, subscriber)
+		}
+	}
+}
+
+func Test_ReadSubscriptions_after(test *testing.T) {
+	EmptyTable(SUBSCRIPTION_TABLE)
+
+	var subscriber string = uuid.New().String()
+	var index, population int = 0, 20
+	for index != population {
+		Subscribe(subscriber, subscribable(test).ID)
+		index++
+	}
+
+	var count, offset int = 10, 5
+	var first, second []types.Subscription
+	var err error
+	if first, _, err = ReadSubscriptions(subscriber, "", count); err != nil {
+		test.Fatal(err)
+	}
+
+	if second, _, err = ReadSubscriptions(subscriber, first[offset].Subscription, count); err != nil {
+		test.Fatal(err)
+	}
+
+	var single types.Subscription
+	for index, single = range first[offset+1:] {
+		if single.Subscription != second[index].Subscription {
+			test.Errorf("subscriptions not aligned! have: %s, want: %s", second[index].Subscription, single.Subscription)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/jmoiron/sqlx"
+
+	"context"
+)
+
+/**
+ * A query of content by its tags
+ * Content matches if it has every tag in All, at least one tag in Any (if there are any), and no tag in None
+ * Empty fields are ignored
+ */
+type TagQuery struct {
+	All  []string
+	Any  []string
+	None []string
+}
+
+func uniqueStrings(them []string) (unique []string) {
+	unique = make([]string, 0, len(them))
+
+	var seen map[string]bool = make(map[string]bool, len(them))
+	var it string
+	for _, it = range them {
+		if !seen[it] {
+			seen[it] = true
+			unique = append(unique, it)
+		}
+	}
+
+	return
+}
+
+/**
+ * Build a statement reading content that matches `query`
+ * Returns the statement along with the values of everything but the before id and count
+ */
+func makeTagQueryable(query TagQuery) (statement string, values []interface{}) {
+	statement = READ_MANY_LIVE_CONTENT
+	values = make([]interface{}, 0)
+
+	var all []string = uniqueStrings(query.All)
+	if len(all) != 0 {
+		statement += FILTER_TAGGED_ALL + "(" + manyParamString("?", len(all)) + ")" + FILTER_TAGGED_ALL_COUNT
+		values = append(append(values, interfaceStrings(all...)...), len(all))
+	}
+
+	if len(query.Any) != 0 {
+		statement += FILTER_TAGGED_ANY + "(" + manyParamString("?", len(query.Any)) + "))"
+		values = append(values, interfaceStrings(query.Any...)...)
+	}
+
+	if len(query.None) != 0 {
+		statement += FILTER_TAGGED_NONE + "(" + manyParamString("?", len(query.None)) + "))"
+		values = append(values, interfaceStrings(query.None...)...)
+	}
+
+	return
+}
+
+/**
+ * Read `count` number of contents that match some tag query `query`, before content of id `before`
+ * If the first set of content should be read, `before` may be empty
+ * Newest posts are returned first, and removed posts are skipped
+ * Uses 2 queries
+ * 		get content: 	SELECT * FROM CONTENT_TABLE WHERE id IN (SELECT id FROM TAG_TABLE WHERE tag IN (...)) ... LIMIT count
+ * 		queries from: 	getManyTags
+ */
+func (store *SQLStore) ReadContentByTagsContext(ctx context.Context, query TagQuery, before string, count int) (content []types.Content, size int, err error) {
+	store = store.reader(ctx)
+	var statement string
+	var values []interface{}
+	statement, values = makeTagQueryable(query)
+
+	if before != "" {
+		statement += FILTER_CONTENT_AFTER_ID
+		values = append(values, before)
+	}
+
+	statement += ORDER_CONTENT_LIMIT
+	values = append(values, count)
+
+	var rows *sqlx.Rows
+	if rows, err = store.db().QueryxContext(ctx, statement, values...); err != nil {
+		return
+	}
+
+	defer rows.Close()
+	content, size, err = store.scanManyContent(ctx, rows, count)
+	return
+}
+
+func (store *SQLStore) ReadContentByTags(query TagQuery, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = store.ReadContentByTagsContext(context.Background(), query, before, count)
+	return
+}
+
+/**
+ * Read `count` number of contents tagged with `tag`, before content of id `before`
+ * Works in the same way as ReadContentByTags, with a single tag
+ */
+func (store *SQLStore) ReadContentByTagContext(ctx context.Context, tag, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = store.ReadContentByTagsContext(ctx, TagQuery{All: []string{tag}}, before, count)
+	return
+}
+
+func (store *SQLStore) ReadContentByTag(tag, before string, count int) (content []types.Content, size int, err error) {
+	content, size, err = store.ReadContentByTagContext(context.Background(), tag, before, count)
+	return
+}
+
+/**
+ * Read the `count` most used tags, and how many times they were used, on content tagged since `since`
+ * Removed content is not counted
+ * Done in one query
+ * 		get tags: 	SELECT tag, COUNT(*) FROM TAG_TABLE WHERE created>=since GROUP BY tag ORDER BY count DESC LIMIT count
+ */
+func (store *SQLStore) ReadPopularTagsContext(ctx context.Context, since int64, count int) (tags []types.TagCount, size int, err error) {
+	store = store.reader(ctx)
+	var rows *sqlx.Rows
+	if rows, err = store.db().QueryxContext(ctx, READ_POPULAR_TAGS_SINCE, since, count); err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	tags = make([]types.TagCount, count)
+	size = 0
+	for rows.Next() {
+		if err = rows.StructScan(&tags[size]); err != nil {
+			break
+		}
+
+		size++
+	}
+
+	tags = tags[:size]
+	return
+}
+
+func (store *SQLStore) ReadPopularTags(since int64, count int) (tags []types.TagCount, size int, err error) {
+	tags, size, err = store.ReadPopularTagsContext(context.Background(), since, count)
+	return
+}
@@ -0,0 +1,202 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/google/uuid"
+
+	"testing"
+	"time"
+)
+
+type tagQuerySet struct {
+	Query TagQuery
+	Want  []string
+}
+
+func populateTagged(tagged map[string][]string) {
+	var name string
+	var tags []string
+	for name, tags = range tagged {
+		WriteContent(mapMod(writableContent, map[string]interface{}{
+			"id":   name,
+			"tags": tags,
+		}))
+	}
+}
+
+func Test_ReadContentByTags(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+	populateTagged(map[string][]string{
+		"cat":     []string{"cat"},
+		"dog":     []string{"dog"},
+		"cat_dog": []string{"cat", "dog"},
+		"cat_eel": []string{"cat", "eel"},
+		"none":    []string{},
+	})
+
+	var sets []tagQuerySet = []tagQuerySet{
+		tagQuerySet{
+			Query: TagQuery{All: []string{"cat"}},
+			Want:  []string{"cat", "cat_dog", "cat_eel"},
+		},
+		tagQuerySet{
+			Query: TagQuery{All: []string{"cat", "dog", "cat"}},
+			Want:  []string{"cat_dog"},
+		},
+		tagQuerySet{
+			Query: TagQuery{Any: []string{"dog", "eel"}},
+			Want:  []string{"dog", "cat_dog", "cat_eel"},
+		},
+		tagQuerySet{
+			Query: TagQuery{All: []string{"cat"}, None: []string{"dog"}},
+			Want:  []string{"cat", "cat_eel"},
+		},
+		tagQuerySet{
+			Query: TagQuery{All: []string{"cat"}, Any: []string{"dog", "eel"}, None: []string{"eel"}},
+			Want:  []string{"cat_dog"},
+		},
+		tagQuerySet{
+			Query: TagQuery{None: []string{"cat", "dog"}},
+			Want:  []string{"none"},
+		},
+		tagQuerySet{
+			Query: TagQuery{All: []string{"fish"}},
+			Want:  []string{},
+		},
+	}
+
+	var set tagQuerySet
+	var content []types.Content
+	var size int
+	var err error
+	var single types.Content
+	var want map[string]bool
+	var name string
+	for _, set = range sets {
+		if content, size, err = ReadContentByTags(set.Query, "", 10); err != nil {
+			test.Fatal(err)
+		}
+
+		if size != len(set.Want) {
+			test.Errorf("size mismatch for %#v! have: %d, want: %d", set.Query, size, len(set.Want))
+		}
+
+		want = map[string]bool{}
+		for _, name = range set.Want {
+			want[name] = true
+		}
+
+		for _, single = range content {
+			if !want[single.ID] {
+				test.Errorf("query %#v matched %s", set.Query, single.ID)
+			}
+		}
+	}
+}
+
+func Test_ReadContentByTag(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+	populate(10)
+
+	var removed map[string]interface{} = mapMod(writableContent, map[string]interface{}{
+		"id":      uuid.New().String(),
+		"tags":    []string{"some", "removed"},
+		"removed": true,
+	})
+	WriteContent(removed)
+
+	var content []types.Content
+	var size int
+	var err error
+	if content, size, err = ReadContentByTag("some", "", 20); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != 10 {
+		test.Errorf("size mismatch! have: %d, want: %d", size, 10)
+	}
+
+	var single types.Content
+	for _, single = range content {
+		if single.ID == removed["id"].(string) {
+			test.Errorf("removed content %s was read", single.ID)
+		}
+
+		if len(single.Tags) != 2 {
+			test.Errorf("tags mismatch! have: %v, want: %v", single.Tags, writableContent["tags"])
+		}
+	}
+
+	if _, size, err = ReadContentByTag("removed", "", 20); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != 0 {
+		test.Errorf("removed content was read by its tag")
+	}
+}
+
+func Test_ReadContentByTag_after(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+	populate(30)
+
+	var count, offset int = 10, 5
+	var first, second []types.Content
+	var err error
+	if first, _, err = ReadContentByTag("tags", "", count); err != nil {
+		test.Fatal(err)
+	}
+
+	if second, _, err = ReadContentByTag("tags", first[offset].ID, count); err != nil {
+		test.Fatal(err)
+	}
+
+	var index int
+	var single types.Content
+	for index, single = range first[offset+1:] {
+		if single.ID != second[index].ID {
+			test.Errorf("IDs not aligned! have: %s, want: %s", second[index].ID, single.ID)
+		}
+	}
+}
+
+func Test_ReadPopularTags(test *testing.T) {
+	EmptyTable(CONTENT_TABLE)
+	var since int64 = time.Now().Unix()
+	populateTagged(map[string][]string{
+		"first":  []string{"cat", "dog", "eel"},
+		"second": []string{"cat", "dog"},
+		"third":  []string{"cat"},
+	})
+
+	var tags []types.TagCount
+	var size int
+	var err error
+	if tags, size, err = ReadPopularTags(since, 2); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != 2 {
+		test.Fatalf("size mismatch! have: %d, want: %d", size, 2)
+	}
+
+	var want []types.TagCount = []types.TagCount{
+		types.TagCount{Tag: "cat", Count: 3},
+		types.TagCount{Tag: "dog", Count: 2},
+	}
+
+	var index int
+	for index = range want {
+		if tags[index] != want[index] {
+			test.Errorf("tag mismatch at %d! have: %#v, want: %#v", index, tags[index], want[index])
+		}
+	}
+
+	if _, size, err = ReadPopularTags(since+60*60, 10); err != nil {
+		test.Fatal(err)
+	}
+
+	if size != 0 {
+		test.Errorf("tags from the future got %d tags", size)
+	}
+}
@@ -2,22 +2,148 @@ package database
 
 import (
 	"github.com/brane-app/librane/types"
+	"github.com/jmoiron/sqlx"
 
+	"context"
 	"database/sql"
 )
 
 /**
- * Write some user `user` into USER_TABLE
- * Uses 1 query
+ * Write some user `user` into USER_TABLE, replacing any user of the same id
+ * Only the columns of USER_TABLE may be given
+ * CreateUser and UpdateUser should be preferred, as they're checked before writing
+ * Fails with ErrDuplicateNick or ErrDuplicateEmail if some other user has its nick or email
+ * Uses 2 queries, inside of one transaction
+ * 		read conflicts: SELECT nick, email FROM USER_TABLE WHERE (nick=nick OR email=email) AND id<>ID FOR UPDATE
  * 		write user: 	REPLACE INTO USER_TABLE (keys...) VALUES (values...)
- * Returns error, if any
  */
-func WriteUser(user map[string]interface{}) (err error) {
-	var statement string
-	var values []interface{}
-	statement, values = makeSQLInsertable(USER_TABLE, user)
+func (store *SQLStore) WriteUserContext(ctx context.Context, user map[string]interface{}) (err error) {
+	err = store.inTx(ctx, func(bound *SQLStore) (err error) {
+		if err = bound.checkUserConflicts(ctx, user["id"], user["nick"], user["email"]); err == nil {
+			err = bound.replace(ctx, USER_TABLE, user)
+		}
+
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) WriteUser(user map[string]interface{}) (err error) {
+	err = store.WriteUserContext(context.Background(), user)
+	return
+}
+
+/**
+ * Create some new user `user`
+ * Its counts start at zero, and it's neither a moderator nor an admin, whatever `user` has
+ * Fails with ErrInvalid if some field of `user` can't be stored, ErrDuplicate if its id is taken,
+ * or ErrDuplicateNick or ErrDuplicateEmail if some other user has its nick or email
+ * Uses 2 queries, inside of one transaction
+ * 		read conflicts: SELECT nick, email FROM USER_TABLE WHERE (nick=nick OR email=email) AND id<>ID FOR UPDATE
+ * 		write user: 	INSERT INTO USER_TABLE (fields...) VALUES (values...)
+ */
+func (store *SQLStore) CreateUserContext(ctx context.Context, user types.User) (err error) {
+	if err = validateUser(user); err != nil {
+		return
+	}
+
+	err = store.inTx(ctx, func(bound *SQLStore) (err error) {
+		if err = bound.checkUserConflicts(ctx, user.ID, user.Nick, user.Email); err == nil {
+			_, err = bound.db().ExecContext(ctx, WRITE_USER, user.ID, user.Email, user.Nick, user.Bio, user.Created)
+		}
+
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) CreateUser(user types.User) (err error) {
+	err = store.CreateUserContext(context.Background(), user)
+	return
+}
+
+/**
+ * Update only the fields in `patch` of some user of id `ID`
+ * Only email, nick, and bio may be patched, so its counts, privileges, and place in order are kept
+ * Fails with ErrInvalid if `patch` has some other key or a value that can't be stored,
+ * ErrNotFound if it doesn't exist, or ErrDuplicateNick or ErrDuplicateEmail if some other user has its nick or email
+ * Uses 4 queries, inside of one transaction
+ * 		queries from: 	lockRow
+ * 		read user: 		SELECT USER_FIELDS FROM USER_TABLE WHERE id=ID LIMIT 1
+ * 		read conflicts: SELECT nick, email FROM USER_TABLE WHERE (nick=nick OR email=email) AND id<>ID FOR UPDATE
+ * 		write user: 	UPDATE USER_TABLE SET patched=patched... WHERE id=ID
+ */
+func (store *SQLStore) UpdateUserContext(ctx context.Context, ID string, patch map[string]interface{}) (err error) {
+	if err = checkPatch(USER_TABLE, patch); err != nil {
+		return
+	}
+
+	err = store.inTx(ctx, func(bound *SQLStore) (err error) {
+		if err = lockRow(ctx, bound.db(), USER_TABLE, ID); err != nil {
+			return
+		}
+
+		var user types.User
+		if user, _, err = bound.readSingleUserKey(ctx, READ_USER_OF_ID, ID); err != nil {
+			return
+		}
+
+		if user, err = patchUser(user, patch); err != nil {
+			return
+		}
+
+		if err = bound.checkUserConflicts(ctx, ID, user.Nick, user.Email); err != nil {
+			return
+		}
+
+		var statement string
+		var values []interface{}
+		if statement, values = makeSQLPatch(USER_TABLE, ID, patch, user.Map()); statement != "" {
+			_, err = bound.db().ExecContext(ctx, statement, values...)
+		}
+
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) UpdateUser(ID string, patch map[string]interface{}) (err error) {
+	err = store.UpdateUserContext(context.Background(), ID, patch)
+	return
+}
+
+/**
+ * Check that no user other than one of id `ID` has the nick `nick` or email `email`
+ */
+func (store *SQLStore) checkUserConflicts(ctx context.Context, ID, nick, email interface{}) (err error) {
+	var rows *sqlx.Rows
+	if rows, err = store.db().QueryxContext(ctx, READ_USER_CONFLICTS, nick, email, ID); err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	var conflictNick, conflictEmail string
+	for rows.Next() {
+		if err = rows.Scan(&conflictNick, &conflictEmail); err != nil {
+			return
+		}
+
+		if conflictNick == nick {
+			err = errorOf(ErrDuplicateNick, "Nick %s is taken", nick)
+			return
+		}
+
+		err = errorOf(ErrDuplicateEmail, "Email %s is taken", email)
+	}
+
+	if err == nil {
+		err = rows.Err()
+	}
 
-	_, err = database_handle.Query(statement, values...)
 	return
 }
 
@@ -26,13 +152,18 @@ func WriteUser(user map[string]interface{}) (err error) {
  * Uses 1 query:
  * 		delete user: 	DELETE FROM USER_TABLE WHERE id=ID LIMIT 1
  */
-func DeleteUser(ID string) (err error) {
-	_, err = database_handle.Exec(DELETE_USER_OF_ID, ID)
+func (store *SQLStore) DeleteUserContext(ctx context.Context, ID string) (err error) {
+	_, err = store.db().ExecContext(ctx, DELETE_USER_OF_ID, ID)
 	return
 }
 
-func readSingleUserKey(statement, query string) (user types.User, exists bool, err error) {
-	if err = database_handle.QueryRowx(statement, query).StructScan(&user); err != nil {
+func (store *SQLStore) DeleteUser(ID string) (err error) {
+	err = store.DeleteUserContext(context.Background(), ID)
+	return
+}
+
+func (store *SQLStore) readSingleUserKey(ctx context.Context, statement, query string) (user types.User, exists bool, err error) {
+	if err = store.db().QueryRowxContext(ctx, statement, query).StructScan(&user); err != nil {
 		if err == sql.ErrNoRows {
 			err = nil
 		}
@@ -49,8 +180,14 @@ func readSingleUserKey(statement, query string) (user types.User, exists bool, e
  * Uses 1 query
  * 		read user: 	SELECT * FROM USER_TABLE WHERE id=ID LIMIT 1
  */
-func ReadSingleUser(ID string) (user types.User, exists bool, err error) {
-	user, exists, err = readSingleUserKey(READ_USER_OF_ID, ID)
+func (store *SQLStore) ReadSingleUserContext(ctx context.Context, ID string) (user types.User, exists bool, err error) {
+	store = store.reader(ctx)
+	user, exists, err = store.readSingleUserKey(ctx, READ_USER_OF_ID, ID)
+	return
+}
+
+func (store *SQLStore) ReadSingleUser(ID string) (user types.User, exists bool, err error) {
+	user, exists, err = store.ReadSingleUserContext(context.Background(), ID)
 	return
 }
 
@@ -60,8 +197,13 @@ func ReadSingleUser(ID string) (user types.User, exists bool, err error) {
  * Uses 1 query
  * 		read user: 	SELECT * FROM USER_TABLE WHERE email=email LIMIT 1
  */
-func ReadSingleUserEmail(email string) (user types.User, exists bool, err error) {
-	user, exists, err = readSingleUserKey(READ_USER_OF_EMAIL, email)
+func (store *SQLStore) ReadSingleUserEmailContext(ctx context.Context, email string) (user types.User, exists bool, err error) {
+	user, exists, err = store.readSingleUserKey(ctx, READ_USER_OF_EMAIL, email)
+	return
+}
+
+func (store *SQLStore) ReadSingleUserEmail(email string) (user types.User, exists bool, err error) {
+	user, exists, err = store.ReadSingleUserEmailContext(context.Background(), email)
 	return
 }
 
@@ -71,8 +213,14 @@ func ReadSingleUserEmail(email string) (user types.User, exists bool, err error)
  * Uses 1 query
  * 		read user: 	SELECT * FROM USER_TABLE WHERE nick=nick LIMIT 1
  */
-func ReadSingleUserNick(nick string) (user types.User, exists bool, err error) {
-	user, exists, err = readSingleUserKey(READ_USER_OF_NICK, nick)
+func (store *SQLStore) ReadSingleUserNickContext(ctx context.Context, nick string) (user types.User, exists bool, err error) {
+	store = store.reader(ctx)
+	user, exists, err = store.readSingleUserKey(ctx, READ_USER_OF_NICK, nick)
+	return
+}
+
+func (store *SQLStore) ReadSingleUserNick(nick string) (user types.User, exists bool, err error) {
+	user, exists, err = store.ReadSingleUserNickContext(context.Background(), nick)
 	return
 }
 
@@ -81,14 +229,19 @@ func ReadSingleUserNick(nick string) (user types.User, exists bool, err error) {
  * Done in one query
  * 		increment: UPDATE USER_TABLE SET post_count=post_count+1 WHERE id=ID
  */
-func IncrementPostCount(ID string) (err error) {
-	_, err = database_handle.Exec(INCREMENT_USER_POST_COUNT_OF_ID, ID)
+func (store *SQLStore) IncrementPostCountContext(ctx context.Context, ID string) (err error) {
+	_, err = store.db().ExecContext(ctx, INCREMENT_USER_POST_COUNT_OF_ID, ID)
 	return
 }
 
-func IsModerator(ID string) (moderator bool, err error) {
+func (store *SQLStore) IncrementPostCount(ID string) (err error) {
+	err = store.IncrementPostCountContext(context.Background(), ID)
+	return
+}
+
+func (store *SQLStore) IsModeratorContext(ctx context.Context, ID string) (moderator bool, err error) {
 	var admin bool
-	if err = database_handle.QueryRowx(READ_ANY_PRIVILEGE_OF_ID, ID).Scan(&admin, &moderator); err != nil {
+	if err = store.db().QueryRowxContext(ctx, READ_ANY_PRIVILEGE_OF_ID, ID).Scan(&admin, &moderator); err != nil {
 		if err == sql.ErrNoRows {
 			err = nil
 		}
@@ -100,20 +253,40 @@ func IsModerator(ID string) (moderator bool, err error) {
 	return
 }
 
-func IsAdmin(ID string) (admin bool, err error) {
-	if err = database_handle.QueryRowx(READ_ADMIN_OF_ID, ID).Scan(&admin); err == sql.ErrNoRows {
+func (store *SQLStore) IsModerator(ID string) (moderator bool, err error) {
+	moderator, err = store.IsModeratorContext(context.Background(), ID)
+	return
+}
+
+func (store *SQLStore) IsAdminContext(ctx context.Context, ID string) (admin bool, err error) {
+	if err = store.db().QueryRowxContext(ctx, READ_ADMIN_OF_ID, ID).Scan(&admin); err == sql.ErrNoRows {
 		err = nil
 	}
 
 	return
 }
 
-func SetModerator(ID string, state bool) (err error) {
-	_, err = database_handle.Exec(WRITE_MODERATOR_OF_ID, state, ID)
+func (store *SQLStore) IsAdmin(ID string) (admin bool, err error) {
+	admin, err = store.IsAdminContext(context.Background(), ID)
+	return
+}
+
+func (store *SQLStore) SetModeratorContext(ctx context.Context, ID string, state bool) (err error) {
+	_, err = store.db().ExecContext(ctx, WRITE_MODERATOR_OF_ID, state, ID)
+	return
+}
+
+func (store *SQLStore) SetModerator(ID string, state bool) (err error) {
+	err = store.SetModeratorContext(context.Background(), ID, state)
+	return
+}
+
+func (store *SQLStore) SetAdminContext(ctx context.Context, ID string, state bool) (err error) {
+	_, err = store.db().ExecContext(ctx, WRITE_ADMIN_OF_ID, state, ID)
 	return
 }
 
-func SetAdmin(ID string, state bool) (err error) {
-	_, err = database_handle.Exec(WRITE_ADMIN_OF_ID, state, ID)
+func (store *SQLStore) SetAdmin(ID string, state bool) (err error) {
+	err = store.SetAdminContext(context.Background(), ID, state)
 	return
 }
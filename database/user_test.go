@@ -4,6 +4,7 @@ import (
 	"github.com/brane-app/librane/types"
 	"github.com/google/uuid"
 
+	"errors"
 	"testing"
 )
 
@@ -12,6 +13,17 @@ var (
 	writableUser map[string]interface{} = user.Map()
 )
 
+func uniqueUser(mods ...map[string]interface{}) (modified map[string]interface{}) {
+	var ID string = uuid.New().String()
+	modified = mapMod(writableUser, append([]map[string]interface{}{{
+		"id":    ID,
+		"nick":  ID[:16],
+		"email": ID + "@imonke.io",
+	}}, mods...)...)
+
+	return
+}
+
 func userOK(test *testing.T, data map[string]interface{}, have types.User) {
 	if data["id"].(string) != have.ID {
 		test.Errorf("User ID mismatch! have: %s, want: %s", have.ID, data["id"])
@@ -26,8 +38,10 @@ func Test_WriteUser(test *testing.T) {
 	var mods []map[string]interface{} = []map[string]interface{}{
 		map[string]interface{}{},
 		map[string]interface{}{
-			"id":  uuid.New().String(),
-			"bio": "' or 1=1; DROP TABLE user",
+			"id":    uuid.New().String(),
+			"nick":  "imonke2",
+			"email": "me2@imonke.io",
+			"bio":   "' or 1=1; DROP TABLE user",
 		},
 	}
 
@@ -69,11 +83,14 @@ func Test_WriteUser_err(test *testing.T) {
 		}
 	}
 
+	if err = WriteUser(uniqueUser(map[string]interface{}{"answer": 42})); !errors.Is(err, ErrInvalid) {
+		test.Errorf("unknown column was not ErrInvalid, have: %v", err)
+	}
+
 }
 
 func Test_ReadSingleUser(test *testing.T) {
-	var modified map[string]interface{} = mapCopy(writableUser)
-	modified["id"] = uuid.New().String()
+	var modified map[string]interface{} = uniqueUser()
 
 	WriteUser(modified)
 
@@ -92,9 +109,7 @@ func Test_ReadSingleUser(test *testing.T) {
 }
 
 func Test_ReadSingleUserNick(test *testing.T) {
-	var modified map[string]interface{} = mapCopy(writableUser)
-	modified["id"] = uuid.New().String()
-	modified["nick"] = "readme"
+	var modified map[string]interface{} = uniqueUser()
 
 	WriteUser(modified)
 
@@ -113,9 +128,7 @@ func Test_ReadSingleUserNick(test *testing.T) {
 }
 
 func Test_ReadSingleUserEmail(test *testing.T) {
-	var modified map[string]interface{} = mapCopy(writableUser)
-	modified["id"] = uuid.New().String()
-	modified["email"] = "read@me.io"
+	var modified map[string]interface{} = uniqueUser()
 
 	WriteUser(modified)
 
@@ -149,13 +162,10 @@ func Test_ReadSingleUser_NotExists(test *testing.T) {
 }
 
 func Test_DeleteUser(test *testing.T) {
-	var mod map[string]interface{} = map[string]interface{}{
-		"id":    uuid.New().String(),
-		"email": "delete@monke.io",
-	}
+	var mod map[string]interface{} = uniqueUser()
 
 	var err error
-	if err = WriteUser(mapMod(writableUser, mod)); err != nil {
+	if err = WriteUser(mod); err != nil {
 		test.Fatal(err)
 	}
 
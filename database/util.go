@@ -4,9 +4,120 @@ import (
 	"github.com/brane-app/librane/types"
 	"github.com/jmoiron/sqlx"
 
+	"context"
+	"database/sql"
+	"sort"
 	"strings"
 )
 
+var (
+	// Columns that makeSQLInsertable may write to, for each table that it's used on
+	tableColumns map[string]map[string]bool = map[string]map[string]bool{
+		CONTENT_TABLE: columnsOf(CONTENT_FIELDS),
+		USER_TABLE:    columnsOf(USER_FIELDS),
+		BAN_TABLE:     columnsOf(BAN_FIELDS),
+		REPORT_TABLE:  columnsOf(REPORT_FIELDS),
+	}
+
+	// Columns that a patch may update, for each table that can be patched
+	patchableColumns map[string]map[string]bool = map[string]map[string]bool{
+		CONTENT_TABLE: columnsOf(CONTENT_PATCHABLE_FIELDS),
+		USER_TABLE:    columnsOf(USER_PATCHABLE_FIELDS),
+	}
+)
+
+/**
+ * Get the set of columns in some comma separated list of `fields`
+ */
+func columnsOf(fields string) (columns map[string]bool) {
+	columns = make(map[string]bool)
+
+	var field string
+	for _, field = range strings.Split(fields, ",") {
+		columns[strings.TrimSpace(field)] = true
+	}
+
+	return
+}
+
+/**
+ * Check that every key of `it` is a column of `table`, failing with ErrInvalid if not
+ */
+func checkColumns(table string, it map[string]interface{}) (err error) {
+	var key string
+	for key = range it {
+		if !tableColumns[table][key] {
+			err = errorOf(ErrInvalid, "%s is not a column of %s", key, table)
+			return
+		}
+	}
+
+	return
+}
+
+/**
+ * Check that every key of `patch` is a patchable column of `table`, or one of `extra`,
+ * failing with ErrInvalid if not
+ */
+func checkPatch(table string, patch map[string]interface{}, extra ...string) (err error) {
+	var key string
+	for key = range patch {
+		if !patchableColumns[table][key] && !hasString(extra, key) {
+			err = errorOf(ErrInvalid, "%s is not a patchable column of %s", key, table)
+			return
+		}
+	}
+
+	return
+}
+
+func hasString(them []string, it string) (has bool) {
+	var one string
+	for _, one = range them {
+		if one == it {
+			has = true
+			return
+		}
+	}
+
+	return
+}
+
+/**
+ * Build an UPDATE of the row of id `ID` in `table`, setting only the patchable columns in `patch`
+ * to their values in `row`, which is the patched row as a map
+ * Columns are set in sorted order, so that the same keys always make the same statement
+ * statement is empty if `patch` has no patchable columns
+ */
+func makeSQLPatch(table, ID string, patch, row map[string]interface{}) (statement string, values []interface{}) {
+	var keys []string = make([]string, 0, len(patch))
+	var key string
+	for key = range patch {
+		if patchableColumns[table][key] {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return
+	}
+
+	sort.Strings(keys)
+
+	var sets []string = make([]string, len(keys))
+	values = make([]interface{}, len(keys)+1)
+
+	var index int
+	for index, key = range keys {
+		sets[index] = key + "=?"
+		values[index] = row[key]
+	}
+
+	values[len(keys)] = ID
+	statement = "UPDATE " + table + " SET " + strings.Join(sets, ", ") + " WHERE id=?"
+	return
+}
+
 func getSQLParams(it map[string]interface{}) (keys []string, values []interface{}) {
 	var size int = len(it)
 	keys, values = make([]string, size), make([]interface{}, size)
@@ -31,6 +142,54 @@ func makeSQLInsertable(table string, it map[string]interface{}) (statement strin
 	return
 }
 
+/**
+ * Write `it` into `table`, replacing any rows that it conflicts with
+ * Dialects with REPLACE INTO do this in one statement, others delete every row that conflicts
+ * on one of the dialect's replaceKeys before inserting, inside of one transaction
+ * Either way, replaced rows take a new order_index and drop any rows that cascade from them
+ * Fails with ErrInvalid if `it` has a key that isn't a column of `table`
+ */
+func (store *SQLStore) replace(ctx context.Context, table string, it map[string]interface{}) (err error) {
+	if err = checkColumns(table, it); err != nil {
+		return
+	}
+
+	var statement string
+	var values []interface{}
+	statement, values = makeSQLInsertable(table, it)
+
+	var keys []string
+	var ok bool
+	if keys, ok = store.handle.dialect.replaceKeys[table]; !ok {
+		_, err = store.db().ExecContext(ctx, statement, values...)
+		return
+	}
+
+	var conditions []string = make([]string, 0, len(keys))
+	var conflicts []interface{} = make([]interface{}, 0, len(keys))
+	var key string
+	for _, key = range keys {
+		var value interface{}
+		if value, ok = it[key]; ok {
+			conditions = append(conditions, key+"=?")
+			conflicts = append(conflicts, value)
+		}
+	}
+
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		if len(conditions) != 0 {
+			if _, err = tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE "+strings.Join(conditions, " OR "), conflicts...); err != nil {
+				return
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT"+strings.TrimPrefix(statement, "REPLACE"), values...)
+		return
+	})
+
+	return
+}
+
 func manyParamString(param string, size int) (param_string string) {
 	var param_slice []string = make([]string, size)
 	for size != 0 {
@@ -65,7 +224,42 @@ func mapCopy(source map[string]interface{}) (copy map[string]interface{}) {
 	return
 }
 
-func scanManyContent(rows *sqlx.Rows, count int) (content []types.Content, size int, err error) {
+/**
+ * Run `work` inside of a single transaction
+ * The transaction is rolled back if `work` returns an error or panics, and committed otherwise
+ * If this store is already inside of a transaction, `work` is run inside of that one,
+ * and it's left to whoever began it to commit or roll back
+ */
+func (store *SQLStore) withTx(ctx context.Context, work func(*dialectTx) error) (err error) {
+	if store.tx != nil {
+		err = work(store.tx)
+		return
+	}
+
+	var tx *dialectTx
+	if tx, err = store.handle.BeginTxx(ctx, nil); err != nil {
+		return
+	}
+
+	defer func() {
+		var recovered interface{}
+		if recovered = recover(); recovered != nil {
+			tx.Rollback()
+			panic(recovered)
+		}
+	}()
+
+	if err = work(tx); err != nil {
+		tx.Rollback()
+		return
+	}
+
+	err = tx.Commit()
+	wroteWith(ctx)
+	return
+}
+
+func (store *SQLStore) scanManyContent(ctx context.Context, rows *sqlx.Rows, count int) (content []types.Content, size int, err error) {
 	var ids []string = make([]string, count)
 	var scanned []types.Content = make([]types.Content, count)
 	size = 0
@@ -76,11 +270,14 @@ func scanManyContent(rows *sqlx.Rows, count int) (content []types.Content, size
 		size++
 	}
 
+	// rows are closed before reading tags, as SQLite has only the one connection to read them with
+	rows.Close()
+
 	content = make([]types.Content, size)
 	copy(content, scanned)
 
 	var tags map[string][]string
-	if tags, err = getManyTags(ids); err != nil {
+	if tags, err = store.getManyTags(ctx, ids); err != nil {
 		return
 	}
 
@@ -91,3 +288,17 @@ func scanManyContent(rows *sqlx.Rows, count int) (content []types.Content, size
 
 	return
 }
+
+/**
+ * Lock the row of id `ID` in `table` until the end of the transaction that `tx` is in
+ * Fails with ErrNotFound if there isn't one
+ * 		lock row: 	SELECT order_index FROM table WHERE id=ID LIMIT 1 FOR UPDATE
+ */
+func lockRow(ctx context.Context, tx dialectQueryer, table, ID string) (err error) {
+	var index int64
+	if err = tx.QueryRowxContext(ctx, "SELECT order_index FROM "+table+" WHERE id=? LIMIT 1 FOR UPDATE", ID).Scan(&index); err == sql.ErrNoRows {
+		err = errorOf(ErrNotFound, "%s of id %s does not exist", table, ID)
+	}
+
+	return
+}
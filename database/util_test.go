@@ -45,3 +45,33 @@ func Test_makeSQLInsertable(test *testing.T) {
 
 	_, _ = makeSQLInsertable("table", data)
 }
+
+func Test_makeSQLPatch(test *testing.T) {
+	var patch map[string]interface{} = map[string]interface{}{
+		"nick": "ignored",
+		"bio":  "ignored",
+	}
+
+	var row map[string]interface{} = map[string]interface{}{
+		"nick":       "patched",
+		"bio":        "bio",
+		"post_count": 1,
+	}
+
+	var statement string
+	var values []interface{}
+	statement, values = makeSQLPatch(USER_TABLE, "id", patch, row)
+
+	var want string = "UPDATE " + USER_TABLE + " SET bio=?, nick=? WHERE id=?"
+	if statement != want {
+		test.Errorf("statement mismatch! have: %s, want: %s", statement, want)
+	}
+
+	if len(values) != 3 || values[0] != "bio" || values[1] != "patched" || values[2] != "id" {
+		test.Errorf("values mismatch! have: %v", values)
+	}
+
+	if statement, _ = makeSQLPatch(USER_TABLE, "id", map[string]interface{}{"tags": nil}, row); statement != "" {
+		test.Errorf("statement made without patchable columns: %s", statement)
+	}
+}
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+
+	"net"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Longest values of each CHAR column, as defined in tables
+const (
+	ID_LENGTH_MAX          = 36
+	FILE_URL_LENGTH_MAX    = 64
+	MIME_LENGTH_MAX        = 255
+	TAG_LENGTH_MAX         = 64
+	EMAIL_LENGTH_MAX       = 254
+	NICK_LENGTH_MAX        = 16
+	BIO_LENGTH_MAX         = 255
+	REASON_LENGTH_MAX      = 255
+	REPORT_TYPE_LENGTH_MAX = 31
+	RESOLUTION_LENGTH_MAX  = 255
+	DEVICE_LENGTH_MAX      = 64
+	IP_LENGTH_MAX          = 45
+	KEY_NAME_LENGTH_MAX    = 64
+	SCOPES_LENGTH_MAX      = 255
+)
+
+/**
+ * Some field `name` of value `value`, which must be between `min` and `max` characters long
+ */
+type fieldRule struct {
+	name  string
+	value string
+	min   int
+	max   int
+}
+
+/**
+ * Check every rule in `rules`, failing with ErrInvalid on the first that's broken
+ */
+func validateFields(rules ...fieldRule) (err error) {
+	var rule fieldRule
+	for _, rule = range rules {
+		var length int = utf8.RuneCountInString(rule.value)
+		if length < rule.min {
+			err = errorOf(ErrInvalid, "Field %s must be at least %d characters long", rule.name, rule.min)
+			return
+		}
+
+		if length > rule.max {
+			err = errorOf(ErrInvalid, "Field %s must be at most %d characters long", rule.name, rule.max)
+			return
+		}
+	}
+
+	return
+}
+
+/**
+ * Check that some mime `mime` looks like type/subtype, or like a bare subtype such as png,
+ * which content written before CreateContent was added has
+ */
+func validMime(mime string) (valid bool) {
+	if strings.IndexFunc(mime, unicode.IsSpace) >= 0 {
+		return
+	}
+
+	var parts []string = strings.Split(mime, "/")
+	if len(parts) > 2 {
+		return
+	}
+
+	var part string
+	for _, part = range parts {
+		if part == "" {
+			return
+		}
+	}
+
+	valid = true
+	return
+}
+
+func validateContent(content types.Content) (err error) {
+	if err = validateFields(
+		fieldRule{"id", content.ID, 1, ID_LENGTH_MAX},
+		fieldRule{"file_url", content.FileURL, 1, FILE_URL_LENGTH_MAX},
+		fieldRule{"author", content.Author, 1, ID_LENGTH_MAX},
+		fieldRule{"mime", content.Mime, 3, MIME_LENGTH_MAX},
+	); err != nil {
+		return
+	}
+
+	if !validMime(content.Mime) {
+		err = errorOf(ErrInvalid, "Field mime must look like type/subtype or subtype, not %s", content.Mime)
+		return
+	}
+
+	var tag string
+	for _, tag = range content.Tags {
+		if err = validateFields(fieldRule{"tags", tag, 1, TAG_LENGTH_MAX}); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func validateUser(user types.User) (err error) {
+	if err = validateFields(
+		fieldRule{"id", user.ID, 1, ID_LENGTH_MAX},
+		fieldRule{"email", user.Email, 3, EMAIL_LENGTH_MAX},
+		fieldRule{"nick", user.Nick, 1, NICK_LENGTH_MAX},
+		fieldRule{"bio", user.Bio, 0, BIO_LENGTH_MAX},
+	); err != nil {
+		return
+	}
+
+	if !strings.Contains(user.Email, "@") {
+		err = errorOf(ErrInvalid, "Field email must be an email address, not %s", user.Email)
+	}
+
+	return
+}
+
+func validateBan(ban types.Ban) (err error) {
+	err = validateFields(
+		fieldRule{"id", ban.ID, 1, ID_LENGTH_MAX},
+		fieldRule{"banner", ban.Banner, 1, ID_LENGTH_MAX},
+		fieldRule{"banned", ban.Banned, 1, ID_LENGTH_MAX},
+		fieldRule{"reason", ban.Reason, 0, REASON_LENGTH_MAX},
+	)
+
+	return
+}
+
+func validateReport(report types.Report) (err error) {
+	err = validateFields(
+		fieldRule{"id", report.ID, 1, ID_LENGTH_MAX},
+		fieldRule{"reporter", report.Reporter, 1, ID_LENGTH_MAX},
+		fieldRule{"reported", report.Reported, 1, ID_LENGTH_MAX},
+		fieldRule{"type", report.Type, 1, REPORT_TYPE_LENGTH_MAX},
+		fieldRule{"reason", report.Reason, 0, REASON_LENGTH_MAX},
+		fieldRule{"resolution", report.Resolution, 0, RESOLUTION_LENGTH_MAX},
+	)
+
+	return
+}
+
+/**
+ * Apply `patch` to `user`, and validate what it becomes
+ */
+func patchUser(user types.User, patch map[string]interface{}) (patched types.User, err error) {
+	patched = user
+	if err = patched.FromMap(patch); err != nil {
+		err = &Error{ErrInvalid, err}
+		return
+	}
+
+	err = validateUser(patched)
+	return
+}
+
+/**
+ * Apply `patch` to `content`, and validate what it becomes
+ * Tags given in `patch` replace those of `content`, rather than being merged into them
+ */
+func patchContent(content types.Content, patch map[string]interface{}) (patched types.Content, err error) {
+	patched = content
+
+	var given bool
+	if _, given = patch["tags"]; given {
+		patched.Tags = nil
+	}
+
+	if err = patched.FromMap(patch); err != nil {
+		err = &Error{ErrInvalid, err}
+		return
+	}
+
+	err = validateContent(patched)
+	return
+}
+
+/**
+ * Make a new session of some user `owner`, for a device labeled `device` at the address `IP`
+ * Labels are cut down to DEVICE_LENGTH_MAX, as they're often a User-Agent of any length,
+ * but an address that's given must be an IPv4 or IPv6 address
+ */
+func newSession(owner, device, IP string) (session types.Session, err error) {
+	if err = validateFields(fieldRule{"owner", owner, 1, ID_LENGTH_MAX}); err != nil {
+		return
+	}
+
+	if IP != "" && net.ParseIP(IP) == nil {
+		err = errorOf(ErrInvalid, "Field ip must be an IP address, not %s", IP)
+		return
+	}
+
+	var runes []rune = []rune(device)
+	if len(runes) > DEVICE_LENGTH_MAX {
+		device = string(runes[:DEVICE_LENGTH_MAX])
+	}
+
+	session = types.NewSession(owner, device, IP)
+	return
+}
+
+/**
+ * Make a new API key of some user `owner` named `name`, that grants `scopes` for `lifetime` seconds or forever if 0
+ * Scopes are stored separated by spaces, so none of them may be empty or have spaces in it
+ */
+func newAPIKey(owner, name string, scopes []string, lifetime int64) (key types.APIKey, err error) {
+	if err = validateFields(
+		fieldRule{"owner", owner, 1, ID_LENGTH_MAX},
+		fieldRule{"name", name, 1, KEY_NAME_LENGTH_MAX},
+		fieldRule{"scopes", strings.Join(scopes, " "), 0, SCOPES_LENGTH_MAX},
+	); err != nil {
+		return
+	}
+
+	var scope string
+	for _, scope = range scopes {
+		if scope == "" || strings.IndexFunc(scope, unicode.IsSpace) >= 0 {
+			err = errorOf(ErrInvalid, "Field scopes must not have an empty scope or one with spaces, not %q", scope)
+			return
+		}
+	}
+
+	if lifetime < 0 {
+		err = errorOf(ErrInvalid, "Key lifetime must not be negative, not %d", lifetime)
+		return
+	}
+
+	key = types.NewAPIKey(owner, name, append([]string(nil), scopes...), lifetime)
+	return
+}
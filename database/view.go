@@ -0,0 +1,366 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	VIEW_WINDOW        = 60 * 30
+	VIEW_FLUSH_SIZE    = 128
+	VIEW_FLUSH_TIMEOUT = 30
+	// How many viewers whose window has passed are forgotten by each view that's recorded
+	VIEW_EXPIRE_STEP = 2
+)
+
+/**
+ * When some viewer was seen, so that they can be forgotten in the order that they were seen
+ */
+type viewSeen struct {
+	key     string
+	expires time.Time
+}
+
+type viewBuffer struct {
+	lock     sync.Mutex
+	window   time.Duration
+	size     int
+	seen     map[string]time.Time
+	expiring []viewSeen
+	pending  map[string]int64
+	total    int
+	failed   func(error)
+	// closed once the flush in the background is done, or nil if there isn't one
+	flushing chan bool
+}
+
+func newViewBuffer() (views *viewBuffer) {
+	views = &viewBuffer{
+		window:  VIEW_WINDOW * time.Second,
+		size:    VIEW_FLUSH_SIZE,
+		seen:    map[string]time.Time{},
+		pending: map[string]int64{},
+	}
+
+	return
+}
+
+func (views *viewBuffer) setWindow(window time.Duration) {
+	views.lock.Lock()
+	views.window = window
+	views.lock.Unlock()
+}
+
+func (views *viewBuffer) setSize(size int) {
+	views.lock.Lock()
+	views.size = size
+	views.lock.Unlock()
+}
+
+func (views *viewBuffer) setFailed(failed func(error)) {
+	views.lock.Lock()
+	views.failed = failed
+	views.lock.Unlock()
+}
+
+/**
+ * Buffer a view of content of id `ID` by `viewer`, unless they were seen inside of the window
+ * Returns whether or not the view was counted, and whether or not the buffer is full
+ */
+func (views *viewBuffer) record(ID, viewer string) (counted, full bool) {
+	var now time.Time = time.Now()
+	var key string = ID + "/" + viewer
+
+	views.lock.Lock()
+	defer views.lock.Unlock()
+
+	views.expire(now, VIEW_EXPIRE_STEP)
+
+	var expires time.Time
+	var seen bool
+	if expires, seen = views.seen[key]; seen && now.Before(expires) {
+		return
+	}
+
+	expires = now.Add(views.window)
+	views.seen[key] = expires
+	views.expiring = append(views.expiring, viewSeen{key, expires})
+	views.pending[ID]++
+	views.total++
+	counted = true
+	full = views.total >= views.size
+	return
+}
+
+/**
+ * Forget up to `count` of the viewers seen longest ago, if their window has passed by `now`
+ * Viewers are forgotten in the order that they were seen, a few at a time, rather than all at once
+ * A viewer seen again since is left to the later time that they're expiring at
+ * Must be called with the lock held
+ */
+func (views *viewBuffer) expire(now time.Time, count int) {
+	var forgotten int
+	var oldest viewSeen
+	for forgotten < count && len(views.expiring) != 0 {
+		if oldest = views.expiring[0]; now.Before(oldest.expires) {
+			break
+		}
+
+		if views.seen[oldest.key].Equal(oldest.expires) {
+			delete(views.seen, oldest.key)
+		}
+
+		views.expiring[0] = viewSeen{}
+		views.expiring = views.expiring[1:]
+		forgotten++
+	}
+
+	if len(views.expiring) == 0 {
+		views.expiring = nil
+	}
+}
+
+/**
+ * Take every buffered view out of the buffer
+ * Returns the views taken, and the ids that they're of in sorted order
+ */
+func (views *viewBuffer) take() (pending map[string]int64, IDs []string) {
+	views.lock.Lock()
+	pending = views.pending
+	views.pending = map[string]int64{}
+	views.total = 0
+	views.lock.Unlock()
+
+	IDs = make([]string, 0, len(pending))
+	var ID string
+	for ID = range pending {
+		IDs = append(IDs, ID)
+	}
+
+	sort.Strings(IDs)
+	return
+}
+
+/**
+ * Put views that were taken but could not be written back into the buffer
+ */
+func (views *viewBuffer) restore(pending map[string]int64) {
+	views.lock.Lock()
+	var ID string
+	var count int64
+	for ID, count = range pending {
+		views.pending[ID] += count
+		views.total += int(count)
+	}
+
+	views.lock.Unlock()
+}
+
+/**
+ * Run `flush` in the background, unless the buffer is already being flushed in the background
+ * If it fails, its error is passed to the function set by setFailed, if any
+ */
+func (views *viewBuffer) flushBackground(flush func() error) {
+	views.lock.Lock()
+	defer views.lock.Unlock()
+
+	if views.flushing != nil {
+		return
+	}
+
+	var done chan bool = make(chan bool)
+	views.flushing = done
+
+	go func() {
+		defer close(done)
+
+		var err error = flush()
+
+		views.lock.Lock()
+		var failed func(error) = views.failed
+		views.flushing = nil
+		views.lock.Unlock()
+
+		if err != nil && failed != nil {
+			failed(err)
+		}
+	}()
+}
+
+/**
+ * Wait for the buffer to be done flushing in the background, if it is
+ */
+func (views *viewBuffer) wait() {
+	views.lock.Lock()
+	var done chan bool = views.flushing
+	views.lock.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+/**
+ * Call `flush` every `interval` in the background, passing errors to `failed`, which may be nil
+ * Returns a function that stops flushing, and flushes one last time
+ */
+func flushEvery(flush func() error, interval time.Duration, failed func(error)) (stop func()) {
+	var ticker *time.Ticker = time.NewTicker(interval)
+	var done chan bool = make(chan bool)
+	var stopped chan bool = make(chan bool)
+
+	go func() {
+		defer close(stopped)
+
+		var err error
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				ticker.Stop()
+				if err = flush(); err != nil && failed != nil {
+					failed(err)
+				}
+
+				return
+			}
+
+			if err = flush(); err != nil && failed != nil {
+				failed(err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+
+	return
+}
+
+/**
+ * Set how long a viewer is remembered for
+ * A viewer that views the same content again inside of this window is not counted twice
+ */
+func (store *SQLStore) SetViewWindow(window time.Duration) {
+	store.views.setWindow(window)
+}
+
+/**
+ * Set how many views may be buffered before they're flushed by RecordView
+ */
+func (store *SQLStore) SetViewFlushSize(size int) {
+	store.views.setSize(size)
+}
+
+/**
+ * Set a function that's passed the error of every flush by RecordView that fails in the background
+ * Its views are kept either way, and `failed` may be nil to not be told
+ */
+func (store *SQLStore) SetViewFlushFailed(failed func(error)) {
+	store.views.setFailed(failed)
+}
+
+/**
+ * Record a view of content of id `ID` by some viewer `viewer`
+ * `viewer` may be anything that identifies a viewer, like a user id or an address
+ * Each viewer is counted at most once per view window, and counted views are buffered in memory
+ * If the buffer is full, it's flushed to the database in the background, outside of any transaction
+ * and with its own context that times out after VIEW_FLUSH_TIMEOUT seconds, so `ctx` only needs to outlive the call
+ * Views that fail to flush in the background are kept, and flushed along with the next,
+ * and the error is passed to the function set by SetViewFlushFailed
+ * Returns whether or not the view was counted
+ * Uses queries from FlushViews, in the background if flushed
+ */
+func (store *SQLStore) RecordViewContext(ctx context.Context, ID, viewer string) (counted bool, err error) {
+	var full bool
+	if counted, full = store.views.record(ID, viewer); !full {
+		return
+	}
+
+	var primary *SQLStore = &SQLStore{
+		handle:      store.handle,
+		views:       store.views,
+		lifetimes:   store.lifetimes,
+		revocations: store.revocations,
+	}
+
+	store.views.flushBackground(func() (err error) {
+		var flushCtx context.Context
+		var cancel context.CancelFunc
+		flushCtx, cancel = context.WithTimeout(context.Background(), VIEW_FLUSH_TIMEOUT*time.Second)
+		defer cancel()
+
+		err = primary.flushViews(flushCtx)
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) RecordView(ID, viewer string) (counted bool, err error) {
+	counted, err = store.RecordViewContext(context.Background(), ID, viewer)
+	return
+}
+
+/**
+ * Write every buffered view to CONTENT_TABLE, once any flush in the background is done
+ * If writing fails, the views are kept in the buffer to be flushed again later
+ * Done in one transaction of one query per viewed content
+ * 		count views: 	UPDATE CONTENT_TABLE SET view_count=view_count+count WHERE id=ID
+ */
+func (store *SQLStore) FlushViewsContext(ctx context.Context) (err error) {
+	store.views.wait()
+	err = store.flushViews(ctx)
+	return
+}
+
+/**
+ * Write every buffered view to CONTENT_TABLE, in the way that FlushViews does
+ * but without waiting for a flush in the background, which is what calls this
+ */
+func (store *SQLStore) flushViews(ctx context.Context) (err error) {
+	var pending map[string]int64
+	var IDs []string
+	if pending, IDs = store.views.take(); len(pending) == 0 {
+		return
+	}
+
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		var ID string
+		for _, ID = range IDs {
+			if _, err = tx.ExecContext(ctx, INCREMENT_CONTENT_VIEW_COUNT_OF_ID, pending[ID], ID); err != nil {
+				return
+			}
+		}
+
+		return
+	})
+
+	if err != nil {
+		store.views.restore(pending)
+	}
+
+	return
+}
+
+func (store *SQLStore) FlushViews() (err error) {
+	err = store.FlushViewsContext(context.Background())
+	return
+}
+
+/**
+ * Flush buffered views every `interval` in the background
+ * Errors from flushing are passed to `failed`, which may be nil
+ * Returns a function that stops flushing, and flushes one last time
+ */
+func (store *SQLStore) FlushViewsEvery(interval time.Duration, failed func(error)) (stop func()) {
+	stop = flushEvery(store.FlushViews, interval, failed)
+	return
+}
@@ -0,0 +1,296 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/google/uuid"
+
+	"context"
+	"testing"
+	"time"
+)
+
+func viewCountOK(test *testing.T, ID string, count int) {
+	var fetched types.Content
+	var err error
+	if fetched, _, err = ReadSingleContent(ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if fetched.ViewCount != count {
+		test.Errorf("view count mismatch for %s! have: %d, want: %d", ID, fetched.ViewCount, count)
+	}
+}
+
+func Test_RecordView(test *testing.T) {
+	var ID string = populateSingle(test)
+	var viewer string = uuid.New().String()
+
+	var counted bool
+	var err error
+	if counted, err = RecordView(ID, viewer); err != nil {
+		test.Fatal(err)
+	}
+
+	if !counted {
+		test.Errorf("first view of %s by %s was not counted", ID, viewer)
+	}
+
+	if counted, err = RecordView(ID, viewer); err != nil {
+		test.Fatal(err)
+	}
+
+	if counted {
+		test.Errorf("second view of %s by %s was counted", ID, viewer)
+	}
+
+	if counted, err = RecordView(ID, uuid.New().String()); err != nil {
+		test.Fatal(err)
+	}
+
+	if !counted {
+		test.Errorf("view of %s by someone else was not counted", ID)
+	}
+
+	viewCountOK(test, ID, 0)
+
+	if err = FlushViews(); err != nil {
+		test.Fatal(err)
+	}
+
+	viewCountOK(test, ID, 2)
+
+	if err = FlushViews(); err != nil {
+		test.Fatal(err)
+	}
+
+	viewCountOK(test, ID, 2)
+}
+
+func Test_RecordView_window(test *testing.T) {
+	SetViewWindow(0)
+	defer SetViewWindow(VIEW_WINDOW * time.Second)
+
+	var ID string = populateSingle(test)
+	var viewer string = uuid.New().String()
+
+	var counted bool
+	var err error
+	var index int
+	for index = 0; index != 3; index++ {
+		if counted, err = RecordView(ID, viewer); err != nil {
+			test.Fatal(err)
+		}
+
+		if !counted {
+			test.Errorf("view %d of %s outside of the window was not counted", index, ID)
+		}
+	}
+
+	if err = FlushViews(); err != nil {
+		test.Fatal(err)
+	}
+
+	viewCountOK(test, ID, 3)
+}
+
+func Test_RecordView_flushSize(test *testing.T) {
+	SetViewFlushSize(5)
+	defer SetViewFlushSize(VIEW_FLUSH_SIZE)
+	FlushViews()
+
+	var ID string = populateSingle(test)
+
+	var index int
+	var err error
+	for index = 0; index != 5; index++ {
+		if _, err = RecordView(ID, uuid.New().String()); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	connected.views.wait()
+	viewCountOK(test, ID, 5)
+
+	for index = 0; index != 2; index++ {
+		if _, err = RecordView(ID, uuid.New().String()); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	viewCountOK(test, ID, 5)
+
+	if err = FlushViews(); err != nil {
+		test.Fatal(err)
+	}
+
+	viewCountOK(test, ID, 7)
+}
+
+func Test_RecordView_flushCancelled(test *testing.T) {
+	SetViewFlushSize(2)
+	defer SetViewFlushSize(VIEW_FLUSH_SIZE)
+	FlushViews()
+
+	var ID string = populateSingle(test)
+	var ctx context.Context
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(context.Background())
+
+	var index int
+	var err error
+	for index = 0; index != 2; index++ {
+		if _, err = RecordViewContext(ctx, ID, uuid.New().String()); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	cancel()
+	connected.views.wait()
+	viewCountOK(test, ID, 2)
+}
+
+func Test_RecordView_flushFailed(test *testing.T) {
+	var store *SQLStore = freshSQLite(test)
+	store.SetViewFlushSize(1)
+
+	var failures chan error = make(chan error, 1)
+	store.SetViewFlushFailed(func(err error) { failures <- err })
+
+	var ID string = uuid.New().String()
+	store.handle.Close()
+
+	var err error
+	if _, err = store.RecordView(ID, uuid.New().String()); err != nil {
+		test.Fatal(err)
+	}
+
+	store.views.wait()
+
+	select {
+	case err = <-failures:
+	default:
+		test.Fatal("failed flush in the background was not reported")
+	}
+
+	if err == nil {
+		test.Errorf("failed flush in the background was reported without an error")
+	}
+
+	store.views.lock.Lock()
+	var pending int64 = store.views.pending[ID]
+	store.views.lock.Unlock()
+
+	if pending != 1 {
+		test.Errorf("views of the failed flush were not kept! have: %d, want: %d", pending, 1)
+	}
+}
+
+func Test_viewBuffer_wait(test *testing.T) {
+	var views *viewBuffer = newViewBuffer()
+	var release chan bool = make(chan bool)
+	views.flushBackground(func() (err error) {
+		<-release
+		return
+	})
+
+	var waited chan bool = make(chan bool)
+	go func() {
+		views.wait()
+		close(waited)
+	}()
+
+	select {
+	case <-waited:
+		test.Fatal("wait returned before the flush in the background was done")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-waited
+
+	var ran chan bool = make(chan bool)
+	views.flushBackground(func() (err error) {
+		close(ran)
+		return
+	})
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		test.Fatal("flush in the background after another was done did not run")
+	}
+
+	views.wait()
+}
+
+func Test_viewBuffer_expire(test *testing.T) {
+	var views *viewBuffer = newViewBuffer()
+	views.setWindow(time.Minute)
+
+	var ID string = uuid.New().String()
+	var index int
+	for index = 0; index != 5; index++ {
+		views.record(ID, uuid.New().String())
+	}
+
+	var later time.Time = time.Now().Add(time.Hour)
+
+	views.lock.Lock()
+	views.expire(later, VIEW_EXPIRE_STEP)
+	var seen int = len(views.seen)
+	var expiring int = len(views.expiring)
+	views.lock.Unlock()
+
+	if seen != 5-VIEW_EXPIRE_STEP || expiring != 5-VIEW_EXPIRE_STEP {
+		test.Errorf("expired too many or too few! have: %d seen, %d expiring, want: %d", seen, expiring, 5-VIEW_EXPIRE_STEP)
+	}
+
+	views.lock.Lock()
+	views.expire(later, 5)
+	seen = len(views.seen)
+	expiring = len(views.expiring)
+	views.lock.Unlock()
+
+	if seen != 0 || expiring != 0 {
+		test.Errorf("viewers were not all forgotten! have: %d seen, %d expiring", seen, expiring)
+	}
+}
+
+func Test_viewBuffer_expire_seenAgain(test *testing.T) {
+	var views *viewBuffer = newViewBuffer()
+	var key string = uuid.New().String() + "/" + uuid.New().String()
+	var now time.Time = time.Now()
+
+	views.lock.Lock()
+	views.seen[key] = now.Add(time.Hour)
+	views.expiring = []viewSeen{{key, now}, {key, now.Add(time.Hour)}}
+	views.expire(now.Add(time.Minute), 5)
+
+	var seen bool
+	_, seen = views.seen[key]
+	var expiring int = len(views.expiring)
+	views.lock.Unlock()
+
+	if !seen {
+		test.Errorf("viewer seen again was forgotten before their later window passed")
+	}
+
+	if expiring != 1 {
+		test.Errorf("expiring mismatch! have: %d, want: %d", expiring, 1)
+	}
+}
+
+func Test_FlushViewsEvery(test *testing.T) {
+	var ID string = populateSingle(test)
+	var stop func() = FlushViewsEvery(time.Hour, func(err error) { test.Error(err) })
+
+	var err error
+	if _, err = RecordView(ID, uuid.New().String()); err != nil {
+		test.Fatal(err)
+	}
+
+	stop()
+	stop()
+
+	viewCountOK(test, ID, 1)
+}
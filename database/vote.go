@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	VOTE_NONE    = 0
+	VOTE_LIKE    = 1
+	VOTE_DISLIKE = -1
+)
+
+var (
+	voteIncrements map[int]string = map[int]string{
+		VOTE_LIKE:    INCREMENT_CONTENT_LIKE_COUNT_OF_ID,
+		VOTE_DISLIKE: INCREMENT_CONTENT_DISLIKE_COUNT_OF_ID,
+	}
+	voteDecrements map[int]string = map[int]string{
+		VOTE_LIKE:    DECREMENT_CONTENT_LIKE_COUNT_OF_ID,
+		VOTE_DISLIKE: DECREMENT_CONTENT_DISLIKE_COUNT_OF_ID,
+	}
+)
+
+/**
+ * Read the vote of some user of id `voter` on content of id `ID` inside of a transaction `tx`
+ * The row is locked until `tx` is done
+ * If no vote exists, value is VOTE_NONE
+ */
+func readVoteLocked(ctx context.Context, tx *dialectTx, voter, ID string) (value int, err error) {
+	if err = tx.QueryRowxContext(ctx, READ_VOTE_OF_CONTENT_FOR_UPDATE, voter, ID).Scan(&value); err == sql.ErrNoRows {
+		value = VOTE_NONE
+		err = nil
+	}
+
+	return
+}
+
+/**
+ * Set the vote of some user of id `voter` on content of id `ID` to `value`
+ * `value` should be one of VOTE_LIKE, VOTE_DISLIKE, or VOTE_NONE to clear the vote
+ * A like may be flipped to a dislike, and the other way around
+ * The like_count and dislike_count of the content are updated in the same transaction
+ * Fails with ErrNotFound if the content doesn't exist, as nothing was counted
+ * Done in one transaction of up to 4 queries
+ * 		read vote: 		SELECT value FROM VOTE_TABLE WHERE voter=voter AND content=ID LIMIT 1 FOR UPDATE
+ * 		write vote: 	INSERT INTO VOTE_TABLE ... or UPDATE VOTE_TABLE SET value=value ...
+ * 		uncount old: 	UPDATE CONTENT_TABLE SET (dis)like_count=(dis)like_count-1 WHERE id=ID
+ * 		count new: 		UPDATE CONTENT_TABLE SET (dis)like_count=(dis)like_count+1 WHERE id=ID
+ */
+func (store *SQLStore) VoteContext(ctx context.Context, voter, ID string, value int) (err error) {
+	if value == VOTE_NONE {
+		err = store.ClearVoteContext(ctx, voter, ID)
+		return
+	}
+
+	var ok bool
+	if _, ok = voteIncrements[value]; !ok {
+		err = errorOf(ErrInvalid, "Incorrect vote value %d", value)
+		return
+	}
+
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		var previous int
+		if previous, err = readVoteLocked(ctx, tx, voter, ID); err != nil || previous == value {
+			return
+		}
+
+		var now int64 = time.Now().Unix()
+		if previous == VOTE_NONE {
+			_, err = tx.ExecContext(ctx, WRITE_VOTE, voter, ID, value, now)
+		} else {
+			_, err = tx.ExecContext(ctx, UPDATE_VOTE, value, now, voter, ID)
+		}
+
+		if err != nil {
+			return
+		}
+
+		if previous != VOTE_NONE {
+			if _, err = tx.ExecContext(ctx, voteDecrements[previous], ID); err != nil {
+				return
+			}
+		}
+
+		var result sql.Result
+		if result, err = tx.ExecContext(ctx, voteIncrements[value], ID); err != nil {
+			return
+		}
+
+		var counted int64
+		if counted, err = result.RowsAffected(); err == nil && counted == 0 {
+			err = errorOf(ErrNotFound, "Content %s does not exist", ID)
+		}
+
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) Vote(voter, ID string, value int) (err error) {
+	err = store.VoteContext(context.Background(), voter, ID, value)
+	return
+}
+
+/**
+ * Clear the vote of some user of id `voter` on content of id `ID`, if any
+ * Done in one transaction of up to 3 queries
+ * 		read vote: 		SELECT value FROM VOTE_TABLE WHERE voter=voter AND content=ID LIMIT 1 FOR UPDATE
+ * 		delete vote: 	DELETE FROM VOTE_TABLE WHERE voter=voter AND content=ID
+ * 		uncount old: 	UPDATE CONTENT_TABLE SET (dis)like_count=(dis)like_count-1 WHERE id=ID
+ */
+func (store *SQLStore) ClearVoteContext(ctx context.Context, voter, ID string) (err error) {
+	err = store.withTx(ctx, func(tx *dialectTx) (err error) {
+		var previous int
+		if previous, err = readVoteLocked(ctx, tx, voter, ID); err != nil || previous == VOTE_NONE {
+			return
+		}
+
+		if _, err = tx.ExecContext(ctx, DELETE_VOTE, voter, ID); err != nil {
+			return
+		}
+
+		_, err = tx.ExecContext(ctx, voteDecrements[previous], ID)
+		return
+	})
+
+	return
+}
+
+func (store *SQLStore) ClearVote(voter, ID string) (err error) {
+	err = store.ClearVoteContext(context.Background(), voter, ID)
+	return
+}
+
+/**
+ * Read the vote of some user of id `voter` on content of id `ID`
+ * If no vote exists, value is VOTE_NONE
+ * Done in one query
+ */
+func (store *SQLStore) ReadVoteContext(ctx context.Context, voter, ID string) (value int, err error) {
+	if err = store.db().QueryRowxContext(ctx, READ_VOTE_OF_CONTENT, voter, ID).Scan(&value); err == sql.ErrNoRows {
+		value = VOTE_NONE
+		err = nil
+	}
+
+	return
+}
+
+func (store *SQLStore) ReadVote(voter, ID string) (value int, err error) {
+	value, err = store.ReadVoteContext(context.Background(), voter, ID)
+	return
+}
+
+/**
+ * Read the votes of some user of id `voter` on every content of id in `IDs`
+ * Returns a map where
+ * 		id -> value
+ * Content that was not voted on is VOTE_NONE
+ * Done in one query
+ * 		read votes: 	SELECT content, value FROM VOTE_TABLE WHERE voter=voter AND content IN (IDs...)
+ */
+func (store *SQLStore) ReadVotesOfContext(ctx context.Context, voter string, IDs []string) (votes map[string]int, err error) {
+	var size int = len(IDs)
+	votes = make(map[string]int, size)
+	if size < 1 {
+		return
+	}
+
+	var id string
+	for _, id = range IDs {
+		votes[id] = VOTE_NONE
+	}
+
+	var paramString string = "(" + manyParamString("?", size) + ")"
+	var rows *sql.Rows
+	if rows, err = store.db().QueryContext(ctx, READ_VOTES_OF_MANY_CONTENT+paramString, append([]interface{}{voter}, interfaceStrings(IDs...)...)...); err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	var value int
+	for rows.Next() {
+		if err = rows.Scan(&id, &value); err != nil {
+			break
+		}
+
+		votes[id] = value
+	}
+
+	if err == nil {
+		err = rows.Err()
+	}
+
+	return
+}
+
+func (store *SQLStore) ReadVotesOf(voter string, IDs []string) (votes map[string]int, err error) {
+	votes, err = store.ReadVotesOfContext(context.Background(), voter, IDs)
+	return
+}
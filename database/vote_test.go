@@ -0,0 +1,204 @@
+package database
+
+import (
+	"github.com/brane-app/librane/types"
+	"github.com/google/uuid"
+
+	"testing"
+)
+
+func votable(test *testing.T) (ID string) {
+	ID = uuid.New().String()
+
+	var err error
+	if err = WriteContent(mapMod(writableContent, map[string]interface{}{"id": ID})); err != nil {
+		test.Fatal(err)
+	}
+
+	return
+}
+
+func voteCountOK(test *testing.T, ID string, likes, dislikes int) {
+	var fetched types.Content
+	var err error
+	if fetched, _, err = ReadSingleContent(ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if fetched.LikeCount != likes {
+		test.Errorf("like count mismatch for %s! have: %d, want: %d", ID, fetched.LikeCount, likes)
+	}
+
+	if fetched.DislikeCount != dislikes {
+		test.Errorf("dislike count mismatch for %s! have: %d, want: %d", ID, fetched.DislikeCount, dislikes)
+	}
+}
+
+func Test_Vote(test *testing.T) {
+	var ID string = votable(test)
+	var voter string = uuid.New().String()
+
+	var err error
+	if err = Vote(voter, ID, VOTE_LIKE); err != nil {
+		test.Fatal(err)
+	}
+
+	var value int
+	if value, err = ReadVote(voter, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if value != VOTE_LIKE {
+		test.Errorf("vote mismatch! have: %d, want: %d", value, VOTE_LIKE)
+	}
+
+	voteCountOK(test, ID, 1, 0)
+}
+
+func Test_Vote_twice(test *testing.T) {
+	var ID string = votable(test)
+	var voter string = uuid.New().String()
+
+	var err error
+	if err = Vote(voter, ID, VOTE_DISLIKE); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = Vote(voter, ID, VOTE_DISLIKE); err != nil {
+		test.Fatal(err)
+	}
+
+	voteCountOK(test, ID, 0, 1)
+}
+
+func Test_Vote_flip(test *testing.T) {
+	var ID string = votable(test)
+	var voter, other string = uuid.New().String(), uuid.New().String()
+
+	var err error
+	if err = Vote(other, ID, VOTE_LIKE); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = Vote(voter, ID, VOTE_LIKE); err != nil {
+		test.Fatal(err)
+	}
+
+	voteCountOK(test, ID, 2, 0)
+
+	if err = Vote(voter, ID, VOTE_DISLIKE); err != nil {
+		test.Fatal(err)
+	}
+
+	var value int
+	if value, err = ReadVote(voter, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if value != VOTE_DISLIKE {
+		test.Errorf("vote mismatch! have: %d, want: %d", value, VOTE_DISLIKE)
+	}
+
+	voteCountOK(test, ID, 1, 1)
+}
+
+func Test_Vote_err(test *testing.T) {
+	var ID string = votable(test)
+
+	var err error
+	if err = Vote(uuid.New().String(), ID, 2); err == nil {
+		test.Errorf("vote of 2 produced no error!")
+	}
+
+	voteCountOK(test, ID, 0, 0)
+}
+
+func Test_ClearVote(test *testing.T) {
+	var ID string = votable(test)
+	var voter string = uuid.New().String()
+
+	var err error
+	if err = Vote(voter, ID, VOTE_LIKE); err != nil {
+		test.Fatal(err)
+	}
+
+	if err = ClearVote(voter, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	var value int
+	if value, err = ReadVote(voter, ID); err != nil {
+		test.Fatal(err)
+	}
+
+	if value != VOTE_NONE {
+		test.Errorf("vote mismatch! have: %d, want: %d", value, VOTE_NONE)
+	}
+
+	voteCountOK(test, ID, 0, 0)
+
+	if err = Vote(voter, ID, VOTE_NONE); err != nil {
+		test.Fatal(err)
+	}
+
+	voteCountOK(test, ID, 0, 0)
+}
+
+func Test_ReadVote_nobody(test *testing.T) {
+	var value int
+	var err error
+	if value, err = ReadVote(uuid.New().String(), uuid.New().String()); err != nil {
+		test.Fatal(err)
+	}
+
+	if value != VOTE_NONE {
+		test.Errorf("random uuid has vote %d", value)
+	}
+}
+
+func Test_ReadVotesOf(test *testing.T) {
+	var voter string = uuid.New().String()
+	var want map[string]int = map[string]int{
+		votable(test): VOTE_LIKE,
+		votable(test): VOTE_DISLIKE,
+		votable(test): VOTE_NONE,
+	}
+
+	var IDs []string = make([]string, 0, len(want))
+	var ID string
+	var value int
+	var err error
+	for ID, value = range want {
+		IDs = append(IDs, ID)
+		if err = Vote(voter, ID, value); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	var votes map[string]int
+	if votes, err = ReadVotesOf(voter, IDs); err != nil {
+		test.Fatal(err)
+	}
+
+	if len(votes) != len(want) {
+		test.Errorf("votes size mismatch! have: %d, want: %d", len(votes), len(want))
+	}
+
+	for ID, value = range want {
+		if votes[ID] != value {
+			test.Errorf("vote mismatch for %s! have: %d, want: %d", ID, votes[ID], value)
+		}
+	}
+}
+
+func Test_ReadVotesOf_empty(test *testing.T) {
+	var votes map[string]int
+	var err error
+	if votes, err = ReadVotesOf(uuid.New().String(), []string{}); err != nil {
+		test.Fatal(err)
+	}
+
+	if len(votes) != 0 {
+		test.Errorf("no ids got votes %#v", votes)
+	}
+}
@@ -2,30 +2,135 @@ package middleware
 
 import (
 	"github.com/brane-app/librane/database"
+	"github.com/brane-app/librane/tools/signed"
+	"github.com/brane-app/librane/types"
 
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 )
 
 /**
- * Reject unauthed users
+ * Auth middleware that reads from some store
+ * The package level middleware of the same names use the store opened by database.Connect
  */
-func MustAuth(request *http.Request) (modified *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
+type Guard struct {
+	store   database.Store
+	keyring *signed.Keyring
+}
+
+var (
+	// Keyring that the package level middleware verify signed tokens with, set by UseKeyring
+	connectedKeyring *signed.Keyring
+)
+
+/**
+ * Make a Guard that authorizes requests against `store`
+ */
+func NewGuard(store database.Store) (guard Guard) {
+	guard = Guard{store: store}
+	return
+}
+
+/**
+ * Get a copy of this Guard that also accepts tokens signed by `keyring`, which are verified
+ * without reading from the store, alongside tokens of sessions that are looked up in it
+ * Every session that the store revokes is revoked on `keyring` too, so this should be called
+ * once for each keyring, before serving any request
+ */
+func (guard Guard) WithKeyring(keyring *signed.Keyring) (signing Guard) {
+	signing = guard
+	signing.keyring = keyring
+	if keyring != nil {
+		guard.store.OnSessionRevoked(keyring.Revoke)
+	}
+
+	return
+}
+
+/**
+ * Have the package level middleware accept tokens signed by `keyring`, or only those of sessions if it's nil
+ * Every session that the store opened by database.Connect revokes is revoked on `keyring` too,
+ * so this should be called once, after database.Connect and before serving any request
+ */
+func UseKeyring(keyring *signed.Keyring) {
+	connectedKeyring = keyring
+	if keyring != nil {
+		database.OnSessionRevoked(keyring.Revoke)
+	}
+}
+
+func connectedGuard() (guard Guard) {
+	guard = Guard{store: database.Connected(), keyring: connectedKeyring}
+	return
+}
+
+/**
+ * Sign a token of the session of some `pair`, that expires along with the token of the pair and grants `scopes`,
+ * or every scope of its user if there are none
+ * Fails if this Guard has no keyring
+ */
+func (guard Guard) SignPair(pair database.TokenPair, scopes ...string) (token string, err error) {
+	if guard.keyring == nil {
+		err = errors.New("Guard has no keyring to sign with")
+		return
+	}
+
+	var claims signed.Claims = signed.NewClaims(pair.Session.Owner, pair.Session.ID, scopes, 0)
+	claims.Expires = pair.Expires
+	token, err = guard.keyring.Sign(claims)
+	return
+}
+
+/**
+ * Reject unauthed users, who may give either a Bearer token or the token of an API key as "Key <token>"
+ * The requester and the id of their session are put in the context, as "requester" and "session"
+ * API keys put the id of the key as "key" rather than a session, and the scopes that it grants as "scopes"
+ * Signed tokens, if this Guard has a keyring, also put the scopes that they grant as "scopes" if they grant any,
+ * and are accepted without reading the store until they expire, or until the store revokes their session
+ */
+func (guard Guard) MustAuth(request *http.Request) (modified *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
 	code = 401
-	var bearer string = strings.TrimPrefix(request.Header.Get("Authorization"), BEARER_PREFIX)
+	var authorization string = request.Header.Get("Authorization")
+	var bearer string = strings.TrimPrefix(authorization, BEARER_PREFIX)
 
-	var owner string
-	if owner, ok, err = database.ReadTokenStat(bearer); err != nil || !ok {
-		r_map = map[string]interface{}{"error": "bad_auth"}
+	var ctx context.Context = request.Context()
+	switch {
+	case strings.HasPrefix(authorization, KEY_PREFIX):
+		var key types.APIKey
+		if key, ok, err = guard.store.ReadAPIKeyOfTokenContext(ctx, strings.TrimPrefix(authorization, KEY_PREFIX)); errors.Is(err, database.ErrInvalidToken) {
+			err = nil
+		}
+
+		ctx = context.WithValue(ctx, "requester", key.Owner)
+		ctx = context.WithValue(ctx, "key", key.ID)
+		ctx = context.WithValue(ctx, "scopes", key.Scopes)
+	case guard.keyring != nil && signed.IsSigned(bearer):
+		var claims signed.Claims
+		claims, err = guard.keyring.Verify(bearer)
+		ok, err = err == nil, nil
+
+		ctx = context.WithValue(ctx, "requester", claims.Subject)
+		ctx = context.WithValue(ctx, "session", claims.Session)
+		if claims.Scope != "" {
+			ctx = context.WithValue(ctx, "scopes", claims.Scopes())
+		}
+	default:
+		var session types.Session
+		if session, ok, err = guard.store.ReadSessionOfTokenContext(ctx, bearer); errors.Is(err, database.ErrInvalidToken) {
+			err = nil
+		}
+
+		ctx = context.WithValue(ctx, "requester", session.Owner)
+		ctx = context.WithValue(ctx, "session", session.ID)
 	}
 
-	modified = request.WithContext(context.WithValue(
-		request.Context(),
-		"requester",
-		owner,
-	))
+	if err != nil || !ok {
+		r_map = map[string]interface{}{"error": "bad_auth"}
+	}
 
+	modified = request.WithContext(ctx)
 	return
 }
 
@@ -33,7 +138,7 @@ func MustAuth(request *http.Request) (modified *http.Request, ok bool, code int,
  * Reject banned requests
  * required before: MustAuth to get the requester
  */
-func RejectBanned(request *http.Request) (_ *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
+func (guard Guard) RejectBanned(request *http.Request) (_ *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
 	var owner string
 	var owned bool
 	if owner, owned = request.Context().Value("requester").(string); !owned {
@@ -43,7 +148,7 @@ func RejectBanned(request *http.Request) (_ *http.Request, ok bool, code int, r_
 
 	ok = false
 	var banned bool
-	if banned, err = database.IsBanned(owner); err != nil || banned {
+	if banned, err = guard.store.IsBannedContext(request.Context(), owner); err != nil || banned {
 		code = 403
 		r_map = map[string]interface{}{"error": "banned"}
 		return
@@ -56,7 +161,7 @@ func RejectBanned(request *http.Request) (_ *http.Request, ok bool, code int, r_
 /**
  * Reject users who are not moderators
  */
-func MustModerator(request *http.Request) (_ *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
+func (guard Guard) MustModerator(request *http.Request) (_ *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
 	code = 403
 
 	var owner string
@@ -65,14 +170,14 @@ func MustModerator(request *http.Request) (_ *http.Request, ok bool, code int, r
 		return
 	}
 
-	ok, err = database.IsModerator(owner)
+	ok, err = guard.store.IsModeratorContext(request.Context(), owner)
 	return
 }
 
 /**
  * Reject users who are not admins
  */
-func MustAdmin(request *http.Request) (_ *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
+func (guard Guard) MustAdmin(request *http.Request) (_ *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
 	code = 403
 
 	var owner string
@@ -81,6 +186,61 @@ func MustAdmin(request *http.Request) (_ *http.Request, ok bool, code int, r_map
 		return
 	}
 
-	ok, err = database.IsAdmin(owner)
+	ok, err = guard.store.IsAdminContext(request.Context(), owner)
+	return
+}
+
+/**
+ * Make middleware that rejects requests whose auth doesn't grant `scope`
+ * Only API keys and signed tokens that grant scopes are limited by them, as sessions that
+ * were logged into may do anything that their user may
+ * required before: MustAuth to get the scopes
+ */
+func RequireScope(scope string) (middleware func(*http.Request) (*http.Request, bool, int, map[string]interface{}, error)) {
+	middleware = func(request *http.Request) (_ *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
+		var scopes []string
+		var scoped bool
+		if scopes, scoped = request.Context().Value("scopes").([]string); !scoped {
+			ok = true
+			return
+		}
+
+		var it string
+		for _, it = range scopes {
+			if ok = it == scope; ok {
+				return
+			}
+		}
+
+		code = 403
+		r_map = map[string]interface{}{"error": "missing_scope", "scope": scope}
+		return
+	}
+
+	return
+}
+
+func SignPair(pair database.TokenPair, scopes ...string) (token string, err error) {
+	token, err = connectedGuard().SignPair(pair, scopes...)
+	return
+}
+
+func MustAuth(request *http.Request) (modified *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
+	modified, ok, code, r_map, err = connectedGuard().MustAuth(request)
+	return
+}
+
+func RejectBanned(request *http.Request) (modified *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
+	modified, ok, code, r_map, err = connectedGuard().RejectBanned(request)
+	return
+}
+
+func MustModerator(request *http.Request) (modified *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
+	modified, ok, code, r_map, err = connectedGuard().MustModerator(request)
+	return
+}
+
+func MustAdmin(request *http.Request) (modified *http.Request, ok bool, code int, r_map map[string]interface{}, err error) {
+	modified, ok, code, r_map, err = connectedGuard().MustAdmin(request)
 	return
 }
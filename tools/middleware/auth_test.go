@@ -2,12 +2,15 @@ package middleware
 
 import (
 	"github.com/brane-app/librane/database"
+	"github.com/brane-app/librane/tools/signed"
 	"github.com/brane-app/librane/types"
 	"github.com/google/uuid"
 
 	"context"
+	"errors"
 	"net/http"
 	"testing"
+	"time"
 )
 
 func Test_MustAuth_blank(test *testing.T) {
@@ -91,6 +94,15 @@ func Test_MustAuth(test *testing.T) {
 	if owner != user.ID {
 		test.Errorf("modified is not owned by %s, but by %s", user.ID, owner)
 	}
+
+	var session types.Session
+	if session, _, err = database.ReadSessionOfToken(token); err != nil {
+		test.Fatal(err)
+	}
+
+	if modified.Context().Value("session").(string) != session.ID {
+		test.Errorf("modified is not of session %s", session.ID)
+	}
 }
 
 func Test_RejectBanned(test *testing.T) {
@@ -125,3 +137,408 @@ func Test_RejectBanned(test *testing.T) {
 		test.Errorf("%#v", r_map)
 	}
 }
+
+type fakeStore struct {
+	database.Store
+	owner   string
+	banned  bool
+	revoked *[]func(string, int64)
+}
+
+func (store fakeStore) ReadSessionOfTokenContext(ctx context.Context, token string) (session types.Session, valid bool, err error) {
+	if token == "fake" {
+		session, valid = types.Session{ID: "fake-session", Owner: store.owner}, true
+	}
+
+	return
+}
+
+func (store fakeStore) OnSessionRevoked(revoked func(session string, until int64)) {
+	*store.revoked = append(*store.revoked, revoked)
+}
+
+func (store fakeStore) ReadAPIKeyOfTokenContext(ctx context.Context, token string) (key types.APIKey, valid bool, err error) {
+	if token == "fake-key" {
+		key, valid = types.APIKey{ID: "fake-key", Owner: store.owner, Scopes: []string{"content:write"}}, true
+	}
+
+	return
+}
+
+func (store fakeStore) IsBannedContext(ctx context.Context, ID string) (banned bool, err error) {
+	banned = store.banned && ID == store.owner
+	return
+}
+
+func Test_MustAuth_canceled(test *testing.T) {
+	var ctx context.Context
+	var cancel func()
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	var request *http.Request = new(http.Request).WithContext(ctx)
+	request.Header = make(http.Header)
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	var ok bool
+	var err error
+	if _, ok, _, _, err = MustAuth(request); err == nil {
+		test.Errorf("canceled request produced no error")
+	}
+
+	if ok {
+		test.Errorf("canceled request got through")
+	}
+}
+
+func Test_Guard_MustAuth(test *testing.T) {
+	var guard Guard = NewGuard(fakeStore{owner: "faker"})
+	var request *http.Request = new(http.Request)
+	request.Header = make(http.Header)
+	request.Header.Add("Authorization", "Bearer fake")
+
+	var modified *http.Request
+	var ok bool
+	var err error
+	if modified, ok, _, _, err = guard.MustAuth(request); err != nil {
+		test.Fatal(err)
+	}
+
+	if !ok {
+		test.Errorf("faked request did not get through")
+	}
+
+	var owner string = modified.Context().Value("requester").(string)
+	if owner != "faker" {
+		test.Errorf("modified is not owned by faker, but by %s", owner)
+	}
+
+	var session string = modified.Context().Value("session").(string)
+	if session != "fake-session" {
+		test.Errorf("modified is not of fake-session, but of %s", session)
+	}
+
+	request.Header.Set("Authorization", "Bearer "+token)
+	if _, ok, _, _, err = guard.MustAuth(request); err != nil {
+		test.Fatal(err)
+	}
+
+	if ok {
+		test.Errorf("request with a token of another store got through")
+	}
+}
+
+func Test_Guard_RejectBanned(test *testing.T) {
+	var guard Guard = NewGuard(fakeStore{owner: "faker", banned: true})
+	var request *http.Request = new(http.Request).WithContext(context.WithValue(
+		context.TODO(),
+		"requester",
+		"faker",
+	))
+
+	var ok bool
+	var code int
+	var err error
+	if _, ok, code, _, err = guard.RejectBanned(request); err != nil {
+		test.Fatal(err)
+	}
+
+	if ok || code != 403 {
+		test.Errorf("banned faker not rejected, got code %d", code)
+	}
+}
+
+func Test_Guard_MustAuth_signed(test *testing.T) {
+	var secret []byte = make([]byte, signed.HMAC_SECRET_MIN)
+	var key signed.Key
+	var err error
+	if key, err = signed.NewHMACKey("test", secret); err != nil {
+		test.Fatal(err)
+	}
+
+	var keyring *signed.Keyring = signed.NewKeyring(key)
+	var hooks []func(string, int64)
+	var guard Guard = NewGuard(fakeStore{owner: "faker", revoked: &hooks}).WithKeyring(keyring)
+
+	var token, revoked, gone string
+	if token, err = keyring.Sign(signed.NewClaims("faker", "fake-session", []string{"read"}, time.Minute)); err != nil {
+		test.Fatal(err)
+	}
+
+	if revoked, err = keyring.Sign(signed.NewClaims("faker", "revoked-session", nil, time.Minute)); err != nil {
+		test.Fatal(err)
+	}
+
+	if gone, err = keyring.Sign(signed.NewClaims("faker", "gone-session", nil, time.Minute)); err != nil {
+		test.Fatal(err)
+	}
+
+	keyring.Revoke("revoked-session", time.Now().Unix()+60)
+
+	if len(hooks) != 1 {
+		test.Fatalf("keyring was given to the store %d times", len(hooks))
+	}
+
+	// as the store does when it revokes gone-session
+	hooks[0]("gone-session", time.Now().Unix()+60)
+
+	var request *http.Request = new(http.Request)
+	request.Header = make(http.Header)
+	request.Header.Add("Authorization", "Bearer "+token)
+
+	var modified *http.Request
+	var ok bool
+	if modified, ok, _, _, err = guard.MustAuth(request); err != nil {
+		test.Fatal(err)
+	}
+
+	if !ok {
+		test.Errorf("signed request did not get through")
+	}
+
+	var owner string = modified.Context().Value("requester").(string)
+	if owner != "faker" {
+		test.Errorf("modified is not owned by faker, but by %s", owner)
+	}
+
+	var session string = modified.Context().Value("session").(string)
+	if session != "fake-session" {
+		test.Errorf("modified is not of fake-session, but of %s", session)
+	}
+
+	var scopes []string = modified.Context().Value("scopes").([]string)
+	if len(scopes) != 1 || scopes[0] != "read" {
+		test.Errorf("modified has scopes %#v", scopes)
+	}
+
+	var code int
+	var it string
+	for _, it = range []string{revoked, gone, token + "x"} {
+		request.Header.Set("Authorization", "Bearer "+it)
+		if _, ok, code, _, err = guard.MustAuth(request); err != nil {
+			test.Fatal(err)
+		}
+
+		if ok || code != 401 {
+			test.Errorf("request with %s got through, got code %d", it, code)
+		}
+	}
+
+	request.Header.Set("Authorization", "Bearer fake")
+	if _, ok, _, _, err = guard.MustAuth(request); err != nil {
+		test.Fatal(err)
+	}
+
+	if !ok {
+		test.Errorf("request with a token of the store did not get through")
+	}
+}
+
+func Test_Guard_MustAuth_key(test *testing.T) {
+	var guard Guard = NewGuard(fakeStore{owner: "faker"})
+	var request *http.Request = new(http.Request)
+	request.Header = make(http.Header)
+	request.Header.Add("Authorization", KEY_PREFIX+"fake-key")
+
+	var modified *http.Request
+	var ok bool
+	var err error
+	if modified, ok, _, _, err = guard.MustAuth(request); err != nil {
+		test.Fatal(err)
+	}
+
+	if !ok {
+		test.Errorf("request with a key did not get through")
+	}
+
+	var owner string = modified.Context().Value("requester").(string)
+	if owner != "faker" {
+		test.Errorf("modified is not owned by faker, but by %s", owner)
+	}
+
+	var key string = modified.Context().Value("key").(string)
+	if key != "fake-key" {
+		test.Errorf("modified is not of fake-key, but of %s", key)
+	}
+
+	var scopes []string = modified.Context().Value("scopes").([]string)
+	if len(scopes) != 1 || scopes[0] != "content:write" {
+		test.Errorf("modified has scopes %#v", scopes)
+	}
+
+	var code int
+	var it string
+	for _, it = range []string{KEY_PREFIX + "fake", BEARER_PREFIX + "fake-key"} {
+		request.Header.Set("Authorization", it)
+		if _, ok, code, _, err = guard.MustAuth(request); err != nil {
+			test.Fatal(err)
+		}
+
+		if ok || code != 401 {
+			test.Errorf("request with %s got through, got code %d", it, code)
+		}
+	}
+}
+
+func Test_MustAuth_key(test *testing.T) {
+	var key string
+	var err error
+	if key, _, err = database.CreateAPIKey(user.ID, "bot", []string{"reports:read"}, 0); err != nil {
+		test.Fatal(err)
+	}
+
+	var request *http.Request = new(http.Request)
+	request.Header = make(http.Header)
+	request.Header.Add("Authorization", KEY_PREFIX+key)
+
+	var modified *http.Request
+	var ok bool
+	if modified, ok, _, _, err = MustAuth(request); err != nil {
+		test.Fatal(err)
+	}
+
+	if !ok {
+		test.Errorf("request with a key did not get through")
+	}
+
+	var owner string = modified.Context().Value("requester").(string)
+	if owner != user.ID {
+		test.Errorf("modified is not owned by %s, but by %s", user.ID, owner)
+	}
+
+	request.Header.Set("Authorization", BEARER_PREFIX+key)
+	if _, ok, _, _, err = MustAuth(request); err != nil {
+		test.Fatal(err)
+	}
+
+	if ok {
+		test.Errorf("key got through as a Bearer token")
+	}
+}
+
+func Test_RequireScope(test *testing.T) {
+	var cases map[string]bool = map[string]bool{
+		"content:write": true,
+		"reports:read":  false,
+	}
+
+	var request *http.Request = new(http.Request).WithContext(context.WithValue(
+		context.TODO(),
+		"scopes",
+		[]string{"content:write"},
+	))
+
+	var scope string
+	var want, ok bool
+	var code int
+	var err error
+	for scope, want = range cases {
+		if _, ok, code, _, err = RequireScope(scope)(request); err != nil {
+			test.Fatal(err)
+		}
+
+		if ok != want {
+			test.Errorf("request requiring %s got through: %t, want: %t", scope, ok, want)
+		}
+
+		if !ok && code != 403 {
+			test.Errorf("request requiring %s got code %d", scope, code)
+		}
+	}
+
+	if _, ok, _, _, err = RequireScope("reports:read")(new(http.Request)); err != nil {
+		test.Fatal(err)
+	}
+
+	if !ok {
+		test.Errorf("request of an unscoped session did not get through")
+	}
+}
+
+func Test_MustAuth_signedRevoked(test *testing.T) {
+	var secret []byte = make([]byte, signed.HMAC_SECRET_MIN)
+	var key signed.Key
+	var err error
+	if key, err = signed.NewHMACKey("test", secret); err != nil {
+		test.Fatal(err)
+	}
+
+	UseKeyring(signed.NewKeyring(key))
+	defer UseKeyring(nil)
+
+	var revoker types.User = types.NewUser("revoker", "", "revoker@bar.com")
+	if err = database.WriteUser(revoker.Map()); err != nil {
+		test.Fatal(err)
+	}
+
+	defer database.DeleteUser(revoker.ID)
+
+	var revokes map[string]func(database.TokenPair) error = map[string]func(database.TokenPair) error{
+		"RevokeSession": func(pair database.TokenPair) error {
+			return database.RevokeSession(pair.Session.ID)
+		},
+		"RevokeOtherSessions": func(pair database.TokenPair) (err error) {
+			var kept database.TokenPair
+			if kept, err = database.CreateTokenPair(revoker.ID, "kept", ""); err == nil {
+				err = database.RevokeOtherSessions(revoker.ID, kept.Session.ID)
+			}
+
+			return
+		},
+		"RevokeToken": func(pair database.TokenPair) error {
+			return database.RevokeToken(pair.Token)
+		},
+		"RevokeTokenOf": func(pair database.TokenPair) error {
+			return database.RevokeTokenOf(revoker.ID)
+		},
+		"RefreshTokenPair reused": func(pair database.TokenPair) (err error) {
+			if _, err = database.RefreshTokenPair(pair.Refresh); err == nil {
+				if _, err = database.RefreshTokenPair(pair.Refresh); errors.Is(err, database.ErrReusedToken) {
+					err = nil
+				}
+			}
+
+			return
+		},
+	}
+
+	var name string
+	var revoke func(database.TokenPair) error
+	for name, revoke = range revokes {
+		var pair database.TokenPair
+		if pair, err = database.CreateTokenPair(revoker.ID, name, ""); err != nil {
+			test.Fatal(err)
+		}
+
+		var token string
+		if token, err = SignPair(pair); err != nil {
+			test.Fatal(err)
+		}
+
+		var request *http.Request = new(http.Request)
+		request.Header = make(http.Header)
+		request.Header.Add("Authorization", "Bearer "+token)
+
+		var modified *http.Request
+		var ok bool
+		if modified, ok, _, _, err = MustAuth(request); err != nil {
+			test.Fatal(err)
+		}
+
+		if !ok || modified.Context().Value("requester").(string) != revoker.ID {
+			test.Errorf("signed token of a session did not get through before %s", name)
+		}
+
+		if err = revoke(pair); err != nil {
+			test.Fatal(err)
+		}
+
+		if _, ok, _, _, err = MustAuth(request); err != nil {
+			test.Fatal(err)
+		}
+
+		if ok {
+			test.Errorf("signed token got through after %s", name)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 const (
 	BEARER_PREFIX      = "Bearer "
+	KEY_PREFIX         = "Key "
 	MULTIPART_MEM_MAX  = 4 << 20
 	RANGE_SIZE_DEFAULT = 50
 	RANGE_SIZE_LIMIT   = 200
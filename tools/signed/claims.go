@@ -0,0 +1,58 @@
+package signed
+
+import (
+	"strings"
+	"time"
+)
+
+/**
+ * What a token says about whoever holds it, as the registered and common claims of a JWT
+ * Scope is every scope that it grants, separated by spaces
+ */
+type Claims struct {
+	Subject string `json:"sub"`
+	Session string `json:"sid"`
+	Scope   string `json:"scope,omitempty"`
+	Issued  int64  `json:"iat"`
+	Expires int64  `json:"exp"`
+}
+
+/**
+ * Make claims of some user of id `subject` in their session of id `session`,
+ * granting `scopes` for `lifetime` from now
+ */
+func NewClaims(subject, session string, scopes []string, lifetime time.Duration) (claims Claims) {
+	var now int64 = time.Now().Unix()
+
+	claims = Claims{
+		Subject: subject,
+		Session: session,
+		Scope:   strings.Join(scopes, " "),
+		Issued:  now,
+		Expires: now + int64(lifetime/time.Second),
+	}
+
+	return
+}
+
+/**
+ * Get every scope that these claims grant
+ */
+func (claims Claims) Scopes() (scopes []string) {
+	scopes = strings.Fields(claims.Scope)
+	return
+}
+
+/**
+ * Check whether or not these claims grant some `scope`
+ */
+func (claims Claims) HasScope(scope string) (has bool) {
+	var it string
+	for _, it = range claims.Scopes() {
+		if has = it == scope; has {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,125 @@
+package signed
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+)
+
+const (
+	ALGORITHM_HMAC    = "HS256"
+	ALGORITHM_ED25519 = "EdDSA"
+
+	// Shortest secret that an HMAC key may have, which is as long as the hash it makes
+	HMAC_SECRET_MIN = sha256.Size
+)
+
+/**
+ * Something that signs tokens and verifies their signatures, named in the header of each token by its id
+ * Algorithm is the JWT alg of the signatures that it makes
+ */
+type Key interface {
+	ID() string
+	Algorithm() string
+	Sign(payload []byte) ([]byte, error)
+	Verify(payload, signature []byte) bool
+}
+
+type hmacKey struct {
+	id     string
+	secret []byte
+}
+
+/**
+ * Make a key of id `ID` that signs with HMAC-SHA256 of some `secret`, which every server that verifies must share
+ * Fails if `secret` is shorter than HMAC_SECRET_MIN
+ */
+func NewHMACKey(ID string, secret []byte) (key Key, err error) {
+	if len(secret) < HMAC_SECRET_MIN {
+		err = fmt.Errorf("HMAC secret of %d bytes is shorter than %d", len(secret), HMAC_SECRET_MIN)
+		return
+	}
+
+	key = hmacKey{ID, append([]byte(nil), secret...)}
+	return
+}
+
+func (key hmacKey) ID() string {
+	return key.id
+}
+
+func (key hmacKey) Algorithm() string {
+	return ALGORITHM_HMAC
+}
+
+func (key hmacKey) Sign(payload []byte) (signature []byte, err error) {
+	var mac hash.Hash = hmac.New(sha256.New, key.secret)
+	mac.Write(payload)
+	signature = mac.Sum(nil)
+	return
+}
+
+func (key hmacKey) Verify(payload, signature []byte) (valid bool) {
+	var expected []byte
+	expected, _ = key.Sign(payload)
+	valid = hmac.Equal(expected, signature)
+	return
+}
+
+type ed25519Key struct {
+	id      string
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+/**
+ * Make a key of id `ID` that signs with Ed25519 of some `private` key
+ * Servers that only verify may be given the public half of it with NewEd25519PublicKey
+ */
+func NewEd25519Key(ID string, private ed25519.PrivateKey) (key Key, err error) {
+	if len(private) != ed25519.PrivateKeySize {
+		err = fmt.Errorf("Ed25519 private key of %d bytes is not %d", len(private), ed25519.PrivateKeySize)
+		return
+	}
+
+	key = ed25519Key{ID, private, private.Public().(ed25519.PublicKey)}
+	return
+}
+
+/**
+ * Make a key of id `ID` that only verifies signatures made by the private half of some `public` key
+ */
+func NewEd25519PublicKey(ID string, public ed25519.PublicKey) (key Key, err error) {
+	if len(public) != ed25519.PublicKeySize {
+		err = fmt.Errorf("Ed25519 public key of %d bytes is not %d", len(public), ed25519.PublicKeySize)
+		return
+	}
+
+	key = ed25519Key{ID, nil, public}
+	return
+}
+
+func (key ed25519Key) ID() string {
+	return key.id
+}
+
+func (key ed25519Key) Algorithm() string {
+	return ALGORITHM_ED25519
+}
+
+func (key ed25519Key) Sign(payload []byte) (signature []byte, err error) {
+	if key.private == nil {
+		err = fmt.Errorf("Key %s only verifies", key.id)
+		return
+	}
+
+	signature = ed25519.Sign(key.private, payload)
+	return
+}
+
+func (key ed25519Key) Verify(payload, signature []byte) (valid bool) {
+	valid = len(signature) == ed25519.SignatureSize && ed25519.Verify(key.public, payload, signature)
+	return
+}
@@ -0,0 +1,97 @@
+package signed
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+)
+
+func newHMACKey(test *testing.T, ID string) (key Key) {
+	var secret []byte = make([]byte, HMAC_SECRET_MIN)
+	rand.Read(secret)
+
+	var err error
+	if key, err = NewHMACKey(ID, secret); err != nil {
+		test.Fatal(err)
+	}
+
+	return
+}
+
+func newEd25519Key(test *testing.T, ID string) (key Key) {
+	var private ed25519.PrivateKey
+	var err error
+	if _, private, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		test.Fatal(err)
+	}
+
+	if key, err = NewEd25519Key(ID, private); err != nil {
+		test.Fatal(err)
+	}
+
+	return
+}
+
+func Test_NewHMACKey_short(test *testing.T) {
+	var err error
+	if _, err = NewHMACKey("short", []byte("hunter2")); err == nil {
+		test.Errorf("HMAC key of a short secret was made")
+	}
+}
+
+func Test_Key(test *testing.T) {
+	var payload []byte = []byte("some.payload")
+
+	var key Key
+	for _, key = range []Key{newHMACKey(test, "hmac"), newEd25519Key(test, "ed25519")} {
+		var signature []byte
+		var err error
+		if signature, err = key.Sign(payload); err != nil {
+			test.Fatal(err)
+		}
+
+		if !key.Verify(payload, signature) {
+			test.Errorf("%s signature does not verify", key.Algorithm())
+		}
+
+		if key.Verify([]byte("some.other"), signature) {
+			test.Errorf("%s signature verifies another payload", key.Algorithm())
+		}
+
+		signature[0] ^= 1
+		if key.Verify(payload, signature) {
+			test.Errorf("%s signature verifies once changed", key.Algorithm())
+		}
+	}
+}
+
+func Test_NewEd25519PublicKey(test *testing.T) {
+	var public ed25519.PublicKey
+	var private ed25519.PrivateKey
+	var err error
+	if public, private, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		test.Fatal(err)
+	}
+
+	var signing, verifying Key
+	if signing, err = NewEd25519Key("ed25519", private); err != nil {
+		test.Fatal(err)
+	}
+
+	if verifying, err = NewEd25519PublicKey("ed25519", public); err != nil {
+		test.Fatal(err)
+	}
+
+	var signature []byte
+	if signature, err = signing.Sign([]byte("payload")); err != nil {
+		test.Fatal(err)
+	}
+
+	if !verifying.Verify([]byte("payload"), signature) {
+		test.Errorf("public key does not verify its private key")
+	}
+
+	if _, err = verifying.Sign([]byte("payload")); err == nil {
+		test.Errorf("public key signed")
+	}
+}
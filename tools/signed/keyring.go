@@ -0,0 +1,246 @@
+package signed
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TOKEN_TYPE = "JWT"
+
+	// How many seconds the clocks of servers that sign and verify may disagree by
+	CLOCK_SKEW = 30
+)
+
+// Why a token doesn't verify, to be checked with errors.Is
+var (
+	ErrMalformed  error = errors.New("Malformed token")
+	ErrUnknownKey error = errors.New("Token of an unknown key")
+	ErrSignature  error = errors.New("Bad token signature")
+	ErrExpired    error = errors.New("Expired token")
+	ErrRevoked    error = errors.New("Token of a revoked session")
+)
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	Key       string `json:"kid"`
+}
+
+/**
+ * Keys that sign and verify tokens, and the sessions whose tokens are no longer accepted
+ * Tokens are signed by the newest key, and verified by whichever key their header names,
+ * so that keys may be rotated without logging out whoever has a token of an older one
+ * A Keyring is safe to share between goroutines
+ */
+type Keyring struct {
+	lock    sync.RWMutex
+	signing Key
+	keys    map[string]Key
+	revoked map[string]int64
+}
+
+/**
+ * Make a keyring that signs with `signing`, and also verifies tokens of any `verifying` key
+ */
+func NewKeyring(signing Key, verifying ...Key) (keyring *Keyring) {
+	keyring = &Keyring{
+		keys:    map[string]Key{},
+		revoked: map[string]int64{},
+	}
+
+	var key Key
+	for _, key = range verifying {
+		keyring.keys[key.ID()] = key
+	}
+
+	keyring.Rotate(signing)
+	return
+}
+
+/**
+ * Sign new tokens with `signing`, while still verifying those of every older key
+ */
+func (keyring *Keyring) Rotate(signing Key) {
+	keyring.lock.Lock()
+	keyring.signing = signing
+	keyring.keys[signing.ID()] = signing
+	keyring.lock.Unlock()
+}
+
+/**
+ * Forget some key of id `ID`, so that tokens of it no longer verify
+ * The key that signs can't be retired until another is rotated in
+ */
+func (keyring *Keyring) Retire(ID string) (err error) {
+	keyring.lock.Lock()
+	defer keyring.lock.Unlock()
+
+	if keyring.signing.ID() == ID {
+		err = fmt.Errorf("Key %s is signing", ID)
+		return
+	}
+
+	delete(keyring.keys, ID)
+	return
+}
+
+/**
+ * Sign some `claims` as a JWT, with the kid of the key that signed it in its header
+ */
+func (keyring *Keyring) Sign(claims Claims) (token string, err error) {
+	keyring.lock.RLock()
+	var key Key = keyring.signing
+	keyring.lock.RUnlock()
+
+	var encoded [2][]byte
+	if encoded[0], err = json.Marshal(header{key.Algorithm(), TOKEN_TYPE, key.ID()}); err != nil {
+		return
+	}
+
+	if encoded[1], err = json.Marshal(claims); err != nil {
+		return
+	}
+
+	var payload string = base64.RawURLEncoding.EncodeToString(encoded[0]) + "." + base64.RawURLEncoding.EncodeToString(encoded[1])
+
+	var signature []byte
+	if signature, err = key.Sign([]byte(payload)); err == nil {
+		token = payload + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+
+	return
+}
+
+/**
+ * Verify some `token` that was signed by a key of this keyring, and get its claims
+ * Fails with ErrMalformed if it isn't a JWT with a subject, ErrUnknownKey if its key isn't known,
+ * ErrSignature if it wasn't signed by that key with the algorithm that the key signs with,
+ * ErrExpired if it's expired or not yet issued, or ErrRevoked if its session has been revoked
+ */
+func (keyring *Keyring) Verify(token string) (claims Claims, err error) {
+	var parts []string = strings.Split(token, ".")
+	if len(parts) != 3 {
+		err = fmt.Errorf("%w of %d parts", ErrMalformed, len(parts))
+		return
+	}
+
+	var decoded header
+	if err = decodePart(parts[0], &decoded); err != nil {
+		return
+	}
+
+	keyring.lock.RLock()
+	var key Key
+	var ok bool
+	key, ok = keyring.keys[decoded.Key]
+	keyring.lock.RUnlock()
+
+	if !ok {
+		err = fmt.Errorf("%w %s", ErrUnknownKey, decoded.Key)
+		return
+	}
+
+	if decoded.Algorithm != key.Algorithm() {
+		err = fmt.Errorf("%w of %s, key %s signs with %s", ErrSignature, decoded.Algorithm, key.ID(), key.Algorithm())
+		return
+	}
+
+	var signature []byte
+	if signature, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		err = fmt.Errorf("%w signature: %s", ErrMalformed, err)
+		return
+	}
+
+	if !key.Verify([]byte(parts[0]+"."+parts[1]), signature) {
+		err = ErrSignature
+		return
+	}
+
+	if err = decodePart(parts[1], &claims); err != nil {
+		claims = Claims{}
+		return
+	}
+
+	var now int64 = time.Now().Unix()
+	switch {
+	case claims.Subject == "":
+		err = fmt.Errorf("%w without a subject", ErrMalformed)
+	case claims.Expires+CLOCK_SKEW < now:
+		err = fmt.Errorf("%w at %d", ErrExpired, claims.Expires)
+	case claims.Issued-CLOCK_SKEW > now:
+		err = fmt.Errorf("%w, issued in the future at %d", ErrExpired, claims.Issued)
+	case keyring.IsRevoked(claims.Session):
+		err = fmt.Errorf("%w %s", ErrRevoked, claims.Session)
+	}
+
+	if err != nil {
+		claims = Claims{}
+	}
+
+	return
+}
+
+func decodePart(part string, target interface{}) (err error) {
+	var bytes []byte
+	if bytes, err = base64.RawURLEncoding.DecodeString(part); err == nil {
+		err = json.Unmarshal(bytes, target)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrMalformed, err)
+	}
+
+	return
+}
+
+/**
+ * Stop accepting tokens of some session of id `session`, until the unix time `until`
+ * that the last of its tokens expires, after which it's forgotten so that the list stays small
+ * Only this keyring knows, which is why the middleware has the store revoke every session here as it
+ * revokes it there, so that their tokens are rejected without reading the store
+ */
+func (keyring *Keyring) Revoke(session string, until int64) {
+	var now int64 = time.Now().Unix()
+
+	keyring.lock.Lock()
+	defer keyring.lock.Unlock()
+
+	var ID string
+	var expires int64
+	for ID, expires = range keyring.revoked {
+		if expires+CLOCK_SKEW < now {
+			delete(keyring.revoked, ID)
+		}
+	}
+
+	if until > keyring.revoked[session] {
+		keyring.revoked[session] = until
+	}
+}
+
+/**
+ * Check whether or not some session of id `session` has been revoked
+ */
+func (keyring *Keyring) IsRevoked(session string) (revoked bool) {
+	keyring.lock.RLock()
+	var until int64
+	until, revoked = keyring.revoked[session]
+	keyring.lock.RUnlock()
+
+	revoked = revoked && until+CLOCK_SKEW >= time.Now().Unix()
+	return
+}
+
+/**
+ * Check whether or not some `token` looks like one that a Keyring signs, rather than one that's looked up
+ */
+func IsSigned(token string) (signed bool) {
+	signed = strings.Count(token, ".") == 2
+	return
+}
@@ -0,0 +1,166 @@
+package signed
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Keyring(test *testing.T) {
+	var key Key
+	for _, key = range []Key{newHMACKey(test, "hmac"), newEd25519Key(test, "ed25519")} {
+		var keyring *Keyring = NewKeyring(key)
+		var claims Claims = NewClaims("user", "session", []string{"read", "write"}, time.Minute)
+
+		var token string
+		var err error
+		if token, err = keyring.Sign(claims); err != nil {
+			test.Fatal(err)
+		}
+
+		if !IsSigned(token) {
+			test.Errorf("%s is not signed", token)
+		}
+
+		var verified Claims
+		if verified, err = keyring.Verify(token); err != nil {
+			test.Fatal(err)
+		}
+
+		if verified != claims {
+			test.Errorf("verified %#v, not %#v", verified, claims)
+		}
+	}
+}
+
+func Test_Keyring_Verify_err(test *testing.T) {
+	var keyring *Keyring = NewKeyring(newHMACKey(test, "hmac"))
+	var other *Keyring = NewKeyring(newHMACKey(test, "hmac"))
+
+	var token, expired, unknown, forged string
+	var err error
+	if token, err = keyring.Sign(NewClaims("user", "session", nil, time.Minute)); err != nil {
+		test.Fatal(err)
+	}
+
+	if expired, err = keyring.Sign(NewClaims("user", "session", nil, -time.Hour)); err != nil {
+		test.Fatal(err)
+	}
+
+	if unknown, err = NewKeyring(newHMACKey(test, "unknown")).Sign(NewClaims("user", "session", nil, time.Minute)); err != nil {
+		test.Fatal(err)
+	}
+
+	if forged, err = other.Sign(NewClaims("user", "session", nil, time.Minute)); err != nil {
+		test.Fatal(err)
+	}
+
+	var parts []string = strings.Split(token, ".")
+	var none string = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT","kid":"hmac"}`)) + "." + parts[1] + "."
+
+	var cases map[string]error = map[string]error{
+		"foobar":                        ErrMalformed,
+		"foo.bar.baz":                   ErrMalformed,
+		expired:                         ErrExpired,
+		unknown:                         ErrUnknownKey,
+		forged:                          ErrSignature,
+		none:                            ErrSignature,
+		parts[0] + "." + parts[1] + ".": ErrSignature,
+	}
+
+	var want error
+	for token, want = range cases {
+		if _, err = keyring.Verify(token); !errors.Is(err, want) {
+			test.Errorf("%s verified with %v, not %v", token, err, want)
+		}
+	}
+}
+
+func Test_Keyring_Rotate(test *testing.T) {
+	var keyring *Keyring = NewKeyring(newHMACKey(test, "old"))
+
+	var old, rotated string
+	var err error
+	if old, err = keyring.Sign(NewClaims("user", "session", nil, time.Minute)); err != nil {
+		test.Fatal(err)
+	}
+
+	keyring.Rotate(newEd25519Key(test, "new"))
+
+	if rotated, err = keyring.Sign(NewClaims("user", "session", nil, time.Minute)); err != nil {
+		test.Fatal(err)
+	}
+
+	var it string
+	for _, it = range []string{old, rotated} {
+		if _, err = keyring.Verify(it); err != nil {
+			test.Errorf("%s does not verify after rotating: %v", it, err)
+		}
+	}
+
+	if err = keyring.Retire("new"); err == nil {
+		test.Errorf("signing key was retired")
+	}
+
+	if err = keyring.Retire("old"); err != nil {
+		test.Fatal(err)
+	}
+
+	if _, err = keyring.Verify(old); !errors.Is(err, ErrUnknownKey) {
+		test.Errorf("token of a retired key verified with %v", err)
+	}
+
+	if _, err = keyring.Verify(rotated); err != nil {
+		test.Errorf("token of the signing key does not verify: %v", err)
+	}
+}
+
+func Test_Keyring_Revoke(test *testing.T) {
+	var keyring *Keyring = NewKeyring(newHMACKey(test, "hmac"))
+
+	var revoked, kept string
+	var err error
+	if revoked, err = keyring.Sign(NewClaims("user", "revoked", nil, time.Minute)); err != nil {
+		test.Fatal(err)
+	}
+
+	if kept, err = keyring.Sign(NewClaims("user", "kept", nil, time.Minute)); err != nil {
+		test.Fatal(err)
+	}
+
+	keyring.Revoke("forgotten", time.Now().Unix()-CLOCK_SKEW-1)
+	keyring.Revoke("revoked", time.Now().Unix()+60)
+
+	if _, err = keyring.Verify(revoked); !errors.Is(err, ErrRevoked) {
+		test.Errorf("token of a revoked session verified with %v", err)
+	}
+
+	if _, err = keyring.Verify(kept); err != nil {
+		test.Errorf("token of a kept session does not verify: %v", err)
+	}
+
+	keyring.Revoke("other", time.Now().Unix()+60)
+
+	var ok bool
+	if _, ok = keyring.revoked["forgotten"]; ok {
+		test.Errorf("expired revocation was kept")
+	}
+}
+
+func Test_Claims_HasScope(test *testing.T) {
+	var claims Claims = NewClaims("user", "session", []string{"read", "write"}, time.Minute)
+
+	if !claims.HasScope("read") || !claims.HasScope("write") {
+		test.Errorf("%#v is missing a scope", claims)
+	}
+
+	if claims.HasScope("admin") || claims.HasScope("rea") {
+		test.Errorf("%#v has a scope that it wasn't given", claims)
+	}
+
+	if claims.Expires-claims.Issued != 60 {
+		test.Errorf("%#v does not expire in a minute", claims)
+	}
+}
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+
+	"encoding/json"
+	"time"
+)
+
+type APIKey struct {
+	ID       string   `json:"id" db:"id"`
+	Owner    string   `json:"owner" db:"owner"`
+	Name     string   `json:"name" db:"name"`
+	Scopes   []string `json:"scopes" db:"scopes"`
+	Created  int64    `json:"created" db:"created"`
+	Expires  int64    `json:"expires" db:"expires"`
+	LastUsed int64    `json:"last_used" db:"last_used"`
+}
+
+func (key APIKey) Map() (data map[string]interface{}) {
+	data = map[string]interface{}{
+		"id":        key.ID,
+		"owner":     key.Owner,
+		"name":      key.Name,
+		"scopes":    key.Scopes,
+		"created":   key.Created,
+		"expires":   key.Expires,
+		"last_used": key.LastUsed,
+	}
+
+	return
+}
+
+func (key APIKey) JSON() (data []byte, err error) {
+	data, err = json.Marshal(key)
+	return
+}
+
+func (it *APIKey) FromMap(data map[string]interface{}) (err error) {
+	var config mapstructure.DecoderConfig = mapstructure.DecoderConfig{
+		Metadata: nil,
+		TagName:  "json",
+		Result:   &it,
+	}
+
+	var decoder *mapstructure.Decoder
+	if decoder, err = mapstructure.NewDecoder(&config); err == nil {
+		err = decoder.Decode(data)
+	}
+
+	return
+}
+
+/**
+ * Check whether or not this key grants some `scope`
+ */
+func (key APIKey) HasScope(scope string) (has bool) {
+	var it string
+	for _, it = range key.Scopes {
+		if has = it == scope; has {
+			return
+		}
+	}
+
+	return
+}
+
+/**
+ * Make a new key of some user `owner` named `name`, that grants `scopes`
+ * It expires `lifetime` seconds from now, or never if `lifetime` is 0, and has never been used
+ */
+func NewAPIKey(owner, name string, scopes []string, lifetime int64) (key APIKey) {
+	if scopes == nil {
+		scopes = make([]string, 0)
+	}
+
+	var now int64 = time.Now().Unix()
+
+	key = APIKey{
+		ID:      uuid.New().String(),
+		Owner:   owner,
+		Name:    name,
+		Scopes:  scopes,
+		Created: now,
+	}
+
+	if lifetime != 0 {
+		key.Expires = now + lifetime
+	}
+
+	return
+}
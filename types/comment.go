@@ -0,0 +1,73 @@
+package types
+
+import (
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+
+	"encoding/json"
+	"time"
+)
+
+type Comment struct {
+	ID      string `json:"id" db:"id"`
+	Content string `json:"content" db:"content"`
+	Author  string `json:"author" db:"author"`
+	Parent  string `json:"parent" db:"parent"`
+	Body    string `json:"body" db:"body"`
+	Created int64  `json:"created" db:"created"`
+	Edited  int64  `json:"edited" db:"edited"`
+	Deleted bool   `json:"deleted" db:"deleted"`
+}
+
+func (comment Comment) Map() (data map[string]interface{}) {
+	data = map[string]interface{}{
+		"id":      comment.ID,
+		"content": comment.Content,
+		"author":  comment.Author,
+		"parent":  comment.Parent,
+		"body":    comment.Body,
+		"created": comment.Created,
+		"edited":  comment.Edited,
+		"deleted": comment.Deleted,
+	}
+
+	return
+}
+
+func (comment Comment) JSON() (data []byte, err error) {
+	data, err = json.Marshal(comment)
+	return
+}
+
+func (it *Comment) FromMap(data map[string]interface{}) (err error) {
+	var config mapstructure.DecoderConfig = mapstructure.DecoderConfig{
+		Metadata: nil,
+		TagName:  "json",
+		Result:   &it,
+	}
+
+	var decoder *mapstructure.Decoder
+	if decoder, err = mapstructure.NewDecoder(&config); err == nil {
+		err = decoder.Decode(data)
+	}
+
+	return
+}
+
+/**
+ * Make a comment by `author` on content of id `content`
+ * Top level comments have an empty `parent`, and replies have the id of the comment they reply to
+ */
+func NewComment(content, author, parent, body string) (comment Comment) {
+	comment = Comment{
+		Content: content,
+		Author:  author,
+		Parent:  parent,
+		Body:    body,
+
+		ID:      uuid.New().String(),
+		Created: time.Now().Unix(),
+	}
+
+	return
+}
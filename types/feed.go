@@ -0,0 +1,28 @@
+package types
+
+import (
+	"encoding/json"
+)
+
+/**
+ * A single entry of a feed
+ * Original posts have the id of their content, and a nil Repub
+ * Repubs have the id of their repub, and point at the original content
+ */
+type FeedEntry struct {
+	ID      string  `json:"id"`
+	Content Content `json:"content"`
+	Repub   *Repub  `json:"repub"`
+}
+
+func (entry FeedEntry) Map() (data map[string]interface{}) {
+	var bytes []byte
+	bytes, _ = json.Marshal(entry)
+	json.Unmarshal(bytes, &data)
+	return
+}
+
+func (entry FeedEntry) JSON() (data []byte, err error) {
+	data, err = json.Marshal(entry)
+	return
+}
@@ -13,6 +13,12 @@ func acceptMonkeType(it MonkeType) {
 func Test_MonkeType(test *testing.T) {
 	acceptMonkeType(Content{})
 	acceptMonkeType(User{})
+	acceptMonkeType(Subscription{})
+	acceptMonkeType(Comment{})
+	acceptMonkeType(Repub{})
+	acceptMonkeType(FeedEntry{})
+	acceptMonkeType(TagCount{})
+	acceptMonkeType(Session{})
 }
 
 func Test_Ban(test *testing.T) {
@@ -56,6 +62,116 @@ func Test_Report(test *testing.T) {
 	}
 }
 
+func Test_Subscription(test *testing.T) {
+	var subscriber string = uuid.New().String()
+	var subscription string = uuid.New().String()
+	var made Subscription = NewSubscription(subscriber, subscription)
+
+	if made.Subscriber != subscriber {
+		test.Errorf("subscription properties not being set for subscriber! have: %s, want: %s", made.Subscriber, subscriber)
+	}
+
+	if made.Map()["subscription"].(string) != subscription {
+		test.Errorf("bad subscription map! %#v", made.Map())
+	}
+
+	var err error
+	if _, err = made.JSON(); err != nil {
+		test.Fatal(err)
+	}
+}
+
+func Test_Session(test *testing.T) {
+	var owner string = uuid.New().String()
+	var made Session = NewSession(owner, "phone", "127.0.0.1")
+
+	if made.Owner != owner {
+		test.Errorf("session properties not being set for owner! have: %s, want: %s", made.Owner, owner)
+	}
+
+	if made.Created != made.LastUsed {
+		test.Errorf("new session last used at %d, not when created at %d", made.LastUsed, made.Created)
+	}
+
+	if made.Created != made.Issued {
+		test.Errorf("new session issued at %d, not when created at %d", made.Issued, made.Created)
+	}
+
+	if made.Map()["device"].(string) != "phone" {
+		test.Errorf("bad session map! %#v", made.Map())
+	}
+
+	var err error
+	if _, err = made.JSON(); err != nil {
+		test.Fatal(err)
+	}
+}
+
+func Test_Comment(test *testing.T) {
+	var content string = uuid.New().String()
+	var author string = uuid.New().String()
+	var comment Comment = NewComment(content, author, "", "first")
+
+	if comment.Author != author {
+		test.Errorf("comment properties not being set for author! have: %s, want: %s", comment.Author, author)
+	}
+
+	if comment.Map()["content"].(string) != content {
+		test.Errorf("bad comment map! %#v", comment.Map())
+	}
+
+	var err error
+	if _, err = comment.JSON(); err != nil {
+		test.Fatal(err)
+	}
+
+	var map_source Comment
+	map_source.FromMap(comment.Map())
+
+	if map_source.Content != content {
+		test.Errorf("content %s not sourced from map %#v", content, comment.Map())
+	}
+}
+
+func Test_Repub(test *testing.T) {
+	var author string = uuid.New().String()
+	var content string = uuid.New().String()
+	var repub Repub = NewRepub(author, content)
+
+	if repub.Author != author {
+		test.Errorf("repub properties not being set for author! have: %s, want: %s", repub.Author, author)
+	}
+
+	if repub.Map()["content"].(string) != content {
+		test.Errorf("bad repub map! %#v", repub.Map())
+	}
+
+	var err error
+	if _, err = repub.JSON(); err != nil {
+		test.Fatal(err)
+	}
+}
+
+func Test_FeedEntry(test *testing.T) {
+	var content Content = NewContent("", uuid.New().String(), "", nil, false, false)
+	var repub Repub = NewRepub(uuid.New().String(), content.ID)
+	var entry FeedEntry = FeedEntry{ID: repub.ID, Content: content, Repub: &repub}
+
+	var mapped map[string]interface{} = entry.Map()
+	if mapped["repub"].(map[string]interface{})["author"].(string) != repub.Author {
+		test.Errorf("bad feed entry map! %#v", mapped)
+	}
+
+	if mapped["content"].(map[string]interface{})["id"].(string) != content.ID {
+		test.Errorf("bad feed entry map! %#v", mapped)
+	}
+
+	var err error
+	if _, err = entry.JSON(); err != nil {
+		test.Fatal(err)
+	}
+}
+
 func Test_User(test *testing.T) {
 	var nick string = "imonke"
 	var user User = NewUser(nick, "", "")
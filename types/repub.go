@@ -0,0 +1,62 @@
+package types
+
+import (
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+
+	"encoding/json"
+	"time"
+)
+
+type Repub struct {
+	ID      string `json:"id" db:"id"`
+	Content string `json:"content" db:"content"`
+	Author  string `json:"author" db:"author"`
+	Created int64  `json:"created" db:"created"`
+}
+
+func (repub Repub) Map() (data map[string]interface{}) {
+	data = map[string]interface{}{
+		"id":      repub.ID,
+		"content": repub.Content,
+		"author":  repub.Author,
+		"created": repub.Created,
+	}
+
+	return
+}
+
+func (repub Repub) JSON() (data []byte, err error) {
+	data, err = json.Marshal(repub)
+	return
+}
+
+func (it *Repub) FromMap(data map[string]interface{}) (err error) {
+	var config mapstructure.DecoderConfig = mapstructure.DecoderConfig{
+		Metadata: nil,
+		TagName:  "json",
+		Result:   &it,
+	}
+
+	var decoder *mapstructure.Decoder
+	if decoder, err = mapstructure.NewDecoder(&config); err == nil {
+		err = decoder.Decode(data)
+	}
+
+	return
+}
+
+/**
+ * Make a repub by `author` of content of id `content`
+ */
+func NewRepub(author, content string) (repub Repub) {
+	repub = Repub{
+		Author:  author,
+		Content: content,
+
+		ID:      uuid.New().String(),
+		Created: time.Now().Unix(),
+	}
+
+	return
+}
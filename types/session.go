@@ -0,0 +1,69 @@
+package types
+
+import (
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+
+	"encoding/json"
+	"time"
+)
+
+type Session struct {
+	ID       string `json:"id" db:"id"`
+	Owner    string `json:"owner" db:"owner"`
+	Device   string `json:"device" db:"device"`
+	IP       string `json:"ip" db:"ip"`
+	Created  int64  `json:"created" db:"created"`
+	LastUsed int64  `json:"last_used" db:"last_used"`
+	Issued   int64  `json:"issued" db:"issued"`
+}
+
+func (session Session) Map() (data map[string]interface{}) {
+	data = map[string]interface{}{
+		"id":        session.ID,
+		"owner":     session.Owner,
+		"device":    session.Device,
+		"ip":        session.IP,
+		"created":   session.Created,
+		"last_used": session.LastUsed,
+		"issued":    session.Issued,
+	}
+
+	return
+}
+
+func (session Session) JSON() (data []byte, err error) {
+	data, err = json.Marshal(session)
+	return
+}
+
+func (it *Session) FromMap(data map[string]interface{}) (err error) {
+	var config mapstructure.DecoderConfig = mapstructure.DecoderConfig{
+		Metadata: nil,
+		TagName:  "json",
+		Result:   &it,
+	}
+
+	var decoder *mapstructure.Decoder
+	if decoder, err = mapstructure.NewDecoder(&config); err == nil {
+		err = decoder.Decode(data)
+	}
+
+	return
+}
+
+func NewSession(owner, device, ip string) (session Session) {
+	var now int64 = time.Now().Unix()
+
+	session = Session{
+		ID:       uuid.New().String(),
+		Owner:    owner,
+		Device:   device,
+		IP:       ip,
+		Created:  now,
+		LastUsed: now,
+		Issued:   now,
+	}
+
+	return
+}
@@ -0,0 +1,55 @@
+package types
+
+import (
+	"github.com/mitchellh/mapstructure"
+
+	"encoding/json"
+	"time"
+)
+
+type Subscription struct {
+	Subscriber   string `json:"subscriber" db:"subscriber"`
+	Subscription string `json:"subscription" db:"subscription"`
+	Created      int64  `json:"created" db:"created"`
+}
+
+func (subscription Subscription) Map() (data map[string]interface{}) {
+	data = map[string]interface{}{
+		"subscriber":   subscription.Subscriber,
+		"subscription": subscription.Subscription,
+		"created":      subscription.Created,
+	}
+
+	return
+}
+
+func (subscription Subscription) JSON() (data []byte, err error) {
+	data, err = json.Marshal(subscription)
+	return
+}
+
+func (it *Subscription) FromMap(data map[string]interface{}) (err error) {
+	var config mapstructure.DecoderConfig = mapstructure.DecoderConfig{
+		Metadata: nil,
+		TagName:  "json",
+		Result:   &it,
+	}
+
+	var decoder *mapstructure.Decoder
+	if decoder, err = mapstructure.NewDecoder(&config); err == nil {
+		err = decoder.Decode(data)
+	}
+
+	return
+}
+
+func NewSubscription(subscriber, subscription string) (made Subscription) {
+	made = Subscription{
+		Subscriber:   subscriber,
+		Subscription: subscription,
+
+		Created: time.Now().Unix(),
+	}
+
+	return
+}
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"encoding/json"
+)
+
+type TagCount struct {
+	Tag   string `json:"tag" db:"tag"`
+	Count int    `json:"count" db:"count"`
+}
+
+func (count TagCount) Map() (data map[string]interface{}) {
+	data = map[string]interface{}{
+		"tag":   count.Tag,
+		"count": count.Count,
+	}
+
+	return
+}
+
+func (count TagCount) JSON() (data []byte, err error) {
+	data, err = json.Marshal(count)
+	return
+}